@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code-pulse/internal/auth"
+)
+
+const keysUsage = `usage: code-pulse keys <command>
+
+commands:
+  create -name NAME -scopes SCOPES [-expires DURATION]
+  list
+  revoke ID`
+
+// runKeys manages API keys from the command line, e.g. to mint the first
+// admin key of a fresh installation.
+func runKeys(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", keysUsage)
+	}
+
+	store := auth.NewKeyStore(db)
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := flags.String("name", "", "name describing who uses the key")
+		scopes := flags.String("scopes", "", "comma-separated scopes: "+strings.Join(auth.Scopes, ", "))
+		expires := flags.Duration("expires", 0, "lifetime of the key, e.g. 720h (default: never expires)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var expiresAt *time.Time
+		if *expires > 0 {
+			t := time.Now().Add(*expires)
+			expiresAt = &t
+		}
+
+		var scopeList []string
+		for _, scope := range strings.Split(*scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopeList = append(scopeList, scope)
+			}
+		}
+
+		key, apiKey, err := store.Create(*name, scopeList, expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created API key %d (%s) with scopes %s; it is not shown again.\n",
+			apiKey.ID, apiKey.Name, strings.Join(apiKey.Scopes, ","))
+		fmt.Println(key)
+		return nil
+
+	case "list":
+		keys, err := store.List()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tEXPIRES\tLAST USED\tREVOKED")
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix,
+				strings.Join(key.Scopes, ","), key.CreatedAt.Format(time.RFC3339),
+				formatOptionalTime(key.ExpiresAt), formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.RevokedAt))
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: code-pulse keys revoke ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+		revoked, err := store.Revoke(id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("no active API key with id %d", id)
+		}
+		fmt.Fprintf(os.Stderr, "Revoked API key %d\n", id)
+		return nil
+
+	default:
+		return fmt.Errorf("%s", keysUsage)
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
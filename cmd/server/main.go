@@ -1,16 +1,23 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"code-pulse/internal/alerting"
+	"code-pulse/internal/auth"
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/collector"
 	"code-pulse/internal/config"
 	"code-pulse/internal/database"
+	"code-pulse/internal/digest"
 	"code-pulse/internal/handlers"
 	"code-pulse/internal/scheduler"
+	"code-pulse/internal/scorecard"
 	"code-pulse/internal/services"
 )
 
@@ -27,28 +34,121 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
-	metricsService := services.NewMetricsService(
-		db,
-		cfg.GithubToken,
-		cfg.SonarqubeURL,
-		cfg.SonarqubeToken,
-		cfg.JiraURL,
-		cfg.JiraEmail,
-		cfg.JiraToken,
-	)
-
-	schedulerService := scheduler.New(metricsService, cfg)
+	if _, err := scorecard.Metrics(cfg.ScorecardMetrics); err != nil {
+		log.Fatal("Invalid SCORECARD_METRICS:", err)
+	}
+
+	if cfg.CatalogFile != "" {
+		c, err := catalog.LoadFile(cfg.CatalogFile)
+		if err != nil {
+			log.Fatal("Failed to load catalog:", err)
+		}
+		if err := catalog.NewStore(db).Import(c, false); err != nil {
+			log.Fatal("Failed to import catalog:", err)
+		}
+		log.Printf("Loaded %d teams and %d services from %s", len(c.Teams), len(c.Services), cfg.CatalogFile)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(db, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	metricsService := services.NewMetricsService(db)
+
+	registry := collector.NewRegistry()
+	for _, c := range []collector.Collector{
+		services.NewGithubCollector(metricsService, cfg),
+		services.NewGitlabCollector(metricsService, cfg),
+		services.NewJenkinsCollector(metricsService, cfg),
+		services.NewBitbucketCollector(metricsService, cfg),
+		services.NewPagerdutyCollector(metricsService, cfg),
+		services.NewSonarqubeCollector(metricsService, cfg),
+		services.NewJiraCollector(metricsService, cfg),
+		services.NewLinearCollector(metricsService, cfg),
+	} {
+		if err := registry.Register(c); err != nil {
+			log.Fatal("Failed to register collector:", err)
+		}
+	}
+
+	alerts, err := alerting.New(db, cfg)
+	if err != nil {
+		log.Fatal("Invalid alert configuration:", err)
+	}
+
+	digests := digest.New(db, cfg)
+	if err := digests.Validate(); err != nil {
+		log.Fatal("Invalid DIGEST_RECIPIENTS:", err)
+	}
+
+	schedulerService := scheduler.New(registry, metricsService, alerts, digests, cfg)
 	if err := schedulerService.Start(); err != nil {
 		log.Fatal("Failed to start scheduler:", err)
 	}
 	defer schedulerService.Stop()
 
-	h := handlers.New(db)
+	h := handlers.New(db, registry, cfg)
 	
-	http.HandleFunc("/api/metrics/github", h.GetGithubMetrics)
-	http.HandleFunc("/api/metrics/sonarqube", h.GetSonarqubeMetrics)
-	http.HandleFunc("/api/metrics/jira", h.GetJiraMetrics)
+	sessions := auth.NewSessionStore(db)
+	authn := auth.NewAuthenticator(auth.NewKeyStore(db), sessions, cfg.AuthEnabled)
+	if !cfg.AuthEnabled {
+		log.Println("API authentication is disabled (AUTH_ENABLED=false)")
+	}
+	if sso := auth.NewSSO(sessions, cfg); sso != nil {
+		http.HandleFunc("/auth/login", sso.Login)
+		http.HandleFunc("/auth/callback", sso.Callback)
+		http.HandleFunc("/auth/logout", sso.Logout)
+	}
+	http.HandleFunc("/auth/me", authn.Require(auth.Me))
+	github, jira, admin := auth.ScopeGithub, auth.ScopeJira, auth.ScopeAdmin
+
+	http.HandleFunc("/api/metrics/github", authn.Require(h.GetGithubMetrics, github))
+	http.HandleFunc("/api/metrics/ci", authn.Require(h.GetGithubMetrics, github))
+	http.HandleFunc("/api/metrics/sonarqube", authn.Require(h.GetSonarqubeMetrics, github))
+	http.HandleFunc("/api/metrics/sonarqube/quality-gates", authn.Require(h.GetSonarqubeQualityGates, github))
+	http.HandleFunc("/api/metrics/sonarqube/quality-gates/failing", authn.Require(h.GetFailingQualityGates, github))
+	http.HandleFunc("/api/metrics/sonarqube/pull-requests", authn.Require(h.GetSonarqubePullRequests, github))
+	http.HandleFunc("/api/metrics/sonarqube/issues/flow", authn.Require(h.GetSonarqubeIssueFlow, github))
+	http.HandleFunc("/api/metrics/sonarqube/issues/time-to-fix", authn.Require(h.GetSonarqubeTimeToFix, github))
+	http.HandleFunc("/api/metrics/sonarqube/components", authn.Require(h.GetSonarqubeComponents, github))
+	http.HandleFunc("/api/metrics/churn-complexity", authn.Require(h.GetChurnComplexity, github))
+	http.HandleFunc("/api/metrics/ci/summary", authn.Require(h.GetCISummary, github))
+	http.HandleFunc("/api/metrics/dora", authn.Require(h.GetDoraMetrics, github))
+	http.HandleFunc("/api/metrics/incidents", authn.Require(h.GetIncidents, github))
+	http.HandleFunc("/api/metrics/jira", authn.Require(h.GetJiraMetrics, jira))
+	http.HandleFunc("/api/metrics/flow", authn.Require(h.GetFlowMetrics, jira))
+	http.HandleFunc("/api/metrics/velocity", authn.Require(h.GetVelocity, jira))
+	http.HandleFunc("/api/metrics/tickets/lead-time", authn.Require(h.GetTicketLeadTimes, github, jira))
+	http.HandleFunc("/api/metrics/tickets/links", authn.Require(h.GetTicketLinks, github, jira))
+	http.HandleFunc("/api/metrics/pull-requests/unlinked", authn.Require(h.GetUnlinkedPullRequests, github))
+	http.HandleFunc("/api/scorecards", authn.Require(h.GetScorecards, github, jira))
+	http.HandleFunc("/api/insights/anomalies", authn.Require(h.GetAnomalies))
+	http.HandleFunc("/api/alerts", authn.Require(h.GetAlerts))
+	http.HandleFunc("/api/alerts/test", authn.Require(h.TestAlertChannels, admin))
+	http.HandleFunc("/api/digests", authn.Require(h.Digests, admin))
+	http.HandleFunc("/api/catalog", authn.Require(h.Catalog))
+	http.HandleFunc("/api/catalog/teams", authn.Require(h.CatalogTeams))
+	http.HandleFunc("/api/catalog/teams/", authn.Require(h.CatalogTeams))
+	http.HandleFunc("/api/catalog/services", authn.Require(h.CatalogServices))
+	http.HandleFunc("/api/catalog/services/", authn.Require(h.CatalogServices))
+	http.HandleFunc("/api/people", authn.Require(h.GetPeople, github, jira))
+	http.HandleFunc("/api/identities", authn.Require(auth.Unrestricted(h.GetIdentities), github, jira))
+	http.HandleFunc("/api/identities/", authn.Require(h.Identity))
+	http.HandleFunc("/api/collectors", authn.Require(h.GetCollectors, admin))
+	http.HandleFunc("/api/keys", authn.Require(h.APIKeys, admin))
+	http.HandleFunc("/api/keys/", authn.Require(h.RevokeAPIKey, admin))
 	http.HandleFunc("/api/health", h.Health)
+	http.HandleFunc("/metrics", authn.Require(auth.Unrestricted(h.GetPrometheusMetrics), github, jira))
+	http.HandleFunc("/grafana/", authn.Require(auth.Unrestricted(h.GrafanaTest), github, jira))
+	http.HandleFunc("/grafana/search", authn.Require(auth.Unrestricted(h.GrafanaSearch), github, jira))
+	http.HandleFunc("/grafana/query", authn.Require(auth.Unrestricted(h.GrafanaQuery), github, jira))
+	http.HandleFunc("/grafana/annotations", authn.Require(auth.Unrestricted(h.GrafanaAnnotations), github, jira))
+	http.HandleFunc("/grafana/tag-keys", authn.Require(auth.Unrestricted(h.GrafanaTagKeys), github, jira))
+	http.HandleFunc("/grafana/tag-values", authn.Require(auth.Unrestricted(h.GrafanaTagValues), github, jira))
 
 	go func() {
 		log.Printf("Server starting on port %s", cfg.Port)
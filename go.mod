@@ -5,3 +5,5 @@ go 1.24.4
 require github.com/lib/pq v1.10.9
 
 require github.com/robfig/cron/v3 v3.0.1
+
+require gopkg.in/yaml.v3 v3.0.1
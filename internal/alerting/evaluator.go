@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"code-pulse/internal/config"
+
+	"github.com/lib/pq"
+)
+
+const (
+	Firing   = "firing"
+	Resolved = "resolved"
+)
+
+// retryWindow bounds how long a notification that failed to deliver is
+// retried for.
+const retryWindow = 24 * time.Hour
+
+// Evaluator checks the alert rules and keeps one alert per rule while it
+// fires, notifying its channels when the alert starts firing and when it
+// resolves.
+type Evaluator struct {
+	db          *sql.DB
+	rules       []*Rule
+	environment string
+	mu          sync.Mutex
+}
+
+func New(db *sql.DB, cfg *config.Config) (*Evaluator, error) {
+	channels, err := Channels(cfg.AlertChannels)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Rules(cfg.AlertRules, channels)
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluator{db: db, rules: rules, environment: cfg.DoraEnvironment}, nil
+}
+
+// Evaluate checks every rule, then delivers the notifications of alerts that
+// changed state, including ones that failed to deliver before. Rules without
+// data leave their alert as it is.
+func (e *Evaluator) Evaluate(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	var errs []error
+	names := make([]string, 0, len(e.rules))
+	for _, rule := range e.rules {
+		names = append(names, rule.Name)
+		if err := e.evaluate(rule, now); err != nil {
+			errs = append(errs, fmt.Errorf("alert rule %s: %w", rule.Name, err))
+		}
+	}
+
+	// Alerts of rules taken out of the configuration resolve silently.
+	if _, err := e.db.Exec(`
+		UPDATE alerts SET status = $2, resolved_at = $3, evaluated_at = $3, notified_status = $2
+		WHERE status = $4 AND NOT (rule = ANY($1))`,
+		pq.Array(names), Resolved, now, Firing); err != nil {
+		errs = append(errs, fmt.Errorf("failed to resolve alerts of removed rules: %w", err))
+	}
+
+	for _, rule := range e.rules {
+		if err := e.notify(ctx, rule, now); err != nil {
+			errs = append(errs, fmt.Errorf("alert rule %s: %w", rule.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Evaluator) evaluate(rule *Rule, now time.Time) error {
+	value, baseline, firing, err := rule.check(e.db, e.environment, now)
+	if err != nil {
+		return err
+	}
+	if value == nil || (rule.compares() && baseline == nil) {
+		return nil
+	}
+	message := rule.message(*value, baseline)
+
+	if firing {
+		_, err = e.db.Exec(`
+			INSERT INTO alerts (rule, status, value, baseline, message, started_at, evaluated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6)
+			ON CONFLICT (rule) WHERE status = 'firing' DO UPDATE SET
+				value = $3, baseline = $4, message = $5, evaluated_at = $6`,
+			rule.Name, Firing, *value, baseline, message, now)
+	} else {
+		_, err = e.db.Exec(`
+			UPDATE alerts SET status = $2, value = $3, baseline = $4, message = $5, resolved_at = $6, evaluated_at = $6
+			WHERE rule = $1 AND status = $7`,
+			rule.Name, Resolved, *value, baseline, message, now, Firing)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save alert: %w", err)
+	}
+	return nil
+}
+
+// notify delivers the alerts of a rule whose state its channels have not
+// been told about. An alert counts as notified once every channel took it.
+// An alert that resolved before its firing notification got through is
+// marked notified without telling the channels about either.
+func (e *Evaluator) notify(ctx context.Context, rule *Rule, now time.Time) error {
+	rows, err := e.db.Query(`
+		SELECT id, status, value, baseline, message, started_at, resolved_at, notified_status
+		FROM alerts
+		WHERE rule = $1
+		AND status <> notified_status
+		AND evaluated_at >= $2
+		ORDER BY id`, rule.Name, now.Add(-retryWindow))
+	if err != nil {
+		return fmt.Errorf("failed to list alerts to notify: %w", err)
+	}
+
+	type pending struct {
+		id             int
+		notifiedStatus string
+		notification   Notification
+	}
+	var alerts []pending
+	for rows.Next() {
+		alert := pending{notification: Notification{
+			Rule:      rule.Name,
+			Metric:    rule.Metric,
+			Condition: rule.Condition,
+			Threshold: rule.Threshold,
+		}}
+		n := &alert.notification
+		if err := rows.Scan(&alert.id, &n.Status, &n.Value, &n.Baseline, &n.Message, &n.StartedAt,
+			&n.ResolvedAt, &alert.notifiedStatus); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list alerts to notify: %w", err)
+	}
+
+	var errs []error
+	for _, alert := range alerts {
+		delivered := true
+		if alert.notification.Status == Firing || alert.notifiedStatus == Firing {
+			for _, channel := range rule.channels {
+				if err := channel.Send(ctx, &alert.notification); err != nil {
+					errs = append(errs, err)
+					delivered = false
+				}
+			}
+		}
+		if !delivered {
+			continue
+		}
+		if _, err := e.db.Exec(`UPDATE alerts SET notified_status = $2 WHERE id = $1`,
+			alert.id, alert.notification.Status); err != nil {
+			errs = append(errs, fmt.Errorf("failed to mark alert notified: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// message describes the value a rule saw, such as "ci_p95_duration of
+// org/api main went from 300 to 480 over 7 days".
+func (r *Rule) message(value float64, baseline *float64) string {
+	subject := r.Metric
+	var scope []string
+	for _, filter := range []string{r.Repository, r.Project, r.Branch, r.Workflow} {
+		if filter != "" {
+			scope = append(scope, filter)
+		}
+	}
+	if len(scope) > 0 {
+		subject += " of " + strings.Join(scope, " ")
+	}
+
+	if baseline == nil {
+		return fmt.Sprintf("%s is %.4g over %d days, threshold %s %.4g", subject, value, r.WindowDays,
+			r.Condition, r.Threshold)
+	}
+	return fmt.Sprintf("%s went from %.4g to %.4g over %d days, threshold %s %.4g", subject, *baseline, value,
+		r.WindowDays, r.Condition, r.Threshold)
+}
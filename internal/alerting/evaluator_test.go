@@ -0,0 +1,180 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/database/dbtest"
+)
+
+const testRepository = "alerting-test/api"
+
+// addRuns stores finished runs of the test repository, each a minute before
+// the runs stored already.
+func addRuns(t *testing.T, db *sql.DB, status string, count int) {
+	t.Helper()
+
+	var stored int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM github_workflows WHERE repository = $1`, testRepository).
+		Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for i := stored; i < stored+count; i++ {
+		createdAt := now.Add(-time.Duration(i+1) * time.Minute)
+		_, err := db.Exec(`
+			INSERT INTO github_workflows (provider, external_id, repository, workflow_name, status, created_at, completed_at)
+			VALUES ('github', $1, $2, 'ci', $3, $4, $4)`,
+			fmt.Sprintf("%s#%d", testRepository, i), testRepository, status, createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type alertState struct {
+	status         string
+	notifiedStatus string
+	resolved       bool
+}
+
+func currentAlert(t *testing.T, db *sql.DB, rule string) (alertState, int) {
+	t.Helper()
+
+	var state alertState
+	var count int
+	var resolvedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT status, notified_status, resolved_at, COUNT(*) OVER ()
+		FROM alerts WHERE rule = $1
+		ORDER BY id DESC LIMIT 1`, rule).Scan(&state.status, &state.notifiedStatus, &resolvedAt, &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.resolved = resolvedAt.Valid
+	return state, count
+}
+
+func TestEvaluatorTransitions(t *testing.T) {
+	db := dbtest.Open(t)
+	rec := newReceiver(t)
+
+	const rule = "alerting-test-success-rate"
+	cleanup := func() {
+		db.Exec(`DELETE FROM github_workflows WHERE repository = $1`, testRepository)
+		db.Exec(`DELETE FROM alerts WHERE rule = $1`, rule)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	channels, err := Channels([]config.AlertChannelConfig{{Name: "ops", Type: Webhook, URL: rec.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Rules([]config.AlertRuleConfig{{
+		Name:       rule,
+		Metric:     "ci_success_rate",
+		Condition:  Below,
+		Threshold:  0.5,
+		Repository: testRepository,
+	}}, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := &Evaluator{db: db, rules: rules}
+	ctx := context.Background()
+
+	// Without runs the rule has no data and raises nothing.
+	if err := evaluator.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM alerts WHERE rule = $1`, rule).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("alerts without data = %d, want 0", count)
+	}
+
+	// One success in four fires; the channel is down, so the alert stays
+	// unnotified and the error is reported.
+	addRuns(t, db, "failure", 3)
+	addRuns(t, db, "success", 1)
+	rec.status = http.StatusInternalServerError
+	if err := evaluator.Evaluate(ctx); err == nil {
+		t.Error("expected the failed delivery to be reported")
+	}
+	if state, count := currentAlert(t, db, rule); state != (alertState{Firing, "", false}) || count != 1 {
+		t.Fatalf("after firing = %+v (%d alerts)", state, count)
+	}
+
+	// The delivery is retried once the channel is back.
+	rec.status = http.StatusOK
+	if err := evaluator.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, count := currentAlert(t, db, rule); state != (alertState{Firing, Firing, false}) || count != 1 {
+		t.Fatalf("after retry = %+v (%d alerts)", state, count)
+	}
+
+	// A firing alert that was notified is not sent again.
+	sent := len(rec.bodies)
+	if err := evaluator.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != sent {
+		t.Errorf("notified again while still firing: %d requests, want %d", len(rec.bodies), sent)
+	}
+
+	// Six successes in ten resolve the alert, which is notified once.
+	addRuns(t, db, "success", 6)
+	if err := evaluator.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, count := currentAlert(t, db, rule); state != (alertState{Resolved, Resolved, true}) || count != 1 {
+		t.Fatalf("after resolving = %+v (%d alerts)", state, count)
+	}
+	if len(rec.bodies) != sent+1 {
+		t.Fatalf("resolution sent %d requests, want 1", len(rec.bodies)-sent)
+	}
+	var notification Notification
+	if err := json.Unmarshal(rec.bodies[sent], &notification); err != nil {
+		t.Fatal(err)
+	}
+	if notification.Status != Resolved || notification.ResolvedAt == nil || notification.Value == nil ||
+		*notification.Value != 0.7 {
+		t.Errorf("resolution = %s", rec.bodies[sent])
+	}
+
+	// Seven successes in twenty fire again while the channel is down.
+	addRuns(t, db, "failure", 10)
+	rec.status = http.StatusInternalServerError
+	if err := evaluator.Evaluate(ctx); err == nil {
+		t.Error("expected the failed delivery to be reported")
+	}
+	if state, count := currentAlert(t, db, rule); state != (alertState{Firing, "", false}) || count != 2 {
+		t.Fatalf("after firing again = %+v (%d alerts)", state, count)
+	}
+
+	// Seventeen successes in thirty resolve it before the retry; the channel
+	// never heard it fired, so it is not told it resolved either.
+	addRuns(t, db, "success", 10)
+	rec.status = http.StatusOK
+	sent = len(rec.bodies)
+	if err := evaluator.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, count := currentAlert(t, db, rule); state != (alertState{Resolved, Resolved, true}) || count != 2 {
+		t.Fatalf("after resolving unnotified = %+v (%d alerts)", state, count)
+	}
+	if len(rec.bodies) != sent {
+		t.Errorf("resolution of an unnotified alert sent %d requests, want 0", len(rec.bodies)-sent)
+	}
+}
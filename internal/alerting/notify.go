@@ -0,0 +1,113 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"code-pulse/internal/config"
+)
+
+const (
+	Webhook = "webhook"
+	Slack   = "slack"
+)
+
+// Notification is the JSON body webhooks receive when an alert starts firing
+// or resolves. Baseline is set for rules comparing with a previous window.
+type Notification struct {
+	Rule       string     `json:"rule"`
+	Status     string     `json:"status"`
+	Metric     string     `json:"metric"`
+	Condition  string     `json:"condition"`
+	Threshold  float64    `json:"threshold"`
+	Value      *float64   `json:"value"`
+	Baseline   *float64   `json:"baseline,omitempty"`
+	Message    string     `json:"message"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Channel delivers alert notifications to a webhook.
+type Channel struct {
+	Name       string
+	Type       string
+	URL        string
+	httpClient *http.Client
+}
+
+// Channels validates the configured notification channels.
+func Channels(configs []config.AlertChannelConfig) ([]*Channel, error) {
+	var channels []*Channel
+	seen := make(map[string]bool)
+	for _, c := range configs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("alert channel without a name")
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate alert channel %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		if c.Type != Webhook && c.Type != Slack {
+			return nil, fmt.Errorf("alert channel %s: unknown type %q, expected %s or %s", c.Name, c.Type, Webhook, Slack)
+		}
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("alert channel %s: invalid url %q", c.Name, c.URL)
+		}
+		channels = append(channels, &Channel{
+			Name:       c.Name,
+			Type:       c.Type,
+			URL:        c.URL,
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	return channels, nil
+}
+
+// slackMessage is the payload of a Slack-compatible incoming webhook.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Send posts a notification: the alert itself as JSON to a webhook, or a
+// one-line summary to Slack.
+func (c *Channel) Send(ctx context.Context, notification *Notification) error {
+	var payload interface{} = notification
+	if c.Type == Slack {
+		icon := ":rotating_light:"
+		if notification.Status == Resolved {
+			icon = ":white_check_mark:"
+		}
+		payload = slackMessage{Text: fmt.Sprintf("%s [%s] %s: %s", icon, notification.Status, notification.Rule,
+			notification.Message)}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to notify %s: %w", c.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to notify %s: status %d: %s", c.Name, resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
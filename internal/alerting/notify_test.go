@@ -0,0 +1,159 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code-pulse/internal/config"
+)
+
+// receiver records the bodies posted to it and answers with status.
+type receiver struct {
+	*httptest.Server
+	status int
+	bodies [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	t.Helper()
+
+	rec := &receiver{status: http.StatusOK}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		rec.bodies = append(rec.bodies, body)
+		w.WriteHeader(rec.status)
+		if rec.status >= 300 {
+			io.WriteString(w, "no such hook\n")
+		}
+	}))
+	t.Cleanup(rec.Close)
+
+	return rec
+}
+
+func channel(t *testing.T, name, kind, url string) *Channel {
+	t.Helper()
+
+	channels, err := Channels([]config.AlertChannelConfig{{Name: name, Type: kind, URL: url}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return channels[0]
+}
+
+func testNotification(status string) *Notification {
+	value, baseline := 480.0, 300.0
+	return &Notification{
+		Rule:      "ci-slow",
+		Status:    status,
+		Metric:    "ci_p95_duration",
+		Condition: IncreasePercent,
+		Threshold: 20,
+		Value:     &value,
+		Baseline:  &baseline,
+		Message:   "ci_p95_duration of org/api went from 300 to 480 over 7 days, threshold increase_percent 20",
+		StartedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookSendsNotification(t *testing.T) {
+	rec := newReceiver(t)
+
+	if err := channel(t, "ops", Webhook, rec.URL).Send(context.Background(), testNotification(Firing)); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != 1 {
+		t.Fatalf("received %d requests, want 1", len(rec.bodies))
+	}
+
+	var got Notification
+	if err := json.Unmarshal(rec.bodies[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rule != "ci-slow" || got.Status != Firing || got.Metric != "ci_p95_duration" ||
+		got.Value == nil || *got.Value != 480 || got.Baseline == nil || *got.Baseline != 300 {
+		t.Errorf("payload = %s", rec.bodies[0])
+	}
+	if strings.Contains(string(rec.bodies[0]), "resolved_at") {
+		t.Errorf("firing payload has resolved_at: %s", rec.bodies[0])
+	}
+}
+
+func TestSlackSendsSummary(t *testing.T) {
+	rec := newReceiver(t)
+	slack := channel(t, "team", Slack, rec.URL)
+
+	for _, status := range []string{Firing, Resolved} {
+		if err := slack.Send(context.Background(), testNotification(status)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		":rotating_light: [firing] ci-slow: ci_p95_duration of org/api went from 300 to 480",
+		":white_check_mark: [resolved] ci-slow: ci_p95_duration of org/api went from 300 to 480",
+	}
+	for i, body := range rec.bodies {
+		var message map[string]interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := message["text"].(string)
+		if len(message) != 1 || !strings.HasPrefix(text, want[i]) {
+			t.Errorf("message %d = %s", i, body)
+		}
+	}
+}
+
+func TestSendReportsFailedDelivery(t *testing.T) {
+	rec := newReceiver(t)
+	rec.status = http.StatusNotFound
+
+	err := channel(t, "ops", Webhook, rec.URL).Send(context.Background(), testNotification(Firing))
+	if err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if !strings.Contains(err.Error(), "ops") || !strings.Contains(err.Error(), "404") ||
+		!strings.Contains(err.Error(), "no such hook") {
+		t.Errorf("error = %v", err)
+	}
+
+	rec.status = http.StatusNoContent
+	if err := channel(t, "ops", Webhook, rec.URL).Send(context.Background(), testNotification(Firing)); err != nil {
+		t.Errorf("204 response: %v", err)
+	}
+}
+
+func TestChannelsRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []config.AlertChannelConfig
+	}{
+		{"name", []config.AlertChannelConfig{{Type: Webhook, URL: "https://hooks.example.com"}}},
+		{"duplicate", []config.AlertChannelConfig{
+			{Name: "ops", Type: Webhook, URL: "https://hooks.example.com"},
+			{Name: "ops", Type: Slack, URL: "https://hooks.slack.com/x"},
+		}},
+		{"type", []config.AlertChannelConfig{{Name: "ops", Type: "email", URL: "https://hooks.example.com"}}},
+		{"url", []config.AlertChannelConfig{{Name: "ops", Type: Webhook, URL: "ftp://hooks.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Channels(tt.channels); err == nil {
+				t.Error("expected the configuration to be rejected")
+			}
+		})
+	}
+}
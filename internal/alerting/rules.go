@@ -0,0 +1,214 @@
+package alerting
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/dora"
+)
+
+const (
+	Above           = "above"
+	Below           = "below"
+	Increase        = "increase"
+	Decrease        = "decrease"
+	IncreasePercent = "increase_percent"
+	DecreasePercent = "decrease_percent"
+)
+
+// Metrics alert rules can watch. CI metrics take repository, branch and
+// workflow; coverage takes project and branch, where the empty branch is the
+// main one; DORA metrics take repository.
+var Metrics = []string{
+	"ci_success_rate",
+	"ci_median_duration",
+	"ci_p95_duration",
+	"coverage",
+	"deployment_frequency",
+	"lead_time_hours",
+	"change_failure_rate",
+}
+
+const defaultWindowDays = 7
+
+// Rule is a validated alert rule with its defaults applied.
+type Rule struct {
+	config.AlertRuleConfig
+	channels []*Channel
+}
+
+// Rules validates the configured rules and resolves their channels; rules
+// without channels notify every one.
+func Rules(configs []config.AlertRuleConfig, channels []*Channel) ([]*Rule, error) {
+	byName := make(map[string]*Channel)
+	for _, channel := range channels {
+		byName[channel.Name] = channel
+	}
+
+	var rules []*Rule
+	seen := make(map[string]bool)
+	for _, c := range configs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("alert rule without a name")
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate alert rule %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		if !contains(Metrics, c.Metric) {
+			return nil, fmt.Errorf("alert rule %s: unknown metric %q", c.Name, c.Metric)
+		}
+		switch c.Condition {
+		case Above, Below, Increase, Decrease, IncreasePercent, DecreasePercent:
+		default:
+			return nil, fmt.Errorf("alert rule %s: unknown condition %q", c.Name, c.Condition)
+		}
+		if c.WindowDays < 0 || c.BaselineDays < 0 {
+			return nil, fmt.Errorf("alert rule %s: window_days and baseline_days must not be negative", c.Name)
+		}
+		if c.WindowDays == 0 {
+			c.WindowDays = defaultWindowDays
+		}
+		if c.BaselineDays == 0 {
+			c.BaselineDays = c.WindowDays
+		}
+
+		rule := &Rule{AlertRuleConfig: c}
+		if len(c.Channels) == 0 {
+			rule.channels = channels
+		}
+		for _, name := range c.Channels {
+			channel, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("alert rule %s: unknown channel %q", c.Name, name)
+			}
+			rule.channels = append(rule.channels, channel)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compares reports whether the rule compares the current window with a
+// baseline rather than with a fixed threshold.
+func (r *Rule) compares() bool {
+	return r.Condition != Above && r.Condition != Below
+}
+
+// check evaluates the rule at now, returning its value, the baseline value
+// for comparing conditions, and whether it fires. A rule without data in
+// either window does not fire.
+func (r *Rule) check(db *sql.DB, environment string, now time.Time) (value, baseline *float64, firing bool, err error) {
+	window := time.Duration(r.WindowDays) * 24 * time.Hour
+	value, err = r.measure(db, environment, now.Add(-window), now)
+	if err != nil || value == nil {
+		return value, nil, false, err
+	}
+
+	switch r.Condition {
+	case Above:
+		return value, nil, *value > r.Threshold, nil
+	case Below:
+		return value, nil, *value < r.Threshold, nil
+	}
+
+	baselineWindow := time.Duration(r.BaselineDays) * 24 * time.Hour
+	baseline, err = r.measure(db, environment, now.Add(-window-baselineWindow), now.Add(-window))
+	if err != nil || baseline == nil {
+		return value, baseline, false, err
+	}
+
+	change := *value - *baseline
+	switch r.Condition {
+	case Increase:
+		firing = change > r.Threshold
+	case Decrease:
+		firing = -change > r.Threshold
+	case IncreasePercent:
+		firing = *baseline != 0 && change / *baseline * 100 > r.Threshold
+	case DecreasePercent:
+		firing = *baseline != 0 && -change / *baseline * 100 > r.Threshold
+	}
+	return value, baseline, firing, nil
+}
+
+// measure computes the metric over [from, to). Coverage is the latest value
+// collected before to.
+func (r *Rule) measure(db *sql.DB, environment string, from, to time.Time) (*float64, error) {
+	var value sql.NullFloat64
+	var err error
+	switch r.Metric {
+	case "ci_success_rate", "ci_median_duration", "ci_p95_duration":
+		aggregate := map[string]string{
+			"ci_success_rate": `COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION /
+				NULLIF(COUNT(*) FILTER (WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')), 0)`,
+			"ci_median_duration": `PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration)`,
+			"ci_p95_duration":    `PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY duration)`,
+		}[r.Metric]
+		err = db.QueryRow(`
+			SELECT `+aggregate+`
+			FROM github_workflows
+			WHERE created_at >= $1
+			AND created_at < $2
+			AND ($3 = '' OR repository = $3)
+			AND ($4 = '' OR head_branch = $4)
+			AND ($5 = '' OR workflow_name = $5)`,
+			from, to, r.Repository, r.Branch, r.Workflow).Scan(&value)
+
+	case "coverage":
+		err = db.QueryRow(`
+			SELECT AVG(numeric_value)
+			FROM (
+				SELECT DISTINCT ON (project_key) numeric_value
+				FROM sonarqube_metrics
+				WHERE metric_key = 'coverage'
+				AND branch = $1 AND pull_request = '' AND qualifier = 'TRK'
+				AND numeric_value IS NOT NULL
+				AND collected_at < $2
+				AND ($3 = '' OR project_key = $3)
+				ORDER BY project_key, collected_at DESC
+			) latest`, r.Branch, to, r.Project).Scan(&value)
+
+	default:
+		var repositories []string
+		if r.Repository != "" {
+			repositories = []string{r.Repository}
+		}
+		metrics, doraErr := dora.Compute(db, dora.Filter{
+			Repositories: repositories,
+			Environment:  environment,
+			From:         from,
+			To:           to,
+		})
+		if doraErr != nil {
+			return nil, doraErr
+		}
+		switch r.Metric {
+		case "deployment_frequency":
+			return &metrics.DeploymentsPerDay, nil
+		case "lead_time_hours":
+			return metrics.LeadTimeHours, nil
+		case "change_failure_rate":
+			return metrics.ChangeFailureRate, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", r.Metric, err)
+	}
+	if !value.Valid {
+		return nil, nil
+	}
+	return &value.Float64, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultThreshold is the modified z-score beyond which a point is flagged,
+// as suggested by Iglewicz and Hoaglin.
+const DefaultThreshold = 3.5
+
+const (
+	// A seasonal baseline takes the same weekday of the previous weeks; a
+	// rolling one the days right before the point.
+	seasonalWeeks   = 8
+	seasonalMinimum = 4
+	rollingDays     = 28
+	rollingMinimum  = 7
+
+	// madScale turns the median absolute deviation into an estimate of the
+	// standard deviation of normally distributed data, meanScale does the
+	// same for the mean absolute deviation.
+	madScale  = 1.4826
+	meanScale = 1.2533
+
+	// Deviations are never taken below one unit of the series, or 5% of its
+	// median, so that series barely moving do not flag every wobble.
+	minDeviation         = 1
+	minRelativeDeviation = 0.05
+)
+
+// Point is the value of a daily series on a day, at midnight.
+type Point struct {
+	Day   time.Time
+	Value float64
+}
+
+// Score rates a point against its baseline. Score is the modified z-score:
+// how many deviations the value lies from the expected median.
+type Score struct {
+	Point
+	Expected  float64
+	Deviation float64
+	Score     float64
+}
+
+// HistoryDays returns how many days before a window a series must reach for
+// its first points to have a full baseline.
+func HistoryDays(seasonal bool) int {
+	if seasonal {
+		return seasonalWeeks * 7
+	}
+	return rollingDays
+}
+
+// Detect scores every point of a daily series with enough history against
+// the rolling median and median absolute deviation of its baseline. Seasonal
+// baselines compare a Monday with the previous Mondays, which keeps weekly
+// patterns such as quiet weekends from being flagged.
+func Detect(points []Point, seasonal bool) []Score {
+	values := make(map[string]float64, len(points))
+	for _, p := range points {
+		values[dayKey(p.Day)] = p.Value
+	}
+
+	var scores []Score
+	for _, p := range points {
+		var baseline []float64
+		minimum := rollingMinimum
+		if seasonal {
+			minimum = seasonalMinimum
+			for week := 1; week <= seasonalWeeks; week++ {
+				if v, ok := values[dayKey(p.Day.AddDate(0, 0, -7*week))]; ok {
+					baseline = append(baseline, v)
+				}
+			}
+		} else {
+			for day := 1; day <= rollingDays; day++ {
+				if v, ok := values[dayKey(p.Day.AddDate(0, 0, -day))]; ok {
+					baseline = append(baseline, v)
+				}
+			}
+		}
+		if len(baseline) < minimum {
+			continue
+		}
+
+		expected := median(baseline)
+		deviations := make([]float64, len(baseline))
+		var total float64
+		for i, v := range baseline {
+			deviations[i] = math.Abs(v - expected)
+			total += deviations[i]
+		}
+		deviation := madScale * median(deviations)
+		if deviation == 0 {
+			deviation = meanScale * total / float64(len(baseline))
+		}
+		deviation = math.Max(deviation, math.Max(minDeviation, minRelativeDeviation*math.Abs(expected)))
+
+		scores = append(scores, Score{
+			Point:     p,
+			Expected:  expected,
+			Deviation: deviation,
+			Score:     (p.Value - expected) / deviation,
+		})
+	}
+	return scores
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func dayKey(day time.Time) string {
+	return day.Format("2006-01-02")
+}
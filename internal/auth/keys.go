@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ScopeGithub = "read:github"
+	ScopeJira   = "read:jira"
+	ScopeAdmin  = "admin"
+)
+
+// Scopes lists every scope a key can be granted. Admin implies the others.
+var Scopes = []string{ScopeGithub, ScopeJira, ScopeAdmin}
+
+const keyPrefix = "cp_"
+
+var ErrInvalidKey = errors.New("invalid API key")
+
+// KeyStore mints API keys and looks them up by their hash.
+type KeyStore struct {
+	db *sql.DB
+}
+
+func NewKeyStore(db *sql.DB) *KeyStore {
+	return &KeyStore{db: db}
+}
+
+// ValidateScopes rejects unknown or missing scopes.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range Scopes {
+			if scope == s {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown scope %q, expected %s", scope, strings.Join(Scopes, ", "))
+		}
+	}
+	return nil
+}
+
+// Create mints a key and returns it along with its stored description. The
+// key itself cannot be recovered later.
+func (s *KeyStore) Create(name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	id := make([]byte, 4)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	prefix := keyPrefix + hex.EncodeToString(id)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := &models.APIKey{Name: name, Prefix: prefix, Scopes: scopes, ExpiresAt: expiresAt}
+	err := s.db.QueryRow(`
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		name, prefix, hashKey(key), pq.Array(scopes), expiresAt).Scan(&apiKey.ID, &apiKey.CreatedAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	return key, apiKey, nil
+}
+
+func (s *KeyStore) List() ([]models.APIKey, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at
+		FROM api_keys
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt,
+			&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a key; it reports false when no active key has the id.
+func (s *KeyStore) Revoke(id int) (bool, error) {
+	result, err := s.db.Exec(`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Authenticate returns the active key matching a presented secret. Last use
+// is recorded at most once a minute per key.
+func (s *KeyStore) Authenticate(key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	var apiKey models.APIKey
+	err := s.db.QueryRow(`
+		SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW())`, hashKey(key)).Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix,
+		pq.Array(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.ExpiresAt, &apiKey.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > time.Minute {
+		if _, err := s.db.Exec(`UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+// hashKey hashes a key for storage. Keys are random, so an unsalted fast hash
+// is enough to keep a database dump from revealing them.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SessionCookie holds the session token of users signed in through OIDC.
+const SessionCookie = "code_pulse_session"
+
+// Principal is the caller of an authenticated request. Restricted principals
+// only see data of their teams; API keys and admins are not restricted.
+type Principal struct {
+	Name       string   `json:"name"`
+	Email      string   `json:"email,omitempty"`
+	Role       string   `json:"role,omitempty"`
+	Scopes     []string `json:"scopes"`
+	Teams      []string `json:"teams"`
+	Restricted bool     `json:"restricted"`
+}
+
+// InTeam reports whether the principal belongs to a team.
+func (p *Principal) InTeam(team string) bool {
+	for _, t := range p.Teams {
+		if t == team {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal was granted a scope, directly or
+// through admin.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// FromContext returns the principal of a request, or nil when authentication
+// is disabled.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
+
+// Authenticator guards handlers with API keys sent as a bearer token or in
+// the X-API-Key header, or with the session cookie of a signed-in user.
+type Authenticator struct {
+	keys     *KeyStore
+	sessions *SessionStore
+	enabled  bool
+}
+
+func NewAuthenticator(keys *KeyStore, sessions *SessionStore, enabled bool) *Authenticator {
+	return &Authenticator{keys: keys, sessions: sessions, enabled: enabled}
+}
+
+// Require wraps a handler so that it only serves callers holding every one of
+// the given scopes.
+func (a *Authenticator) Require(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next(w, r)
+			return
+		}
+
+		principal, err := a.authenticate(r)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidKey) && !errors.Is(err, ErrInvalidSession) {
+				log.Printf("Error authenticating request to %s: %v", r.URL.Path, err)
+				http.Error(w, "Authentication failed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="code-pulse"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				http.Error(w, "Forbidden: requires scope "+scope, http.StatusForbidden)
+				return
+			}
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, principal)))
+	}
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if authorization := r.Header.Get("Authorization"); key == "" && authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, ErrInvalidKey
+		}
+		key = strings.TrimSpace(token)
+	}
+	if key == "" {
+		cookie, err := r.Cookie(SessionCookie)
+		if err != nil || cookie.Value == "" {
+			return nil, ErrInvalidKey
+		}
+		return a.sessions.Lookup(cookie.Value)
+	}
+
+	apiKey, err := a.keys.Authenticate(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Name: apiKey.Name, Scopes: apiKey.Scopes}, nil
+}
+
+// Unrestricted wraps a handler that cannot filter its data by team, turning
+// away principals limited to their teams.
+func Unrestricted(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if principal := FromContext(r.Context()); principal != nil && principal.Restricted {
+			http.Error(w, "Forbidden: not available to team-restricted users", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
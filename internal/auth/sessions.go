@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Roles of users signed in through OIDC. Admins see every team and manage
+// keys; viewers read the data of their own teams.
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
+// RoleScopes returns the scopes a role grants.
+func RoleScopes(role string) []string {
+	switch role {
+	case RoleAdmin:
+		return []string{ScopeAdmin}
+	case RoleViewer:
+		return []string{ScopeGithub, ScopeJira}
+	}
+	return nil
+}
+
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// SessionStore keeps browser sessions, identified by a random token of which
+// only the hash is stored.
+type SessionStore struct {
+	db *sql.DB
+}
+
+func NewSessionStore(db *sql.DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+// Create starts a session for a signed-in user and returns its token.
+// Expired sessions are cleaned up on the way.
+func (s *SessionStore) Create(subject string, principal *Principal, ttl time.Duration) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate session: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at < NOW()`); err != nil {
+		return "", fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO sessions (token_hash, subject, name, email, role, teams, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		hashKey(token), subject, principal.Name, principal.Email, principal.Role,
+		pq.Array(principal.Teams), time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return token, nil
+}
+
+// Lookup returns the principal of an unexpired session.
+func (s *SessionStore) Lookup(token string) (*Principal, error) {
+	var principal Principal
+	err := s.db.QueryRow(`
+		SELECT name, email, role, teams
+		FROM sessions
+		WHERE token_hash = $1
+		AND expires_at > NOW()`, hashKey(token)).Scan(&principal.Name, &principal.Email, &principal.Role,
+		pq.Array(&principal.Teams))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	principal.Scopes = RoleScopes(principal.Role)
+	principal.Restricted = principal.Role != RoleAdmin
+	return &principal, nil
+}
+
+func (s *SessionStore) Delete(token string) error {
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE token_hash = $1`, hashKey(token)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
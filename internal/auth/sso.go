@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/pkg/oidc"
+)
+
+const (
+	loginCookie = "code_pulse_oidc"
+	loginTTL    = 10 * time.Minute
+)
+
+// loginState is kept in a short-lived cookie between the redirect to the
+// provider and the callback.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// SSO signs users in through an OpenID Connect provider and maps their role
+// and team claims onto a session.
+type SSO struct {
+	client      *oidc.Client
+	sessions    *SessionStore
+	roleClaim   string
+	teamClaim   string
+	roleMapping map[string]string
+	defaultRole string
+	ttl         time.Duration
+	secure      bool
+}
+
+// NewSSO returns nil when no OIDC issuer is configured.
+func NewSSO(sessions *SessionStore, cfg *config.Config) *SSO {
+	if cfg.OIDCIssuer == "" {
+		return nil
+	}
+	return &SSO{
+		client: oidc.NewClient(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret,
+			cfg.OIDCRedirectURL, cfg.OIDCScopes),
+		sessions:    sessions,
+		roleClaim:   cfg.OIDCRoleClaim,
+		teamClaim:   cfg.OIDCTeamClaim,
+		roleMapping: cfg.OIDCRoleMapping,
+		defaultRole: cfg.OIDCDefaultRole,
+		ttl:         time.Duration(cfg.SessionTTLHours) * time.Hour,
+		secure:      strings.HasPrefix(cfg.OIDCRedirectURL, "https://"),
+	}
+}
+
+// Login redirects to the provider; return_to names the page to come back to.
+func (s *SSO) Login(w http.ResponseWriter, r *http.Request) {
+	state := loginState{ReturnTo: safeReturnTo(r.URL.Query().Get("return_to"))}
+	var err error
+	for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		if *value, err = oidc.RandomString(); err != nil {
+			http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	redirect, err := s.client.AuthCodeURL(state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		log.Printf("Error starting OIDC sign-in: %v", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	data, _ := json.Marshal(state)
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(data),
+		Path:     "/auth/",
+		MaxAge:   int(loginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// Callback completes a sign-in started by Login and sets the session cookie.
+func (s *SSO) Callback(w http.ResponseWriter, r *http.Request) {
+	if providerError := r.URL.Query().Get("error"); providerError != "" {
+		http.Error(w, fmt.Sprintf("Sign-in failed: %s %s", providerError, r.URL.Query().Get("error_description")),
+			http.StatusUnauthorized)
+		return
+	}
+
+	state, err := readLoginState(r)
+	if err != nil || state.State == "" || r.URL.Query().Get("state") != state.State {
+		http.Error(w, "Sign-in failed: invalid or expired state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/auth/", MaxAge: -1})
+
+	token, err := s.client.Exchange(r.URL.Query().Get("code"), state.Verifier)
+	if err != nil {
+		log.Printf("Error exchanging OIDC code: %v", err)
+		http.Error(w, "Sign-in failed: could not redeem code", http.StatusBadGateway)
+		return
+	}
+
+	claims, err := s.client.VerifyIDToken(token.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("Error verifying OIDC ID token: %v", err)
+		http.Error(w, "Sign-in failed: invalid ID token", http.StatusUnauthorized)
+		return
+	}
+
+	principal, err := s.principal(claims)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Sign-in failed: %v", err), http.StatusForbidden)
+		return
+	}
+
+	session, err := s.sessions.Create(claims.Subject, principal, s.ttl)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		http.Error(w, "Sign-in failed", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    session,
+		Path:     "/",
+		Expires:  time.Now().Add(s.ttl),
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, state.ReturnTo, http.StatusFound)
+}
+
+// Logout ends the session of the caller.
+func (s *SSO) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		if err := s.sessions.Delete(cookie.Value); err != nil {
+			log.Printf("Error deleting session: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// principal maps role claims through OIDC_ROLE_MAPPING, preferring admin,
+// and falls back to the default role. Team claims name teams directly.
+func (s *SSO) principal(claims *oidc.Claims) (*Principal, error) {
+	role := ""
+	for _, value := range claims.Strings(s.roleClaim) {
+		mapped, ok := s.roleMapping[value]
+		if !ok {
+			continue
+		}
+		if mapped == RoleAdmin || role == "" {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = s.defaultRole
+	}
+	if RoleScopes(role) == nil {
+		return nil, fmt.Errorf("no role granted to %s", claims.Subject)
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name = claims.Email
+	}
+
+	teams := claims.Strings(s.teamClaim)
+	if teams == nil {
+		teams = []string{}
+	}
+
+	return &Principal{
+		Name:       name,
+		Email:      claims.Email,
+		Role:       role,
+		Scopes:     RoleScopes(role),
+		Teams:      teams,
+		Restricted: role != RoleAdmin,
+	}, nil
+}
+
+func readLoginState(r *http.Request) (*loginState, error) {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	var state loginState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// safeReturnTo only allows paths on this server, so that sign-in cannot be
+// used to redirect elsewhere.
+func safeReturnTo(returnTo string) string {
+	u, err := url.Parse(returnTo)
+	if err != nil || returnTo == "" || u.IsAbs() || u.Host != "" ||
+		!strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.Contains(returnTo, "\\") {
+		return "/"
+	}
+	return returnTo
+}
+
+// Me describes the caller.
+func Me(w http.ResponseWriter, r *http.Request) {
+	principal := FromContext(r.Context())
+	if principal == nil {
+		principal = &Principal{Name: "anonymous", Scopes: Scopes, Teams: []string{}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(principal)
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/database/dbtest"
+	"code-pulse/pkg/oidc"
+	"code-pulse/pkg/oidc/oidctest"
+)
+
+func newTestSSO(issuer *oidctest.Issuer, sessions *SessionStore) *SSO {
+	return NewSSO(sessions, &config.Config{
+		OIDCIssuer:       issuer.URL,
+		OIDCClientID:     oidctest.ClientID,
+		OIDCClientSecret: oidctest.ClientSecret,
+		OIDCRedirectURL:  "http://pulse.example.com/auth/callback",
+		OIDCScopes:       []string{"openid", "profile", "email"},
+		OIDCRoleClaim:    "realm_access.roles",
+		OIDCTeamClaim:    "groups",
+		OIDCRoleMapping:  map[string]string{"pulse-admins": RoleAdmin, "engineers": RoleViewer},
+		OIDCDefaultRole:  "",
+		SessionTTLHours:  8,
+	})
+}
+
+// login runs Login and records the nonce the provider is sent, returning the
+// login cookie and the state to call back with.
+func login(t *testing.T, sso *SSO, issuer *oidctest.Issuer) (*http.Cookie, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	sso.Login(rec, httptest.NewRequest("GET", "/auth/login?return_to=/teams/payments", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d", rec.Code)
+	}
+
+	redirect, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redirect.Path != "/authorize" || redirect.Query().Get("client_id") != oidctest.ClientID {
+		t.Fatalf("login redirects to %s", redirect)
+	}
+	issuer.Nonce = redirect.Query().Get("nonce")
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == loginCookie {
+			return cookie, redirect.Query().Get("state")
+		}
+	}
+	t.Fatal("login set no state cookie")
+	return nil, ""
+}
+
+func callback(sso *SSO, cookie *http.Cookie, state string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/auth/callback?code="+oidctest.Code+"&state="+url.QueryEscape(state), nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	sso.Callback(rec, req)
+	return rec
+}
+
+func validClaims(issuer *oidctest.Issuer) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":          issuer.URL,
+		"sub":          "user-1",
+		"aud":          oidctest.ClientID,
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"email":        "ada@example.com",
+		"name":         "Ada",
+		"realm_access": map[string]interface{}{"roles": []string{"engineers"}},
+		"groups":       []string{"payments", "platform"},
+	}
+}
+
+func TestCallbackRejects(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+	sso := newTestSSO(issuer, nil)
+
+	tests := []struct {
+		name   string
+		state  func(string) string
+		claims func(map[string]interface{})
+		status int
+	}{
+		{"state", func(string) string { return "forged" }, func(map[string]interface{}) {}, http.StatusBadRequest},
+		{"nonce", nil, func(c map[string]interface{}) { c["nonce"] = "replayed" }, http.StatusUnauthorized},
+		{"audience", nil, func(c map[string]interface{}) { c["aud"] = "other-client" }, http.StatusUnauthorized},
+		{"expiry", nil, func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, http.StatusUnauthorized},
+		{"role", nil, func(c map[string]interface{}) { delete(c, "realm_access") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, state := login(t, sso, issuer)
+			if tt.state != nil {
+				state = tt.state(state)
+			}
+			issuer.Claims = validClaims(issuer)
+			tt.claims(issuer.Claims)
+
+			rec := callback(sso, cookie, state)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrincipalMapsClaims(t *testing.T) {
+	sso := &SSO{
+		roleClaim:   "realm_access.roles",
+		teamClaim:   "groups",
+		roleMapping: map[string]string{"pulse-admins": RoleAdmin, "engineers": RoleViewer},
+		defaultRole: RoleViewer,
+	}
+
+	tests := []struct {
+		name       string
+		raw        map[string]interface{}
+		role       string
+		teams      []string
+		restricted bool
+	}{
+		{
+			name:       "viewer with teams",
+			raw:        map[string]interface{}{"realm_access": map[string]interface{}{"roles": []interface{}{"engineers"}}, "groups": []interface{}{"payments"}},
+			role:       RoleViewer,
+			teams:      []string{"payments"},
+			restricted: true,
+		},
+		{
+			name:       "admin wins over viewer",
+			raw:        map[string]interface{}{"realm_access": map[string]interface{}{"roles": []interface{}{"engineers", "pulse-admins"}}},
+			role:       RoleAdmin,
+			teams:      []string{},
+			restricted: false,
+		},
+		{
+			name:       "default role",
+			raw:        map[string]interface{}{"realm_access": map[string]interface{}{"roles": []interface{}{"unmapped"}}, "groups": "platform"},
+			role:       RoleViewer,
+			teams:      []string{"platform"},
+			restricted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := sso.principal(&oidc.Claims{Subject: "user-1", PreferredUsername: "ada", Raw: tt.raw})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.Role != tt.role || principal.Restricted != tt.restricted || principal.Name != "ada" {
+				t.Errorf("principal = %+v", principal)
+			}
+			if strings.Join(principal.Teams, ",") != strings.Join(tt.teams, ",") {
+				t.Errorf("teams = %v, want %v", principal.Teams, tt.teams)
+			}
+		})
+	}
+
+	sso.defaultRole = ""
+	if _, err := sso.principal(&oidc.Claims{Subject: "user-1", Raw: map[string]interface{}{}}); err == nil {
+		t.Error("expected no role to be granted without a mapped claim or default role")
+	}
+}
+
+func TestCallbackCreatesSession(t *testing.T) {
+	db := dbtest.Open(t)
+
+	issuer := oidctest.NewIssuer(t)
+	sessions := NewSessionStore(db)
+	sso := newTestSSO(issuer, sessions)
+
+	cookie, state := login(t, sso, issuer)
+	issuer.Claims = validClaims(issuer)
+
+	rec := callback(sso, cookie, state)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/teams/payments" {
+		t.Fatalf("callback = %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+
+	var session string
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == SessionCookie {
+			session = c.Value
+		}
+	}
+	if session == "" {
+		t.Fatal("callback set no session cookie")
+	}
+	defer sessions.Delete(session)
+
+	principal, err := sessions.Lookup(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Name != "Ada" || principal.Role != RoleViewer || !principal.Restricted ||
+		strings.Join(principal.Teams, ",") != "payments,platform" {
+		t.Errorf("principal = %+v", principal)
+	}
+}
@@ -0,0 +1,458 @@
+package catalog
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNotFound = errors.New("not found in catalog")
+	ErrInvalid  = errors.New("invalid catalog")
+)
+
+// Resources lists what a team or service is made of. Jira scopes are project
+// keys (PAY) or project keys with a component (PAY/Checkout), as matched by
+// the ticket_in_scope SQL function.
+type Resources struct {
+	Repositories      []string
+	SonarqubeProjects []string
+	JiraScopes        []string
+	IncidentServices  []string
+}
+
+func newResources() *Resources {
+	return &Resources{
+		Repositories:      []string{},
+		SonarqubeProjects: []string{},
+		JiraScopes:        []string{},
+		IncidentServices:  []string{},
+	}
+}
+
+func (r *Resources) add(service *models.Service) {
+	r.Repositories = appendUnique(r.Repositories, service.Repositories...)
+	r.SonarqubeProjects = appendUnique(r.SonarqubeProjects, service.SonarqubeProjects...)
+	r.IncidentServices = appendUnique(r.IncidentServices, service.IncidentServices...)
+	for _, project := range service.JiraProjects {
+		if len(service.JiraComponents) == 0 {
+			r.JiraScopes = appendUnique(r.JiraScopes, project)
+			continue
+		}
+		for _, component := range service.JiraComponents {
+			r.JiraScopes = appendUnique(r.JiraScopes, project+"/"+component)
+		}
+	}
+}
+
+// Store keeps the catalog in the database.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Parse reads a catalog in YAML. Unknown keys are rejected so that typos do
+// not silently drop a mapping.
+func Parse(data []byte) (*models.Catalog, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var c models.Catalog
+	if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := Validate(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// LoadFile reads the catalog YAML file named by CATALOG_FILE.
+func LoadFile(path string) (*models.Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Marshal writes a catalog in the YAML format read by Parse.
+func Marshal(c *models.Catalog) ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Validate checks names and normalizes missing lists to empty ones. Teams of
+// services are checked when they are saved, as they may already be stored.
+func Validate(c *models.Catalog) error {
+	teams := make(map[string]bool)
+	for i := range c.Teams {
+		if err := ValidateTeam(&c.Teams[i]); err != nil {
+			return err
+		}
+		if teams[c.Teams[i].Name] {
+			return fmt.Errorf("%w: team %s is listed twice", ErrInvalid, c.Teams[i].Name)
+		}
+		teams[c.Teams[i].Name] = true
+	}
+
+	services := make(map[string]bool)
+	for i := range c.Services {
+		service := &c.Services[i]
+		if err := ValidateService(service); err != nil {
+			return err
+		}
+		if services[service.Name] {
+			return fmt.Errorf("%w: service %s is listed twice", ErrInvalid, service.Name)
+		}
+		services[service.Name] = true
+	}
+	return nil
+}
+
+func ValidateTeam(team *models.Team) error {
+	team.Name = strings.TrimSpace(team.Name)
+	if team.Name == "" {
+		return fmt.Errorf("%w: team name is required", ErrInvalid)
+	}
+	if team.Members == nil {
+		team.Members = []models.TeamMember{}
+	}
+
+	emails := make(map[string]bool)
+	for i := range team.Members {
+		member := &team.Members[i]
+		member.Email = strings.ToLower(strings.TrimSpace(member.Email))
+		if member.Email == "" {
+			return fmt.Errorf("%w: member of team %s has no email", ErrInvalid, team.Name)
+		}
+		if emails[member.Email] {
+			return fmt.Errorf("%w: %s is listed twice in team %s", ErrInvalid, member.Email, team.Name)
+		}
+		emails[member.Email] = true
+	}
+	return nil
+}
+
+func ValidateService(service *models.Service) error {
+	service.Name = strings.TrimSpace(service.Name)
+	if service.Name == "" {
+		return fmt.Errorf("%w: service name is required", ErrInvalid)
+	}
+	if len(service.JiraComponents) > 0 && len(service.JiraProjects) == 0 {
+		return fmt.Errorf("%w: service %s lists Jira components without a Jira project", ErrInvalid, service.Name)
+	}
+	for _, list := range []*[]string{&service.Repositories, &service.SonarqubeProjects, &service.JiraProjects,
+		&service.JiraComponents, &service.IncidentServices} {
+		*list = appendUnique([]string{}, *list...)
+	}
+	return nil
+}
+
+// Get returns the whole catalog.
+func (s *Store) Get() (*models.Catalog, error) {
+	teams, err := s.Teams()
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.Services()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Catalog{Teams: teams, Services: services}, nil
+}
+
+// Teams lists every team with its members.
+func (s *Store) Teams() ([]models.Team, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.name, t.description, t.updated_at, m.name, m.email, m.role
+		FROM teams t
+		LEFT JOIN team_members m ON m.team_id = t.id
+		ORDER BY t.name, m.email`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var team models.Team
+		var name, email, role sql.NullString
+		if err := rows.Scan(&team.ID, &team.Name, &team.Description, &team.UpdatedAt, &name, &email, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		if len(teams) == 0 || teams[len(teams)-1].ID != team.ID {
+			team.Members = []models.TeamMember{}
+			teams = append(teams, team)
+		}
+		if email.Valid {
+			last := &teams[len(teams)-1]
+			last.Members = append(last.Members, models.TeamMember{Name: name.String, Email: email.String, Role: role.String})
+		}
+	}
+	return teams, rows.Err()
+}
+
+// Services lists every service.
+func (s *Store) Services() ([]models.Service, error) {
+	rows, err := s.db.Query(`
+		SELECT s.id, s.name, COALESCE(t.name, ''), s.description, s.repositories, s.sonarqube_projects,
+			s.jira_projects, s.jira_components, s.incident_services, s.updated_at
+		FROM services s
+		LEFT JOIN teams t ON t.id = s.team_id
+		ORDER BY s.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		var service models.Service
+		if err := rows.Scan(&service.ID, &service.Name, &service.Team, &service.Description,
+			pq.Array(&service.Repositories), pq.Array(&service.SonarqubeProjects), pq.Array(&service.JiraProjects),
+			pq.Array(&service.JiraComponents), pq.Array(&service.IncidentServices), &service.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan service: %w", err)
+		}
+		services = append(services, service)
+	}
+	return services, rows.Err()
+}
+
+// Import saves every team and service of a catalog in one transaction. With
+// prune, teams and services missing from it are deleted, making the catalog
+// replace what is stored.
+func (s *Store) Import(c *models.Catalog, prune bool) error {
+	if err := Validate(c); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	teams := make([]string, 0, len(c.Teams))
+	for i := range c.Teams {
+		if err := saveTeam(tx, &c.Teams[i]); err != nil {
+			return err
+		}
+		teams = append(teams, c.Teams[i].Name)
+	}
+	services := make([]string, 0, len(c.Services))
+	for i := range c.Services {
+		if err := saveService(tx, &c.Services[i]); err != nil {
+			return err
+		}
+		services = append(services, c.Services[i].Name)
+	}
+
+	if prune {
+		if _, err := tx.Exec(`DELETE FROM services WHERE name <> ALL($1)`, pq.Array(services)); err != nil {
+			return fmt.Errorf("failed to delete services: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM teams WHERE name <> ALL($1)`, pq.Array(teams)); err != nil {
+			return fmt.Errorf("failed to delete teams: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit catalog: %w", err)
+	}
+	return nil
+}
+
+// SaveTeam creates or updates a team by name, replacing its members.
+func (s *Store) SaveTeam(team *models.Team) error {
+	if err := ValidateTeam(team); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveTeam(tx, team); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit team: %w", err)
+	}
+	return nil
+}
+
+// SaveService creates or updates a service by name. Its team must exist.
+func (s *Store) SaveService(service *models.Service) error {
+	if err := ValidateService(service); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveService(tx, service); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit service: %w", err)
+	}
+	return nil
+}
+
+func saveTeam(tx *sql.Tx, team *models.Team) error {
+	err := tx.QueryRow(`
+		INSERT INTO teams (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET description = $2, updated_at = NOW()
+		RETURNING id, updated_at`, team.Name, team.Description).Scan(&team.ID, &team.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save team %s: %w", team.Name, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM team_members WHERE team_id = $1`, team.ID); err != nil {
+		return fmt.Errorf("failed to replace members of team %s: %w", team.Name, err)
+	}
+	for _, member := range team.Members {
+		_, err := tx.Exec(`
+			INSERT INTO team_members (team_id, name, email, role)
+			VALUES ($1, $2, $3, $4)`, team.ID, member.Name, member.Email, member.Role)
+		if err != nil {
+			return fmt.Errorf("failed to save member %s of team %s: %w", member.Email, team.Name, err)
+		}
+	}
+	return nil
+}
+
+func saveService(tx *sql.Tx, service *models.Service) error {
+	var teamID sql.NullInt64
+	if service.Team != "" {
+		err := tx.QueryRow(`SELECT id FROM teams WHERE name = $1`, service.Team).Scan(&teamID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: service %s belongs to unknown team %s", ErrInvalid, service.Name, service.Team)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up team %s: %w", service.Team, err)
+		}
+	}
+
+	err := tx.QueryRow(`
+		INSERT INTO services (name, team_id, description, repositories, sonarqube_projects, jira_projects,
+			jira_components, incident_services)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (name) DO UPDATE SET
+			team_id = $2, description = $3, repositories = $4, sonarqube_projects = $5, jira_projects = $6,
+			jira_components = $7, incident_services = $8, updated_at = NOW()
+		RETURNING id, updated_at`,
+		service.Name, teamID, service.Description, pq.Array(service.Repositories),
+		pq.Array(service.SonarqubeProjects), pq.Array(service.JiraProjects), pq.Array(service.JiraComponents),
+		pq.Array(service.IncidentServices)).Scan(&service.ID, &service.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save service %s: %w", service.Name, err)
+	}
+	return nil
+}
+
+// DeleteTeam deletes a team and its members. Its services are kept without
+// a team.
+func (s *Store) DeleteTeam(name string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM teams WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete team: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+func (s *Store) DeleteService(name string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM services WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete service: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// TeamResources returns the resources of every service a team owns.
+func (s *Store) TeamResources(name string) (*Resources, error) {
+	return s.resources(`
+		SELECT s.repositories, s.sonarqube_projects, s.jira_projects, s.jira_components, s.incident_services
+		FROM teams t
+		LEFT JOIN services s ON s.team_id = t.id
+		WHERE t.name = $1`, name)
+}
+
+// ServiceResources returns the resources of a service.
+func (s *Store) ServiceResources(name string) (*Resources, error) {
+	return s.resources(`
+		SELECT repositories, sonarqube_projects, jira_projects, jira_components, incident_services
+		FROM services
+		WHERE name = $1`, name)
+}
+
+// resources merges the services a query returns, failing with ErrNotFound
+// when it returns no rows. A team without services returns a single row of
+// NULLs and owns nothing.
+func (s *Store) resources(query, name string) (*Resources, error) {
+	rows, err := s.db.Query(query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	resources := newResources()
+	found := false
+	for rows.Next() {
+		var service models.Service
+		if err := rows.Scan(pq.Array(&service.Repositories), pq.Array(&service.SonarqubeProjects),
+			pq.Array(&service.JiraProjects), pq.Array(&service.JiraComponents),
+			pq.Array(&service.IncidentServices)); err != nil {
+			return nil, fmt.Errorf("failed to scan resources of %s: %w", name, err)
+		}
+		resources.add(&service)
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return resources, nil
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		seen := false
+		for _, v := range list {
+			if v == value {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			list = append(list, value)
+		}
+	}
+	return list
+}
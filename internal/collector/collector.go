@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Collector is a source of engineering data. Validate reports whether the
+// source is configured well enough to run, Collect gathers everything that
+// changed since the given time (zero meaning a full collection) and Health
+// checks that the upstream API is reachable with the configured credentials.
+type Collector interface {
+	Name() string
+	Validate() error
+	Collect(ctx context.Context, since time.Time) error
+	Health(ctx context.Context) error
+}
+
+// RateLimited is implemented by collectors whose API enforces a request quota.
+type RateLimited interface {
+	RateLimitRemaining() (int, bool)
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.collectors[c.Name()]; exists {
+		return fmt.Errorf("collector %q already registered", c.Name())
+	}
+
+	r.collectors[c.Name()] = c
+	return nil
+}
+
+func (r *Registry) Get(name string) (Collector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.collectors[name]
+	return c, ok
+}
+
+// All returns the registered collectors ordered by name.
+func (r *Registry) All() []Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].Name() < collectors[j].Name()
+	})
+
+	return collectors
+}
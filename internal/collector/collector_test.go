@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type stubCollector struct {
+	name string
+}
+
+func (c stubCollector) Name() string                                   { return c.name }
+func (c stubCollector) Validate() error                                { return nil }
+func (c stubCollector) Collect(ctx context.Context, _ time.Time) error { return nil }
+func (c stubCollector) Health(ctx context.Context) error               { return nil }
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		register []string
+		wantErr  []bool
+		want     []string
+	}{
+		{
+			name:     "empty",
+			register: nil,
+			want:     []string{},
+		},
+		{
+			name:     "ordered by name",
+			register: []string{"sonarqube", "github", "jira"},
+			wantErr:  []bool{false, false, false},
+			want:     []string{"github", "jira", "sonarqube"},
+		},
+		{
+			name:     "duplicate name",
+			register: []string{"github", "jira", "github"},
+			wantErr:  []bool{false, false, true},
+			want:     []string{"github", "jira"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			for i, name := range tt.register {
+				err := registry.Register(stubCollector{name: name})
+				if (err != nil) != tt.wantErr[i] {
+					t.Errorf("Register(%q) error = %v, want error %v", name, err, tt.wantErr[i])
+				}
+			}
+
+			all := registry.All()
+			if len(all) != len(tt.want) {
+				t.Fatalf("All() returned %d collectors, want %d", len(all), len(tt.want))
+			}
+			for i, c := range all {
+				if c.Name() != tt.want[i] {
+					t.Errorf("All()[%d] = %q, want %q", i, c.Name(), tt.want[i])
+				}
+				if got, ok := registry.Get(tt.want[i]); !ok || got.Name() != tt.want[i] {
+					t.Errorf("Get(%q) = %v, %v", tt.want[i], got, ok)
+				}
+			}
+
+			if _, ok := registry.Get("missing"); ok {
+				t.Error("Get(missing) found a collector")
+			}
+		})
+	}
+}
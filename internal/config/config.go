@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,13 +15,86 @@ type Config struct {
 	GithubOrg      string
 	GithubRepos    []RepoConfig
 	
-	SonarqubeURL   string
-	SonarqubeToken string
+	SonarqubeURL      string
+	SonarqubeToken    string
+	SonarqubeProjects []SonarqubeProjectConfig
+
+	SonarqubeMetricKeys            []string
+	SonarqubePullRequestMetricKeys []string
+	SonarqubeTreeMetricKeys        []string
+	SonarqubeTreeQualifiers        []string
+	SonarqubeTreeDepth             int
+
+	GitlabURL      string
+	GitlabToken    string
+	GitlabProjects []string
+	GitlabGroups   []string
+
+	JenkinsURL      string
+	JenkinsUsername string
+	JenkinsToken    string
+	JenkinsJobs     []string
+
+	BitbucketURL          string
+	BitbucketUsername     string
+	BitbucketToken        string
+	BitbucketWorkspaces   []string
+	BitbucketRepositories []string
+
+	PagerdutyURL      string
+	PagerdutyToken    string
+	PagerdutyServices []IncidentServiceConfig
+
 	JiraURL        string
 	JiraEmail      string
 	JiraToken      string
+	JiraJQL        string
+
+	JiraSprintField   string
+	JiraEstimateField string
+
+	LinearURL   string
+	LinearToken string
+	LinearTeams []string
 	
+	DoraEnvironment   string
+	DoraIncidentLabel string
+
+	ScorecardMetrics map[string]ScorecardMetricConfig
+
+	AlertRules    []AlertRuleConfig
+	AlertChannels []AlertChannelConfig
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	DigestSchedule   string
+	DigestRecipients map[string][]string
+
+	MetricsWindowDays int
+
+	AuthEnabled bool
+
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	OIDCRoleClaim    string
+	OIDCTeamClaim    string
+	OIDCRoleMapping  map[string]string
+	OIDCDefaultRole  string
+	SessionTTLHours  int
+
+	Teams       []TeamConfig
+	CatalogFile string
+
 	CollectionSchedule string
+	CollectorSchedules map[string]string
+	EnabledCollectors  []string
 }
 
 type RepoConfig struct {
@@ -27,6 +102,61 @@ type RepoConfig struct {
 	Workflows []string `json:"workflows"`
 }
 
+// IncidentServiceConfig maps an incident service to the repository it runs.
+type IncidentServiceConfig struct {
+	ID         string `json:"id"`
+	Repository string `json:"repository"`
+}
+
+// TeamConfig lists the resources a team owns. Users signed in through OIDC
+// only see data of the teams in their team claim, unless they are admins.
+// Teams of the catalog grant access to their services as well.
+type TeamConfig struct {
+	Name              string   `json:"name"`
+	Repositories      []string `json:"repositories"`
+	SonarqubeProjects []string `json:"sonarqube_projects"`
+	JiraProjects      []string `json:"jira_projects"`
+}
+
+// ScorecardMetricConfig overrides the weight of a scorecard metric, zero to
+// leave it out, or its elite, high and medium thresholds.
+type ScorecardMetricConfig struct {
+	Weight     *float64  `json:"weight"`
+	Thresholds []float64 `json:"thresholds"`
+}
+
+// AlertRuleConfig fires when a metric crosses a threshold (above, below) or
+// moves against its baseline, the window before the current one (increase,
+// decrease, by a number; increase_percent, decrease_percent, by a share).
+// Repository, branch, workflow and project narrow the data it looks at.
+type AlertRuleConfig struct {
+	Name         string   `json:"name"`
+	Metric       string   `json:"metric"`
+	Condition    string   `json:"condition"`
+	Threshold    float64  `json:"threshold"`
+	WindowDays   int      `json:"window_days"`
+	BaselineDays int      `json:"baseline_days"`
+	Repository   string   `json:"repository"`
+	Branch       string   `json:"branch"`
+	Workflow     string   `json:"workflow"`
+	Project      string   `json:"project"`
+	Channels     []string `json:"channels"`
+}
+
+// AlertChannelConfig is where alerts are delivered: a generic JSON webhook
+// or a Slack-compatible incoming webhook.
+type AlertChannelConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type SonarqubeProjectConfig struct {
+	Key        string   `json:"key"`
+	Repository string   `json:"repository"`
+	Branches   []string `json:"branches"`
+}
+
 func Load() *Config {
 	cfg := &Config{
 		Port:               getEnv("PORT", "8080"),
@@ -37,11 +167,75 @@ func Load() *Config {
 		
 		SonarqubeURL:       getEnv("SONARQUBE_URL", ""),
 		SonarqubeToken:     getEnv("SONARQUBE_TOKEN", ""),
+		SonarqubeMetricKeys: getEnvList("SONARQUBE_METRICS",
+			"ncloc,coverage,duplicated_lines_density,bugs,vulnerabilities,code_smells,"+
+				"reliability_rating,security_rating,sqale_rating,"+
+				"new_coverage,new_duplicated_lines_density,new_bugs,new_vulnerabilities,new_code_smells"),
+		SonarqubePullRequestMetricKeys: getEnvList("SONARQUBE_PR_METRICS",
+			"new_lines,new_coverage,new_duplicated_lines_density,new_bugs,new_vulnerabilities,new_code_smells"),
+		SonarqubeTreeMetricKeys: getEnvList("SONARQUBE_TREE_METRICS", "ncloc,complexity,cognitive_complexity,coverage"),
+		SonarqubeTreeQualifiers: getEnvList("SONARQUBE_TREE_QUALIFIERS", "DIR,FIL"),
+		SonarqubeTreeDepth:      getEnvInt("SONARQUBE_TREE_DEPTH", 0),
+		GitlabURL:      getEnv("GITLAB_URL", "https://gitlab.com"),
+		GitlabToken:    getEnv("GITLAB_TOKEN", ""),
+		GitlabProjects: getEnvList("GITLAB_PROJECTS", ""),
+		GitlabGroups:   getEnvList("GITLAB_GROUPS", ""),
+
+		JenkinsURL:      getEnv("JENKINS_URL", ""),
+		JenkinsUsername: getEnv("JENKINS_USERNAME", ""),
+		JenkinsToken:    getEnv("JENKINS_TOKEN", ""),
+		JenkinsJobs:     getEnvList("JENKINS_JOBS", ""),
+
+		BitbucketURL:          getEnv("BITBUCKET_URL", "https://api.bitbucket.org"),
+		BitbucketUsername:     getEnv("BITBUCKET_USERNAME", ""),
+		BitbucketToken:        getEnv("BITBUCKET_TOKEN", ""),
+		BitbucketWorkspaces:   getEnvList("BITBUCKET_WORKSPACES", ""),
+		BitbucketRepositories: getEnvList("BITBUCKET_REPOSITORIES", ""),
+
+		PagerdutyURL:   getEnv("PAGERDUTY_URL", "https://api.pagerduty.com"),
+		PagerdutyToken: getEnv("PAGERDUTY_TOKEN", ""),
+
 		JiraURL:            getEnv("JIRA_URL", ""),
 		JiraEmail:          getEnv("JIRA_EMAIL", ""),
 		JiraToken:          getEnv("JIRA_TOKEN", ""),
+		JiraJQL:            getEnv("JIRA_JQL", ""),
+
+		JiraSprintField:   getEnv("JIRA_SPRINT_FIELD", "customfield_10020"),
+		JiraEstimateField: getEnv("JIRA_ESTIMATE_FIELD", "customfield_10016"),
+
+		LinearURL:   getEnv("LINEAR_URL", "https://api.linear.app/graphql"),
+		LinearToken: getEnv("LINEAR_TOKEN", ""),
+		LinearTeams: getEnvList("LINEAR_TEAMS", ""),
+		
+		DoraEnvironment:   getEnv("DORA_ENVIRONMENT", "production"),
+		DoraIncidentLabel: getEnv("DORA_INCIDENT_LABEL", "incident"),
+		
+		MetricsWindowDays: getEnvInt("METRICS_WINDOW_DAYS", 7),
+
+		AuthEnabled: getEnv("AUTH_ENABLED", "true") != "false",
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       getEnvList("OIDC_SCOPES", "openid,profile,email"),
+		OIDCRoleClaim:    getEnv("OIDC_ROLE_CLAIM", "roles"),
+		OIDCTeamClaim:    getEnv("OIDC_TEAM_CLAIM", "groups"),
+		OIDCDefaultRole:  getEnv("OIDC_DEFAULT_ROLE", "viewer"),
+		SessionTTLHours:  getEnvInt("SESSION_TTL_HOURS", 12),
+
+		CatalogFile: getEnv("CATALOG_FILE", ""),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		DigestSchedule: getEnv("DIGEST_SCHEDULE", "0 8 * * 1"), // Mondays at 08:00 by default
 		
 		CollectionSchedule: getEnv("COLLECTION_SCHEDULE", "0 */6 * * *"), // Every 6 hours by default
+		EnabledCollectors:  getEnvList("COLLECTORS", ""),
 	}
 	
 	if reposJSON := getEnv("GITHUB_REPOS", ""); reposJSON != "" {
@@ -51,6 +245,60 @@ func Load() *Config {
 		}
 	}
 	
+	if schedulesJSON := getEnv("COLLECTOR_SCHEDULES", ""); schedulesJSON != "" {
+		if err := json.Unmarshal([]byte(schedulesJSON), &cfg.CollectorSchedules); err != nil {
+			cfg.CollectorSchedules = map[string]string{}
+		}
+	}
+	
+	if projectsJSON := getEnv("SONARQUBE_PROJECTS", ""); projectsJSON != "" {
+		if err := json.Unmarshal([]byte(projectsJSON), &cfg.SonarqubeProjects); err != nil {
+			cfg.SonarqubeProjects = []SonarqubeProjectConfig{}
+		}
+	}
+	
+	if servicesJSON := getEnv("PAGERDUTY_SERVICES", ""); servicesJSON != "" {
+		if err := json.Unmarshal([]byte(servicesJSON), &cfg.PagerdutyServices); err != nil {
+			cfg.PagerdutyServices = []IncidentServiceConfig{}
+		}
+	}
+	
+	if mappingJSON := getEnv("OIDC_ROLE_MAPPING", ""); mappingJSON != "" {
+		if err := json.Unmarshal([]byte(mappingJSON), &cfg.OIDCRoleMapping); err != nil {
+			cfg.OIDCRoleMapping = map[string]string{}
+		}
+	}
+	
+	if scorecardJSON := getEnv("SCORECARD_METRICS", ""); scorecardJSON != "" {
+		if err := json.Unmarshal([]byte(scorecardJSON), &cfg.ScorecardMetrics); err != nil {
+			cfg.ScorecardMetrics = map[string]ScorecardMetricConfig{}
+		}
+	}
+	
+	if rulesJSON := getEnv("ALERT_RULES", ""); rulesJSON != "" {
+		if err := json.Unmarshal([]byte(rulesJSON), &cfg.AlertRules); err != nil {
+			cfg.AlertRules = []AlertRuleConfig{}
+		}
+	}
+	
+	if channelsJSON := getEnv("ALERT_CHANNELS", ""); channelsJSON != "" {
+		if err := json.Unmarshal([]byte(channelsJSON), &cfg.AlertChannels); err != nil {
+			cfg.AlertChannels = []AlertChannelConfig{}
+		}
+	}
+	
+	if recipientsJSON := getEnv("DIGEST_RECIPIENTS", ""); recipientsJSON != "" {
+		if err := json.Unmarshal([]byte(recipientsJSON), &cfg.DigestRecipients); err != nil {
+			cfg.DigestRecipients = map[string][]string{}
+		}
+	}
+	
+	if teamsJSON := getEnv("TEAMS", ""); teamsJSON != "" {
+		if err := json.Unmarshal([]byte(teamsJSON), &cfg.Teams); err != nil {
+			cfg.Teams = []TeamConfig{}
+		}
+	}
+	
 	return cfg
 }
 
@@ -59,4 +307,44 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// CollectorEnabled reports whether a collector is listed in COLLECTORS. An
+// empty list enables every collector.
+func (c *Config) CollectorEnabled(name string) bool {
+	if len(c.EnabledCollectors) == 0 {
+		return true
+	}
+	for _, enabled := range c.EnabledCollectors {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectorSchedule returns the cron schedule of a collector, falling back to
+// COLLECTION_SCHEDULE.
+func (c *Config) CollectorSchedule(name string) string {
+	if schedule, ok := c.CollectorSchedules[name]; ok {
+		return schedule
+	}
+	return c.CollectionSchedule
+}
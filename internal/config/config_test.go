@@ -0,0 +1,72 @@
+package config
+
+import "testing"
+
+func TestCollectorSelection(t *testing.T) {
+	tests := []struct {
+		name         string
+		collectors   string
+		schedules    string
+		collector    string
+		wantEnabled  bool
+		wantSchedule string
+	}{
+		{
+			name:         "all enabled by default",
+			collector:    "github",
+			wantEnabled:  true,
+			wantSchedule: "0 */6 * * *",
+		},
+		{
+			name:         "listed",
+			collectors:   "github, jira",
+			collector:    "jira",
+			wantEnabled:  true,
+			wantSchedule: "0 */6 * * *",
+		},
+		{
+			name:         "not listed",
+			collectors:   "github,jira",
+			collector:    "sonarqube",
+			wantEnabled:  false,
+			wantSchedule: "0 */6 * * *",
+		},
+		{
+			name:         "schedule override",
+			schedules:    `{"github":"0 * * * *"}`,
+			collector:    "github",
+			wantEnabled:  true,
+			wantSchedule: "0 * * * *",
+		},
+		{
+			name:         "override for another collector",
+			schedules:    `{"github":"0 * * * *"}`,
+			collector:    "jira",
+			wantEnabled:  true,
+			wantSchedule: "0 */6 * * *",
+		},
+		{
+			name:         "invalid overrides are ignored",
+			schedules:    `{"github":`,
+			collector:    "github",
+			wantEnabled:  true,
+			wantSchedule: "0 */6 * * *",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COLLECTION_SCHEDULE", "")
+			t.Setenv("COLLECTORS", tt.collectors)
+			t.Setenv("COLLECTOR_SCHEDULES", tt.schedules)
+
+			cfg := Load()
+			if got := cfg.CollectorEnabled(tt.collector); got != tt.wantEnabled {
+				t.Errorf("CollectorEnabled(%q) = %v, want %v", tt.collector, got, tt.wantEnabled)
+			}
+			if got := cfg.CollectorSchedule(tt.collector); got != tt.wantSchedule {
+				t.Errorf("CollectorSchedule(%q) = %q, want %q", tt.collector, got, tt.wantSchedule)
+			}
+		})
+	}
+}
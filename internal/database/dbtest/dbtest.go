@@ -0,0 +1,33 @@
+// Package dbtest connects tests to a scratch PostgreSQL database.
+package dbtest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"code-pulse/internal/database"
+)
+
+// Open connects to TEST_DATABASE_URL and applies the migrations, skipping
+// the test when no database is configured. The connection is closed when the
+// test ends.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := database.Connect(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -5,8 +5,8 @@ CREATE TABLE IF NOT EXISTS github_workflows (
     workflow_name VARCHAR(255) NOT NULL,
     status VARCHAR(50) NOT NULL,
     duration INTEGER NOT NULL DEFAULT 0,
-    created_at TIMESTAMP NOT NULL,
-    completed_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ NOT NULL,
     UNIQUE(repository, workflow_name, created_at)
 );
 
@@ -21,7 +21,7 @@ CREATE TABLE IF NOT EXISTS sonarqube_metrics (
     metric_key VARCHAR(255) NOT NULL,
     value TEXT NOT NULL,
     component VARCHAR(255) NOT NULL,
-    collected_at TIMESTAMP NOT NULL,
+    collected_at TIMESTAMPTZ NOT NULL,
     UNIQUE(project_key, metric_key, component, collected_at)
 );
 
@@ -37,11 +37,642 @@ CREATE TABLE IF NOT EXISTS jira_tickets (
     status VARCHAR(100) NOT NULL,
     priority VARCHAR(50) NOT NULL,
     assignee VARCHAR(255),
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
-    resolved_at TIMESTAMP
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
 );
 
 CREATE INDEX IF NOT EXISTS idx_jira_tickets_status ON jira_tickets(status);
 CREATE INDEX IF NOT EXISTS idx_jira_tickets_assignee ON jira_tickets(assignee);
-CREATE INDEX IF NOT EXISTS idx_jira_tickets_created_at ON jira_tickets(created_at);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_jira_tickets_created_at ON jira_tickets(created_at);
+
+-- SonarQube quality gate status
+CREATE TABLE IF NOT EXISTS sonarqube_quality_gates (
+    id SERIAL PRIMARY KEY,
+    project_key VARCHAR(255) NOT NULL,
+    branch VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    collected_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(project_key, branch, collected_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_quality_gates_project_key ON sonarqube_quality_gates(project_key);
+CREATE INDEX IF NOT EXISTS idx_sonarqube_quality_gates_collected_at ON sonarqube_quality_gates(collected_at);
+
+CREATE TABLE IF NOT EXISTS sonarqube_quality_gate_conditions (
+    id SERIAL PRIMARY KEY,
+    quality_gate_id INTEGER NOT NULL REFERENCES sonarqube_quality_gates(id) ON DELETE CASCADE,
+    metric_key VARCHAR(255) NOT NULL,
+    comparator VARCHAR(10) NOT NULL,
+    error_threshold VARCHAR(255) NOT NULL,
+    actual_value VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_quality_gate_conditions_gate_id ON sonarqube_quality_gate_conditions(quality_gate_id);
+
+-- SonarQube branch and pull request analysis
+ALTER TABLE sonarqube_metrics ADD COLUMN IF NOT EXISTS branch VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sonarqube_metrics ADD COLUMN IF NOT EXISTS pull_request VARCHAR(50) NOT NULL DEFAULT '';
+
+DO $$
+DECLARE
+    existing TEXT;
+BEGIN
+    SELECT conname INTO existing
+    FROM pg_constraint
+    WHERE conrelid = 'sonarqube_metrics'::regclass
+    AND contype = 'u'
+    AND NOT EXISTS (
+        SELECT 1 FROM pg_attribute
+        WHERE attrelid = conrelid AND attnum = ANY(conkey) AND attname = 'branch'
+    );
+
+    IF existing IS NOT NULL THEN
+        EXECUTE format('ALTER TABLE sonarqube_metrics DROP CONSTRAINT %I', existing);
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sonarqube_metrics_unique
+    ON sonarqube_metrics(project_key, branch, pull_request, metric_key, component, collected_at);
+CREATE INDEX IF NOT EXISTS idx_sonarqube_metrics_pull_request ON sonarqube_metrics(project_key, pull_request);
+
+ALTER TABLE sonarqube_quality_gates ADD COLUMN IF NOT EXISTS pull_request VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS sonarqube_branches (
+    id SERIAL PRIMARY KEY,
+    project_key VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    is_main BOOLEAN NOT NULL DEFAULT FALSE,
+    type VARCHAR(50) NOT NULL,
+    quality_gate_status VARCHAR(20) NOT NULL DEFAULT '',
+    analysis_date TIMESTAMPTZ,
+    UNIQUE(project_key, name)
+);
+
+CREATE TABLE IF NOT EXISTS sonarqube_pull_requests (
+    id SERIAL PRIMARY KEY,
+    project_key VARCHAR(255) NOT NULL,
+    repository VARCHAR(255) NOT NULL DEFAULT '',
+    pull_request VARCHAR(50) NOT NULL,
+    title TEXT NOT NULL,
+    branch VARCHAR(255) NOT NULL,
+    base_branch VARCHAR(255) NOT NULL,
+    quality_gate_status VARCHAR(20) NOT NULL DEFAULT '',
+    analysis_date TIMESTAMPTZ,
+    UNIQUE(project_key, pull_request)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_pull_requests_repository ON sonarqube_pull_requests(repository);
+
+-- GitHub pull requests
+CREATE TABLE IF NOT EXISTS github_pull_requests (
+    id SERIAL PRIMARY KEY,
+    repository VARCHAR(255) NOT NULL,
+    number INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    head_branch VARCHAR(255) NOT NULL,
+    base_branch VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ,
+    merged_at TIMESTAMPTZ,
+    UNIQUE(repository, number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_pull_requests_repository ON github_pull_requests(repository);
+CREATE INDEX IF NOT EXISTS idx_github_pull_requests_created_at ON github_pull_requests(created_at);
+
+-- SonarQube issues and security hotspots
+CREATE TABLE IF NOT EXISTS sonarqube_issues (
+    id SERIAL PRIMARY KEY,
+    issue_key VARCHAR(255) NOT NULL UNIQUE,
+    project_key VARCHAR(255) NOT NULL,
+    rule VARCHAR(255) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    file TEXT NOT NULL,
+    line INTEGER,
+    status VARCHAR(50) NOT NULL,
+    resolution VARCHAR(50) NOT NULL DEFAULT '',
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_issues_project_key ON sonarqube_issues(project_key);
+CREATE INDEX IF NOT EXISTS idx_sonarqube_issues_created_at ON sonarqube_issues(created_at);
+CREATE INDEX IF NOT EXISTS idx_sonarqube_issues_closed_at ON sonarqube_issues(closed_at);
+
+CREATE TABLE IF NOT EXISTS sonarqube_hotspots (
+    id SERIAL PRIMARY KEY,
+    hotspot_key VARCHAR(255) NOT NULL UNIQUE,
+    project_key VARCHAR(255) NOT NULL,
+    rule VARCHAR(255) NOT NULL,
+    security_category VARCHAR(100) NOT NULL,
+    vulnerability_probability VARCHAR(20) NOT NULL,
+    file TEXT NOT NULL,
+    line INTEGER,
+    status VARCHAR(50) NOT NULL,
+    resolution VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_hotspots_project_key ON sonarqube_hotspots(project_key);
+CREATE INDEX IF NOT EXISTS idx_sonarqube_hotspots_created_at ON sonarqube_hotspots(created_at);
+
+-- Typed SonarQube measure values
+ALTER TABLE sonarqube_metrics ADD COLUMN IF NOT EXISTS numeric_value DOUBLE PRECISION;
+
+UPDATE sonarqube_metrics
+SET numeric_value = value::DOUBLE PRECISION
+WHERE numeric_value IS NULL
+AND value ~ '^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$';
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_metrics_numeric_value ON sonarqube_metrics(metric_key, numeric_value);
+
+-- SonarQube component tree measures
+ALTER TABLE sonarqube_metrics ADD COLUMN IF NOT EXISTS qualifier VARCHAR(10) NOT NULL DEFAULT 'TRK';
+ALTER TABLE sonarqube_metrics ADD COLUMN IF NOT EXISTS path TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_metrics_component ON sonarqube_metrics(project_key, qualifier, component);
+
+-- GitHub commits and changed files
+CREATE TABLE IF NOT EXISTS github_commits (
+    id SERIAL PRIMARY KEY,
+    repository VARCHAR(255) NOT NULL,
+    sha VARCHAR(64) NOT NULL,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    author_email VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    committed_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(repository, sha)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_commits_committed_at ON github_commits(committed_at);
+
+CREATE TABLE IF NOT EXISTS github_commit_files (
+    id SERIAL PRIMARY KEY,
+    repository VARCHAR(255) NOT NULL,
+    sha VARCHAR(64) NOT NULL,
+    path TEXT NOT NULL,
+    additions INTEGER NOT NULL DEFAULT 0,
+    deletions INTEGER NOT NULL DEFAULT 0,
+    committed_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(repository, sha, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_commit_files_path ON github_commit_files(repository, path);
+CREATE INDEX IF NOT EXISTS idx_github_commit_files_committed_at ON github_commit_files(committed_at);
+
+-- Collector runs
+CREATE TABLE IF NOT EXISTS collection_runs (
+    id SERIAL PRIMARY KEY,
+    collector VARCHAR(100) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_runs_collector ON collection_runs(collector, started_at);
+
+-- Multi-provider CI and pull request storage. Unique constraints that predate
+-- the provider column are replaced by provider-scoped unique indexes.
+CREATE OR REPLACE FUNCTION drop_unique_constraint_without(tbl REGCLASS, col TEXT) RETURNS VOID AS $$
+DECLARE
+    existing TEXT;
+BEGIN
+    FOR existing IN
+        SELECT conname
+        FROM pg_constraint
+        WHERE conrelid = tbl
+        AND contype = 'u'
+        AND NOT EXISTS (
+            SELECT 1 FROM pg_attribute
+            WHERE attrelid = conrelid AND attnum = ANY(conkey) AND attname = col
+        )
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', tbl, existing);
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'github';
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS external_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS head_branch VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS head_sha VARCHAR(64) NOT NULL DEFAULT '';
+
+SELECT drop_unique_constraint_without('github_workflows', 'provider');
+CREATE INDEX IF NOT EXISTS idx_github_workflows_provider ON github_workflows(provider);
+
+ALTER TABLE github_pull_requests ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'github';
+
+SELECT drop_unique_constraint_without('github_pull_requests', 'provider');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_github_pull_requests_unique
+    ON github_pull_requests(provider, repository, number);
+
+CREATE TABLE IF NOT EXISTS repositories (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    default_branch VARCHAR(255) NOT NULL DEFAULT '',
+    web_url TEXT NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(provider, repository)
+);
+
+CREATE TABLE IF NOT EXISTS ci_jobs (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    pipeline_id VARCHAR(100) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    stage VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    duration INTEGER NOT NULL DEFAULT 0,
+    queued_duration INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    UNIQUE(provider, repository, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_jobs_pipeline ON ci_jobs(provider, repository, pipeline_id);
+
+-- Deployments
+CREATE TABLE IF NOT EXISTS deployments (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+    environment VARCHAR(255) NOT NULL,
+    ref VARCHAR(255) NOT NULL DEFAULT '',
+    sha VARCHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    UNIQUE(provider, repository, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_repository ON deployments(repository, environment);
+CREATE INDEX IF NOT EXISTS idx_deployments_created_at ON deployments(created_at);
+
+-- Jira labels, used to identify incidents
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+-- Build queue time and trigger, e.g. push, schedule or the Jenkins build cause
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS queue_duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE github_workflows ADD COLUMN IF NOT EXISTS cause VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Incidents from incident management tools, and the repository behind each
+-- service for DORA time to restore and change failure rate
+CREATE TABLE IF NOT EXISTS incidents (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+    number INTEGER NOT NULL DEFAULT 0,
+    title TEXT NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    urgency VARCHAR(50) NOT NULL DEFAULT '',
+    service_id VARCHAR(100) NOT NULL,
+    service_name VARCHAR(255) NOT NULL DEFAULT '',
+    triggered_at TIMESTAMPTZ NOT NULL,
+    acknowledged_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    UNIQUE(source, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_service ON incidents(source, service_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_triggered_at ON incidents(triggered_at);
+
+CREATE TABLE IF NOT EXISTS incident_services (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL,
+    service_id VARCHAR(100) NOT NULL,
+    service_name VARCHAR(255) NOT NULL DEFAULT '',
+    repository VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(source, service_id)
+);
+
+-- Pull request reviews, comments and state changes
+CREATE TABLE IF NOT EXISTS pull_request_activities (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    number INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(provider, repository, number, action, author, created_at)
+);
+
+-- Tickets from every issue tracker share jira_tickets, told apart by source,
+-- with status transitions and sprints (Linear cycles) for flow and velocity
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'jira';
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS status_category VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS estimate DOUBLE PRECISION;
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS sprint_id VARCHAR(100) NOT NULL DEFAULT '';
+
+SELECT drop_unique_constraint_without('jira_tickets', 'source');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jira_tickets_unique ON jira_tickets(source, ticket_key);
+CREATE INDEX IF NOT EXISTS idx_jira_tickets_sprint ON jira_tickets(source, sprint_id);
+
+CREATE TABLE IF NOT EXISTS ticket_transitions (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL,
+    ticket_key VARCHAR(50) NOT NULL,
+    from_status VARCHAR(100) NOT NULL DEFAULT '',
+    to_status VARCHAR(100) NOT NULL,
+    to_category VARCHAR(20) NOT NULL DEFAULT '',
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    transitioned_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(source, ticket_key, to_status, transitioned_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_transitions_ticket ON ticket_transitions(source, ticket_key, transitioned_at);
+
+CREATE TABLE IF NOT EXISTS sprints (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    UNIQUE(source, external_id)
+);
+
+-- Store instants rather than wall-clock times. The columns below were created
+-- as TIMESTAMP and are converted in place. They hold UTC wall-clock times:
+-- provider APIs report UTC, the server runs in UTC in the container image and
+-- a TIMESTAMP column drops the offset of the times it is given. A server that
+-- ran with another TZ must convert these columns itself, using that zone,
+-- before upgrading. Only code-pulse columns are listed so that other tables
+-- sharing the schema are left alone.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT c.table_name, c.column_name
+        FROM information_schema.columns c
+        JOIN (VALUES
+            ('github_workflows', 'created_at'), ('github_workflows', 'completed_at'),
+            ('sonarqube_metrics', 'collected_at'),
+            ('jira_tickets', 'created_at'), ('jira_tickets', 'updated_at'), ('jira_tickets', 'resolved_at'),
+            ('sonarqube_quality_gates', 'collected_at'),
+            ('sonarqube_branches', 'analysis_date'),
+            ('sonarqube_pull_requests', 'analysis_date'),
+            ('github_pull_requests', 'created_at'), ('github_pull_requests', 'updated_at'),
+            ('github_pull_requests', 'closed_at'), ('github_pull_requests', 'merged_at'),
+            ('sonarqube_issues', 'created_at'), ('sonarqube_issues', 'updated_at'), ('sonarqube_issues', 'closed_at'),
+            ('sonarqube_hotspots', 'created_at'), ('sonarqube_hotspots', 'updated_at'), ('sonarqube_hotspots', 'closed_at'),
+            ('github_commits', 'committed_at'),
+            ('github_commit_files', 'committed_at'),
+            ('collection_runs', 'started_at'), ('collection_runs', 'finished_at'),
+            ('repositories', 'updated_at'),
+            ('ci_jobs', 'created_at'), ('ci_jobs', 'started_at'), ('ci_jobs', 'finished_at'),
+            ('deployments', 'created_at'), ('deployments', 'finished_at'),
+            ('incidents', 'triggered_at'), ('incidents', 'acknowledged_at'), ('incidents', 'resolved_at'),
+            ('incident_services', 'updated_at'),
+            ('pull_request_activities', 'created_at'),
+            ('ticket_transitions', 'transitioned_at'),
+            ('sprints', 'starts_at'), ('sprints', 'ends_at'), ('sprints', 'completed_at')
+        ) AS converted(table_name, column_name)
+            ON converted.table_name = c.table_name AND converted.column_name = c.column_name
+        WHERE c.table_schema = current_schema()
+        AND c.data_type = 'timestamp without time zone'
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+            col.table_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
+
+-- API keys; only a SHA-256 hash of each key is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Browser sessions of users signed in through OIDC
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    subject VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(20) NOT NULL,
+    teams TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+-- Catalog of teams, their members and the services they own. A service maps
+-- to the repositories, SonarQube projects, Jira projects and components and
+-- incident services it is made of.
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(100) NOT NULL DEFAULT '',
+    UNIQUE(team_id, email)
+);
+
+CREATE TABLE IF NOT EXISTS services (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+    description TEXT NOT NULL DEFAULT '',
+    repositories TEXT[] NOT NULL DEFAULT '{}',
+    sonarqube_projects TEXT[] NOT NULL DEFAULT '{}',
+    jira_projects TEXT[] NOT NULL DEFAULT '{}',
+    jira_components TEXT[] NOT NULL DEFAULT '{}',
+    incident_services TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_services_team_id ON services(team_id);
+
+ALTER TABLE jira_tickets ADD COLUMN IF NOT EXISTS components TEXT[] NOT NULL DEFAULT '{}';
+
+-- Whether a ticket falls within a list of Jira scopes, each either a project
+-- key (PAY) or a project key and component (PAY/Checkout). A NULL list
+-- matches every ticket.
+CREATE OR REPLACE FUNCTION ticket_in_scope(ticket_key TEXT, components TEXT[], scopes TEXT[]) RETURNS BOOLEAN AS $$
+    SELECT scopes IS NULL
+        OR split_part(ticket_key, '-', 1) = ANY(scopes)
+        OR EXISTS (
+            SELECT 1 FROM unnest(components) component
+            WHERE split_part(ticket_key, '-', 1) || '/' || component = ANY(scopes)
+        )
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- People and the accounts they use in each system. Accounts are linked to a
+-- person by email when a system exposes it; manual links are kept as they are.
+CREATE TABLE IF NOT EXISTS people (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS identities (
+    id SERIAL PRIMARY KEY,
+    system VARCHAR(20) NOT NULL,
+    account_id VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    person_id INTEGER REFERENCES people(id) ON DELETE SET NULL,
+    manual BOOLEAN NOT NULL DEFAULT FALSE,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(system, account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_identities_person_id ON identities(person_id);
+
+-- Ticket assignees are stable account ids, with the display name alongside.
+-- Tickets stored before held display names; the issue trackers collect
+-- everything again on their next run to replace them.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema()
+        AND table_name = 'jira_tickets'
+        AND column_name = 'assignee_name'
+    ) THEN
+        ALTER TABLE jira_tickets ADD COLUMN assignee_name VARCHAR(255) NOT NULL DEFAULT '';
+        UPDATE jira_tickets SET assignee_name = COALESCE(assignee, '');
+        DELETE FROM collection_runs WHERE collector IN ('jira', 'linear');
+    END IF;
+END $$;
+
+-- Transition authors are account ids too. Names stored before are replaced
+-- with the account of the only identity of that system showing the name;
+-- the rest are replaced as their tickets are collected again.
+UPDATE ticket_transitions tr SET author = i.account_id
+FROM identities i
+WHERE i.system = tr.source
+AND i.display_name = tr.author
+AND tr.author <> ''
+AND NOT EXISTS (
+    SELECT 1 FROM identities known WHERE known.system = tr.source AND known.account_id = tr.author
+)
+AND NOT EXISTS (
+    SELECT 1 FROM identities other
+    WHERE other.system = i.system AND other.display_name = i.display_name AND other.id <> i.id
+);
+
+-- Tickets referenced by pull requests, in their title or head branch, and by
+-- commits, in their message. ref is the pull request number or commit sha.
+CREATE TABLE IF NOT EXISTS ticket_links (
+    id SERIAL PRIMARY KEY,
+    ticket_key VARCHAR(50) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    ref VARCHAR(64) NOT NULL,
+    UNIQUE(kind, provider, repository, ref, ticket_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_links_ticket_key ON ticket_links(ticket_key);
+CREATE INDEX IF NOT EXISTS idx_jira_tickets_project ON jira_tickets(split_part(ticket_key, '-', 1));
+
+-- The first successful deployment to an environment after each pull request
+-- was merged, the same match DORA lead time makes.
+CREATE TABLE IF NOT EXISTS pull_request_deployments (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    number INTEGER NOT NULL,
+    environment VARCHAR(255) NOT NULL,
+    deployment_id INTEGER NOT NULL REFERENCES deployments(id) ON DELETE CASCADE,
+    deployed_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(provider, repository, number, environment)
+);
+
+-- Link the pull requests and commits stored before ticket links were kept,
+-- with the patterns the collectors use: keys are upper case, except in
+-- branch names.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM ticket_links) THEN
+        INSERT INTO ticket_links (ticket_key, kind, provider, repository, ref)
+        SELECT DISTINCT upper(m[1]), 'pull_request', pr.provider, pr.repository, pr.number::TEXT
+        FROM github_pull_requests pr,
+            regexp_matches(pr.title, '\m([A-Z][A-Z0-9_]+-[1-9][0-9]*)\M', 'g') m
+        UNION
+        SELECT DISTINCT upper(m[1]), 'pull_request', pr.provider, pr.repository, pr.number::TEXT
+        FROM github_pull_requests pr,
+            regexp_matches(pr.head_branch, '\m([A-Z][A-Z0-9_]+-[1-9][0-9]*)\M', 'gi') m
+        UNION
+        SELECT DISTINCT upper(m[1]), 'commit', 'github', c.repository, c.sha
+        FROM github_commits c,
+            regexp_matches(c.message, '\m([A-Z][A-Z0-9_]+-[1-9][0-9]*)\M', 'g') m
+        ON CONFLICT DO NOTHING;
+    END IF;
+END $$;
+
+-- Alerts raised by the rules in ALERT_RULES. A rule has at most one firing
+-- alert, updated on every evaluation until it resolves. notified_status is
+-- the last status its channels were told about.
+CREATE TABLE IF NOT EXISTS alerts (
+    id SERIAL PRIMARY KEY,
+    rule VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    value DOUBLE PRECISION,
+    baseline DOUBLE PRECISION,
+    message TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    evaluated_at TIMESTAMPTZ NOT NULL,
+    notified_status VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_firing ON alerts(rule) WHERE status = 'firing';
+CREATE INDEX IF NOT EXISTS idx_alerts_rule ON alerts(rule, started_at);
+
+-- Workflow runs are identified by provider and run id; runs created in the
+-- same second no longer collide. Jenkins build and Bitbucket pipeline numbers
+-- only count within a job or repository, so those are qualified by it. Rows
+-- stored before run ids were kept are matched on their next save.
+UPDATE github_workflows SET external_id = repository || '/' || workflow_name || '#' || external_id
+WHERE provider = 'jenkins' AND external_id ~ '^[0-9]+$';
+UPDATE github_workflows SET external_id = repository || '#' || external_id
+WHERE provider = 'bitbucket' AND external_id ~ '^[0-9]+$';
+UPDATE ci_jobs SET pipeline_id = repository || '#' || pipeline_id
+WHERE provider = 'bitbucket' AND pipeline_id ~ '^[0-9]+$';
+
+DROP INDEX IF EXISTS idx_github_workflows_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_github_workflows_external_id
+    ON github_workflows(provider, external_id) WHERE external_id <> '';
@@ -0,0 +1,111 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"sort"
+	"time"
+
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/config"
+)
+
+// Reporter builds the weekly digest of each team and mails it to the team's
+// recipients.
+type Reporter struct {
+	db     *sql.DB
+	config *config.Config
+}
+
+func New(db *sql.DB, cfg *config.Config) *Reporter {
+	return &Reporter{db: db, config: cfg}
+}
+
+// Enabled tells whether digests can be sent: an SMTP server, a sender and at
+// least one team with recipients are configured.
+func (r *Reporter) Enabled() bool {
+	return r.config.SMTPHost != "" && r.config.SMTPFrom != "" && len(r.config.DigestRecipients) > 0
+}
+
+// Validate checks that every team of DIGEST_RECIPIENTS is in the catalog and
+// that its recipients are valid addresses, so that mistakes show at startup
+// rather than at the weekly run.
+func (r *Reporter) Validate() error {
+	teams := make([]string, 0, len(r.config.DigestRecipients))
+	for team := range r.config.DigestRecipients {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	store := catalog.NewStore(r.db)
+	for _, team := range teams {
+		if _, err := store.TeamResources(team); err != nil {
+			if errors.Is(err, catalog.ErrNotFound) {
+				return fmt.Errorf("unknown team %q", team)
+			}
+			return fmt.Errorf("failed to look up team %q: %w", team, err)
+		}
+		for _, recipient := range r.config.DigestRecipients[team] {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("team %s: invalid recipient %q: %w", team, recipient, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Build gathers the digest of a team for the seven days before midnight UTC
+// of now, so that a run on Monday morning covers last Monday to Sunday.
+func (r *Reporter) Build(team string, now time.Time) (*Report, error) {
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return r.build(team, to)
+}
+
+// Send builds the digest of a team and mails it to its recipients.
+func (r *Reporter) Send(ctx context.Context, team string) error {
+	recipients := r.config.DigestRecipients[team]
+	if len(recipients) == 0 {
+		return fmt.Errorf("no digest recipients for team %s", team)
+	}
+
+	report, err := r.Build(team, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to build digest of team %s: %w", team, err)
+	}
+	message, err := r.message(report, recipients)
+	if err != nil {
+		return fmt.Errorf("failed to render digest of team %s: %w", team, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := r.send(recipients, message); err != nil {
+		return fmt.Errorf("failed to send digest of team %s: %w", team, err)
+	}
+	return nil
+}
+
+// SendAll mails the digest of every team with recipients, carrying on past
+// the teams that fail.
+func (r *Reporter) SendAll(ctx context.Context) error {
+	teams := make([]string, 0, len(r.config.DigestRecipients))
+	for team := range r.config.DigestRecipients {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	var errs []error
+	for _, team := range teams {
+		if err := r.Send(ctx, team); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Sent weekly digest of team %s to %d recipients", team, len(r.config.DigestRecipients[team]))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// message renders a report as a multipart/alternative email carrying both
+// the plain text and the HTML version.
+func (r *Reporter) message(report *Report, recipients []string) ([]byte, error) {
+	text, err := report.Text()
+	if err != nil {
+		return nil, err
+	}
+	html, err := report.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := mail.ParseAddress(r.config.SMTPFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", r.config.SMTPFrom, err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", report.Subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send hands a message to the SMTP server, authenticating only when a
+// username is configured so that local relays and test stubs work as is.
+func (r *Reporter) send(recipients []string, message []byte) error {
+	from, err := mail.ParseAddress(r.config.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", r.config.SMTPFrom, err)
+	}
+
+	var auth smtp.Auth
+	if r.config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", r.config.SMTPUsername, r.config.SMTPPassword, r.config.SMTPHost)
+	}
+	addr := net.JoinHostPort(r.config.SMTPHost, strconv.Itoa(r.config.SMTPPort))
+	return smtp.SendMail(addr, auth, from.Address, recipients, message)
+}
@@ -0,0 +1,206 @@
+package digest
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"code-pulse/internal/config"
+)
+
+// delivery is a message an smtpStub accepted.
+type delivery struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// smtpStub accepts one SMTP session without extensions and sends what it
+// received on the returned channel.
+func smtpStub(t *testing.T) (string, int, <-chan delivery) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	deliveries := make(chan delivery, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		var d delivery
+		reply("220 localhost ESMTP stub")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+			switch {
+			case verb == "EHLO" || verb == "HELO":
+				reply("250 localhost")
+			case strings.HasPrefix(strings.ToUpper(command), "MAIL FROM:"):
+				d.from = strings.Trim(command[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(strings.ToUpper(command), "RCPT TO:"):
+				d.recipients = append(d.recipients, strings.Trim(command[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case verb == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				d.data = data.String()
+				reply("250 OK")
+			case verb == "QUIT":
+				reply("221 Bye")
+				deliveries <- d
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, deliveries
+}
+
+func testReport() *Report {
+	successRate, previousSuccessRate := 0.9, 0.8
+	return &Report{
+		Team: "payments",
+		From: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		CI: CIHealth{
+			Runs:                40,
+			Failures:            4,
+			SuccessRate:         &successRate,
+			PreviousSuccessRate: &previousSuccessRate,
+			FailingWorkflows:    []WorkflowFailures{{Repository: "acme/pay", Workflow: "ci", Failures: 4}},
+		},
+		Throughput: Throughput{Created: 12, Resolved: 9, PreviousResolved: 7},
+		Grade:      "B",
+	}
+}
+
+func TestSendDeliversMultipartDigest(t *testing.T) {
+	host, port, deliveries := smtpStub(t)
+	reporter := New(nil, &config.Config{
+		SMTPHost: host,
+		SMTPPort: port,
+		SMTPFrom: "Code Pulse <pulse@example.com>",
+	})
+	recipients := []string{"lead@example.com", "team@example.com"}
+
+	message, err := reporter.message(testReport(), recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.send(recipients, message); err != nil {
+		t.Fatal(err)
+	}
+
+	var d delivery
+	select {
+	case d = <-deliveries:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered")
+	}
+	if d.from != "pulse@example.com" {
+		t.Errorf("MAIL FROM = %q", d.from)
+	}
+	if strings.Join(d.recipients, ",") != "lead@example.com,team@example.com" {
+		t.Errorf("RCPT TO = %v", d.recipients)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(d.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Weekly digest for payments: Mar 4 to Mar 10" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if to := msg.Header.Get("To"); to != "lead@example.com, team@example.com" {
+		t.Errorf("To = %q", to)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", mediaType)
+	}
+
+	// The reader decodes quoted-printable parts.
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct {
+		contentType string
+		contains    []string
+	}{
+		{"text/plain", []string{"Weekly digest for payments", "40 runs, 4 failed", "acme/pay / ci: 4 failures"}},
+		{"text/html", []string{"<html", "payments", "acme/pay"}},
+	}
+	for _, w := range want {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("%s part: %v", w.contentType, err)
+		}
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil || contentType != w.contentType {
+			t.Errorf("part Content-Type = %q, want %s", part.Header.Get("Content-Type"), w.contentType)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range w.contains {
+			if !strings.Contains(string(body), s) {
+				t.Errorf("%s part lacks %q:\n%s", w.contentType, s, body)
+			}
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got %v", err)
+	}
+}
+
+func TestMessageRejectsInvalidSender(t *testing.T) {
+	reporter := New(nil, &config.Config{SMTPFrom: "not an address"})
+	if _, err := reporter.message(testReport(), []string{"lead@example.com"}); err == nil {
+		t.Error("expected an invalid sender to be rejected")
+	}
+}
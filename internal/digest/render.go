@@ -0,0 +1,70 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strconv"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("Mon Jan 2, 2006")
+	},
+	// lastDay turns the exclusive end of the week into its last day.
+	"lastDay": func(t time.Time) time.Time {
+		return t.AddDate(0, 0, -1)
+	},
+	"percent": func(value *float64) string {
+		if value == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1f%%", *value*100)
+	},
+	"minutes": func(value *float64) string {
+		if value == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1f min", *value)
+	},
+	"number": func(value float64) string {
+		return strconv.FormatFloat(value, 'g', 4, 64)
+	},
+}
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(funcs).
+			ParseFS(templates, "templates/digest.txt"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(funcs).
+			ParseFS(templates, "templates/digest.html"))
+)
+
+// Subject is the subject line of a report's email.
+func (report *Report) Subject() string {
+	return fmt.Sprintf("Weekly digest for %s: %s to %s", report.Team, report.From.Format("Jan 2"),
+		report.To.AddDate(0, 0, -1).Format("Jan 2"))
+}
+
+// Text renders a report as plain text.
+func (report *Report) Text() (string, error) {
+	var buf bytes.Buffer
+	if err := textTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render text digest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// HTML renders a report as an HTML page.
+func (report *Report) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render HTML digest: %w", err)
+	}
+	return buf.String(), nil
+}
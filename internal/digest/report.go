@@ -0,0 +1,238 @@
+package digest
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/scorecard"
+
+	"github.com/lib/pq"
+)
+
+const (
+	period              = 7 * 24 * time.Hour
+	maxFailingWorkflows = 5
+)
+
+// Report is the weekly digest of a team, over the week before To compared
+// with the week before that.
+type Report struct {
+	Team         string
+	From         time.Time
+	To           time.Time
+	CI           CIHealth
+	QualityGates []GateChange
+	Throughput   Throughput
+	Grade        string
+	Regressions  []Regression
+}
+
+type CIHealth struct {
+	Runs                int
+	Failures            int
+	SuccessRate         *float64
+	PreviousSuccessRate *float64
+	MedianMinutes       *float64
+	FailingWorkflows    []WorkflowFailures
+}
+
+type WorkflowFailures struct {
+	Repository string
+	Workflow   string
+	Failures   int
+}
+
+// GateChange is a SonarQube project whose main branch quality gate changed
+// status during the week.
+type GateChange struct {
+	Project   string
+	From      string
+	To        string
+	ChangedAt time.Time
+}
+
+type Throughput struct {
+	Created          int
+	Resolved         int
+	PreviousResolved int
+}
+
+// Regression is a scorecard metric that fell to a lower tier than the week
+// before.
+type Regression struct {
+	Metric        string
+	Value         float64
+	PreviousValue float64
+	Tier          string
+	PreviousTier  string
+}
+
+// build gathers the report of a team for the week ending at to.
+func (r *Reporter) build(team string, to time.Time) (*Report, error) {
+	resources, err := catalog.NewStore(r.db).TeamResources(team)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Team: team, From: to.Add(-period), To: to}
+	previous := report.From.Add(-period)
+	repositories := pq.Array(resources.Repositories)
+
+	ci := &report.CI
+	var duration, successRate, previousSuccessRate sql.NullFloat64
+	err = r.db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE created_at >= $2),
+			COUNT(*) FILTER (WHERE created_at >= $2 AND status = 'failure'),
+			COUNT(*) FILTER (WHERE created_at >= $2 AND status = 'success')::DOUBLE PRECISION /
+				NULLIF(COUNT(*) FILTER (WHERE created_at >= $2
+					AND status IN ('success', 'failure', 'cancelled', 'timed_out')), 0),
+			COUNT(*) FILTER (WHERE created_at < $2 AND status = 'success')::DOUBLE PRECISION /
+				NULLIF(COUNT(*) FILTER (WHERE created_at < $2
+					AND status IN ('success', 'failure', 'cancelled', 'timed_out')), 0),
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration) FILTER (WHERE created_at >= $2) / 60
+		FROM github_workflows
+		WHERE repository = ANY($4)
+		AND created_at >= $1
+		AND created_at < $3`,
+		previous, report.From, to, repositories).Scan(&ci.Runs, &ci.Failures, &successRate,
+		&previousSuccessRate, &duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize CI runs: %w", err)
+	}
+	ci.SuccessRate = nullableFloat(successRate)
+	ci.PreviousSuccessRate = nullableFloat(previousSuccessRate)
+	ci.MedianMinutes = nullableFloat(duration)
+
+	rows, err := r.db.Query(`
+		SELECT repository, workflow_name, COUNT(*)
+		FROM github_workflows
+		WHERE repository = ANY($1)
+		AND status = 'failure'
+		AND created_at >= $2
+		AND created_at < $3
+		GROUP BY repository, workflow_name
+		ORDER BY COUNT(*) DESC, repository, workflow_name
+		LIMIT $4`, repositories, report.From, to, maxFailingWorkflows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failing workflows: %w", err)
+	}
+	for rows.Next() {
+		var workflow WorkflowFailures
+		if err := rows.Scan(&workflow.Repository, &workflow.Workflow, &workflow.Failures); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan failing workflow: %w", err)
+		}
+		ci.FailingWorkflows = append(ci.FailingWorkflows, workflow)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list failing workflows: %w", err)
+	}
+
+	rows, err = r.db.Query(`
+		SELECT project_key, previous, status, collected_at
+		FROM (
+			SELECT project_key, status, collected_at,
+				LAG(status) OVER (PARTITION BY project_key ORDER BY collected_at) AS previous
+			FROM sonarqube_quality_gates
+			WHERE branch = '' AND pull_request = ''
+			AND project_key = ANY($1)
+			AND collected_at < $3
+		) gates
+		WHERE collected_at >= $2
+		AND previous <> status
+		ORDER BY collected_at, project_key`, pq.Array(resources.SonarqubeProjects), report.From, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quality gate changes: %w", err)
+	}
+	for rows.Next() {
+		var change GateChange
+		if err := rows.Scan(&change.Project, &change.From, &change.To, &change.ChangedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan quality gate change: %w", err)
+		}
+		report.QualityGates = append(report.QualityGates, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list quality gate changes: %w", err)
+	}
+
+	throughput := &report.Throughput
+	err = r.db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE created_at >= $2 AND created_at < $3),
+			COUNT(*) FILTER (WHERE resolved_at >= $2 AND resolved_at < $3 AND status_category = 'done'),
+			COUNT(*) FILTER (WHERE resolved_at >= $1 AND resolved_at < $2 AND status_category = 'done')
+		FROM jira_tickets
+		WHERE ticket_in_scope(ticket_key, components, $4)
+		AND (created_at >= $1 OR resolved_at >= $1)`,
+		previous, report.From, to, pq.Array(resources.JiraScopes)).Scan(&throughput.Created,
+		&throughput.Resolved, &throughput.PreviousResolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tickets: %w", err)
+	}
+
+	if err := r.regressions(report, resources); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// regressions grades the team's scorecard for both weeks and keeps the
+// metrics that lost a tier.
+func (r *Reporter) regressions(report *Report, resources *catalog.Resources) error {
+	metrics, err := scorecard.Metrics(r.config.ScorecardMetrics)
+	if err != nil {
+		return err
+	}
+
+	filter := scorecard.Filter{
+		Repositories:      resources.Repositories,
+		SonarqubeProjects: resources.SonarqubeProjects,
+		JiraScopes:        resources.JiraScopes,
+		IncidentServices:  resources.IncidentServices,
+		Environment:       r.config.DoraEnvironment,
+		IncidentLabel:     r.config.DoraIncidentLabel,
+		From:              report.From,
+		To:                report.To,
+	}
+	current, results, err := scorecard.Compute(r.db, metrics, filter)
+	if err != nil {
+		return err
+	}
+	report.Grade = current.Grade
+
+	filter.From, filter.To = report.From.Add(-period), report.From
+	_, previous, err := scorecard.Compute(r.db, metrics, filter)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		before := previous[i]
+		if result.Value == nil || before.Value == nil {
+			continue
+		}
+		if scorecard.Points(result.Tier) < scorecard.Points(before.Tier) {
+			report.Regressions = append(report.Regressions, Regression{
+				Metric:        result.Name,
+				Value:         *result.Value,
+				PreviousValue: *before.Value,
+				Tier:          result.Tier,
+				PreviousTier:  before.Tier,
+			})
+		}
+	}
+	return nil
+}
+
+func nullableFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
@@ -0,0 +1,209 @@
+package dora
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	Elite  = "elite"
+	High   = "high"
+	Medium = "medium"
+	Low    = "low"
+)
+
+// Filter narrows the deployments, pull requests and incidents the four keys
+// are computed from. An empty provider and nil lists match everything.
+// Incidents count when their service is listed or maps to a listed
+// repository; incident tickets when they fall within the Jira scopes.
+type Filter struct {
+	Provider         string
+	Repositories     []string
+	JiraScopes       []string
+	IncidentServices []string
+	Environment      string
+	IncidentLabel    string
+	From             time.Time
+	To               time.Time
+}
+
+func Compute(db *sql.DB, f Filter) (*models.DoraMetrics, error) {
+	metrics := &models.DoraMetrics{From: f.From, To: f.To}
+
+	var hasIncidents bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM incidents)`).Scan(&hasIncidents); err != nil {
+		return nil, fmt.Errorf("failed to check incidents: %w", err)
+	}
+
+	// A successful deployment counts as failed when an incident hits a service
+	// of its repository before the repository's next successful deployment.
+	var successes, failures, causedIncidents int
+	err := db.QueryRow(`
+		WITH scoped AS (
+			SELECT provider, repository, status, created_at
+			FROM deployments
+			WHERE environment = $1
+			AND ($2 = '' OR provider = $2)
+			AND ($3::TEXT[] IS NULL OR repository = ANY($3))
+		), successful AS (
+			SELECT repository, created_at,
+				LEAD(created_at) OVER (PARTITION BY provider, repository ORDER BY created_at) AS next_at
+			FROM scoped
+			WHERE status = 'success'
+		)
+		SELECT
+			(SELECT COUNT(*) FROM successful WHERE created_at >= $4 AND created_at < $5),
+			(SELECT COUNT(*) FROM scoped WHERE status = 'failure' AND created_at >= $4 AND created_at < $5),
+			(SELECT COUNT(*) FROM successful d
+				WHERE d.created_at >= $4 AND d.created_at < $5
+				AND EXISTS (
+					SELECT 1
+					FROM incidents i
+					JOIN incident_services s ON s.source = i.source AND s.service_id = i.service_id
+					WHERE s.repository = d.repository
+					AND i.triggered_at >= d.created_at
+					AND (d.next_at IS NULL OR i.triggered_at < d.next_at)
+				))`,
+		f.Environment, f.Provider, pq.Array(f.Repositories), f.From, f.To).Scan(&successes, &failures, &causedIncidents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count deployments: %w", err)
+	}
+
+	days := f.To.Sub(f.From).Hours() / 24
+	metrics.Deployments = successes
+	if days > 0 {
+		metrics.DeploymentsPerDay = float64(successes) / days
+	}
+	metrics.DeploymentFrequency = DeploymentFrequencyTier(metrics.DeploymentsPerDay)
+
+	if successes+failures > 0 {
+		rate := float64(failures+causedIncidents) / float64(successes+failures)
+		metrics.ChangeFailureRate = &rate
+		metrics.ChangeFailure = ChangeFailureRateTier(rate)
+	}
+
+	// Lead time runs from pull request creation to the first successful
+	// deployment of the same repository after the merge.
+	var leadTime sql.NullFloat64
+	err = db.QueryRow(`
+		SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM d.created_at - pr.created_at)) / 3600
+		FROM github_pull_requests pr
+		JOIN LATERAL (
+			SELECT created_at
+			FROM deployments
+			WHERE provider = pr.provider
+			AND repository = pr.repository
+			AND environment = $1
+			AND status = 'success'
+			AND created_at >= pr.merged_at
+			ORDER BY created_at
+			LIMIT 1
+		) d ON TRUE
+		WHERE ($2 = '' OR pr.provider = $2)
+		AND ($3::TEXT[] IS NULL OR pr.repository = ANY($3))
+		AND pr.merged_at >= $4 AND pr.merged_at < $5`,
+		f.Environment, f.Provider, pq.Array(f.Repositories), f.From, f.To).Scan(&leadTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute lead time: %w", err)
+	}
+	if leadTime.Valid {
+		metrics.LeadTimeHours = &leadTime.Float64
+		metrics.LeadTime = LeadTimeTier(leadTime.Float64)
+	}
+
+	// Time to restore comes from collected incidents; Jira tickets carrying the
+	// incident label are only used while no incident source has reported any.
+	var timeToRestore sql.NullFloat64
+	if hasIncidents {
+		metrics.IncidentSource = "incidents"
+		err = db.QueryRow(`
+			SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM i.resolved_at - i.triggered_at)) / 3600
+			FROM incidents i
+			WHERE i.resolved_at >= $3 AND i.resolved_at < $4
+			AND (($1::TEXT[] IS NULL AND $2::TEXT[] IS NULL)
+				OR i.service_id = ANY($2)
+				OR EXISTS (
+					SELECT 1 FROM incident_services s
+					WHERE s.source = i.source AND s.service_id = i.service_id
+					AND s.repository = ANY($1)
+				))`,
+			pq.Array(f.Repositories), pq.Array(f.IncidentServices), f.From, f.To).Scan(&timeToRestore)
+	} else {
+		metrics.IncidentSource = "jira"
+		err = db.QueryRow(`
+			SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600
+			FROM jira_tickets
+			WHERE $1 = ANY(labels)
+			AND resolved_at >= $2 AND resolved_at < $3
+			AND ticket_in_scope(ticket_key, components, $4)`,
+			f.IncidentLabel, f.From, f.To, pq.Array(f.JiraScopes)).Scan(&timeToRestore)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute time to restore: %w", err)
+	}
+	if timeToRestore.Valid {
+		metrics.TimeToRestoreHours = &timeToRestore.Float64
+		metrics.TimeToRestore = TimeToRestoreTier(timeToRestore.Float64)
+	}
+
+	return metrics, nil
+}
+
+// The tier boundaries follow the State of DevOps report performance clusters.
+
+func DeploymentFrequencyTier(perDay float64) string {
+	switch {
+	case perDay >= 1:
+		return Elite
+	case perDay >= 1.0/7:
+		return High
+	case perDay >= 1.0/30:
+		return Medium
+	default:
+		return Low
+	}
+}
+
+func LeadTimeTier(hours float64) string {
+	switch {
+	case hours < 24:
+		return Elite
+	case hours < 24*7:
+		return High
+	case hours < 24*30:
+		return Medium
+	default:
+		return Low
+	}
+}
+
+func ChangeFailureRateTier(rate float64) string {
+	switch {
+	case rate <= 0.05:
+		return Elite
+	case rate <= 0.10:
+		return High
+	case rate <= 0.15:
+		return Medium
+	default:
+		return Low
+	}
+}
+
+func TimeToRestoreTier(hours float64) string {
+	switch {
+	case hours < 1:
+		return Elite
+	case hours < 24:
+		return High
+	case hours < 24*7:
+		return Medium
+	default:
+		return Low
+	}
+}
@@ -0,0 +1,120 @@
+// Package exposition writes metrics in the Prometheus text exposition format.
+package exposition
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Gauge     = "gauge"
+	Counter   = "counter"
+	Histogram = "histogram"
+)
+
+// Writer writes metric families one after another. Every sample of a family
+// must follow its Family call. The first write error is kept and returned by
+// Err.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Family starts a metric family with its HELP and TYPE lines.
+func (w *Writer) Family(name, help, metricType string) {
+	w.printf("# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	w.printf("# TYPE %s %s\n", name, metricType)
+}
+
+// Sample writes one sample. Labels are given as name, value pairs.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.printf("%s%s %s\n", name, formatLabels(labels), formatValue(value))
+}
+
+// Histogram writes the bucket, sum and count samples of a histogram.
+func (w *Writer) Histogram(name string, h *Buckets, labels ...string) {
+	for i, bound := range h.bounds {
+		w.Sample(name+"_bucket", float64(h.counts[i]), append(labels[:len(labels):len(labels)], "le", formatValue(bound))...)
+	}
+	w.Sample(name+"_bucket", float64(h.count), append(labels[:len(labels):len(labels)], "le", "+Inf")...)
+	w.Sample(name+"_sum", h.sum, labels...)
+	w.Sample(name+"_count", float64(h.count), labels...)
+}
+
+func (w *Writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+// Buckets accumulates observations into cumulative histogram buckets.
+type Buckets struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewBuckets(bounds ...float64) *Buckets {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	return &Buckets{bounds: sorted, counts: make([]uint64, len(sorted))}
+}
+
+func (b *Buckets) Observe(value float64) {
+	for i, bound := range b.bounds {
+		if value <= bound {
+			b.counts[i]++
+		}
+	}
+	b.sum += value
+	b.count++
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escape.Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
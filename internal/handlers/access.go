@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code-pulse/internal/auth"
+	"code-pulse/internal/catalog"
+)
+
+// access lists the repositories, SonarQube projects, Jira scopes and incident
+// services a request covers. Jira scopes are project keys, optionally with a
+// component (PAY/Checkout), matched by the ticket_in_scope SQL function.
+// Lists are nil when not narrowed, which queries pass as NULL to skip the
+// filter; empty lists match nothing.
+type access struct {
+	Repositories      []string
+	SonarqubeProjects []string
+	JiraScopes        []string
+	IncidentServices  []string
+}
+
+// access returns what the caller may see. Restricted principals see the
+// resources of their teams, from TEAMS and from the services the catalog
+// lists for them.
+func (h *Handlers) access(r *http.Request) (access, error) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil || !principal.Restricted {
+		return access{}, nil
+	}
+
+	a := access{Repositories: []string{}, SonarqubeProjects: []string{}, JiraScopes: []string{},
+		IncidentServices: []string{}}
+	for _, team := range h.config.Teams {
+		if !principal.InTeam(team.Name) {
+			continue
+		}
+		a.Repositories = append(a.Repositories, team.Repositories...)
+		a.SonarqubeProjects = append(a.SonarqubeProjects, team.SonarqubeProjects...)
+		a.JiraScopes = append(a.JiraScopes, team.JiraProjects...)
+	}
+	for _, team := range principal.Teams {
+		resources, err := h.catalog.TeamResources(team)
+		if errors.Is(err, catalog.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return access{}, err
+		}
+		a.Repositories = append(a.Repositories, resources.Repositories...)
+		a.SonarqubeProjects = append(a.SonarqubeProjects, resources.SonarqubeProjects...)
+		a.JiraScopes = append(a.JiraScopes, resources.JiraScopes...)
+		a.IncidentServices = append(a.IncidentServices, resources.IncidentServices...)
+	}
+	return a, nil
+}
+
+// visibleTeams returns the catalog teams the caller may see, or nil when
+// the caller is not restricted to teams.
+func visibleTeams(r *http.Request) []string {
+	principal := auth.FromContext(r.Context())
+	if principal == nil || !principal.Restricted {
+		return nil
+	}
+	return append([]string{}, principal.Teams...)
+}
+
+// scope narrows what the caller may see to the team and service query
+// parameters, resolved through the catalog. It writes an error and returns
+// false when the request cannot be served.
+func (h *Handlers) scope(w http.ResponseWriter, r *http.Request) (access, bool) {
+	a, err := h.access(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return access{}, false
+	}
+
+	filters := []struct {
+		name    string
+		resolve func(string) (*catalog.Resources, error)
+	}{
+		{"team", h.catalog.TeamResources},
+		{"service", h.catalog.ServiceResources},
+	}
+	for _, filter := range filters {
+		value := r.URL.Query().Get(filter.name)
+		if value == "" {
+			continue
+		}
+		resources, err := filter.resolve(value)
+		if errors.Is(err, catalog.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("Unknown %s %q", filter.name, value), http.StatusBadRequest)
+			return access{}, false
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return access{}, false
+		}
+
+		a = a.narrow(resources)
+	}
+	return a, true
+}
+
+// narrow keeps the resources of a team or service that the access allows.
+func (a access) narrow(resources *catalog.Resources) access {
+	return access{
+		Repositories:      intersect(a.Repositories, resources.Repositories),
+		SonarqubeProjects: intersect(a.SonarqubeProjects, resources.SonarqubeProjects),
+		JiraScopes:        intersectJiraScopes(a.JiraScopes, resources.JiraScopes),
+		IncidentServices:  intersect(a.IncidentServices, resources.IncidentServices),
+	}
+}
+
+// allows reports whether value is in an access list.
+func allows(list []string, value string) bool {
+	if list == nil {
+		return true
+	}
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// intersect keeps the values of a filter that an access list allows.
+func intersect(list, filter []string) []string {
+	values := []string{}
+	for _, value := range filter {
+		if allows(list, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// intersectJiraScopes is intersect for Jira scopes, where a project allows
+// every component of it and a component narrows its project.
+func intersectJiraScopes(list, filter []string) []string {
+	if list == nil {
+		return append([]string{}, filter...)
+	}
+
+	values := []string{}
+	seen := make(map[string]bool)
+	for _, value := range filter {
+		project, _, _ := strings.Cut(value, "/")
+		for _, allowed := range list {
+			allowedProject, _, _ := strings.Cut(allowed, "/")
+			scope := ""
+			switch {
+			case allowed == value || allowed == project:
+				scope = value
+			case value == allowedProject:
+				scope = allowed
+			}
+			if scope != "" && !seen[scope] {
+				seen[scope] = true
+				values = append(values, scope)
+			}
+		}
+	}
+	return values
+}
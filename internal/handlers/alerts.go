@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"code-pulse/internal/alerting"
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var alertSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"started_at":   "started_at",
+		"evaluated_at": "evaluated_at",
+		"rule":         "rule",
+	},
+	defaultSort: "-started_at",
+}
+
+// GetAlerts lists the alerts raised by the alert rules; pass status (firing,
+// resolved) or rule to narrow it. Team-restricted callers and the team and
+// service parameters only see alerts of rules on a repository or project in
+// scope.
+func (h *Handlers) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	rule := r.URL.Query().Get("rule")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	columns := `id, rule, status, value, baseline, message, started_at, resolved_at, evaluated_at,
+			notified_status = status`
+	from := `FROM alerts
+		WHERE ($1 = '' OR status = $1)
+		AND ($2 = '' OR rule = $2)
+		AND ($3::TEXT[] IS NULL OR rule = ANY($3))`
+
+	args := []interface{}{status, rule, pq.Array(scope.alertRules(h.config.AlertRules))}
+	rows := h.listRows(w, r, alertSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(alert *models.Alert) error {
+		return rows.Scan(&alert.ID, &alert.Rule, &alert.Status, &alert.Value, &alert.Baseline, &alert.Message,
+			&alert.StartedAt, &alert.ResolvedAt, &alert.EvaluatedAt, &alert.Notified)
+	})
+}
+
+// alertRules names the rules watching a repository or project the access
+// allows, or returns nil when the access is not narrowed. Rules on neither
+// watch everything and are left out of narrowed access.
+func (a access) alertRules(rules []config.AlertRuleConfig) []string {
+	if a.Repositories == nil && a.SonarqubeProjects == nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, rule := range rules {
+		if (rule.Repository != "" && allows(a.Repositories, rule.Repository)) ||
+			(rule.Project != "" && allows(a.SonarqubeProjects, rule.Project)) {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+type channelTestResult struct {
+	Channel string `json:"channel"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TestAlertChannels sends a test notification to every alert channel, or the
+// one channel names, answering 502 when any delivery fails.
+func (h *Handlers) TestAlertChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("channel")
+
+	channels, err := alerting.Channels(h.config.AlertChannels)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid alert configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	notification := &alerting.Notification{
+		Rule:      "test",
+		Status:    alerting.Firing,
+		Message:   "Test notification from code-pulse",
+		StartedAt: time.Now(),
+	}
+
+	status := http.StatusOK
+	results := []channelTestResult{}
+	for _, channel := range channels {
+		if name != "" && channel.Name != name {
+			continue
+		}
+		result := channelTestResult{Channel: channel.Name}
+		if err := channel.Send(r.Context(), notification); err != nil {
+			result.Error = err.Error()
+			status = http.StatusBadGateway
+		}
+		results = append(results, result)
+	}
+	if name != "" && len(results) == 0 {
+		http.Error(w, fmt.Sprintf("Unknown channel %q", name), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, status, results)
+}
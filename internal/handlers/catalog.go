@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"code-pulse/internal/auth"
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/models"
+)
+
+const maxCatalogSize = 1 << 20
+
+// Catalog returns the whole catalog on GET, as YAML with format=yaml or an
+// Accept of application/yaml, and replaces it on PUT with a YAML or JSON
+// body. Teams and services missing from a PUT are deleted. Team-restricted
+// callers get their own teams and the services those teams own.
+func (h *Handlers) Catalog(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		c, err := h.catalog.Get()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if teams := visibleTeams(r); teams != nil {
+			c = &models.Catalog{
+				Teams:    visibleCatalogTeams(c.Teams, teams),
+				Services: visibleCatalogServices(c.Services, teams),
+			}
+		}
+		writeCatalog(w, r, c)
+
+	case http.MethodPut:
+		if !requireAdmin(w, r) {
+			return
+		}
+		c, err := readCatalog(r)
+		if errors.Is(err, catalog.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.catalog.Import(c, true); err != nil {
+			writeCatalogError(w, err)
+			return
+		}
+		if c, err = h.catalog.Get(); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeCatalog(w, r, c)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CatalogTeams lists teams on GET /api/catalog/teams, and reads, creates or
+// replaces, and deletes one on GET, PUT and DELETE /api/catalog/teams/{name}.
+// Team-restricted callers only see their own teams.
+func (h *Handlers) CatalogTeams(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/catalog/teams"), "/")
+
+	switch {
+	case r.Method == http.MethodGet:
+		teams, err := h.catalog.Teams()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if visible := visibleTeams(r); visible != nil {
+			teams = visibleCatalogTeams(teams, visible)
+		}
+		if name == "" {
+			writeJSON(w, http.StatusOK, teams)
+			return
+		}
+		for _, team := range teams {
+			if team.Name == name {
+				writeJSON(w, http.StatusOK, team)
+				return
+			}
+		}
+		http.Error(w, "Team not found", http.StatusNotFound)
+
+	case r.Method == http.MethodPut && name != "":
+		if !requireAdmin(w, r) {
+			return
+		}
+		var team models.Team
+		if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		team.Name = name
+		if err := h.catalog.SaveTeam(&team); err != nil {
+			writeCatalogError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, team)
+
+	case r.Method == http.MethodDelete && name != "":
+		if !requireAdmin(w, r) {
+			return
+		}
+		deleted, err := h.catalog.DeleteTeam(name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Team not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		if name == "" {
+			w.Header().Set("Allow", "GET")
+		} else {
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CatalogServices lists services on GET /api/catalog/services, and reads,
+// creates or replaces, and deletes one on GET, PUT and DELETE
+// /api/catalog/services/{name}. Team-restricted callers only see the services
+// their teams own.
+func (h *Handlers) CatalogServices(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/catalog/services"), "/")
+
+	switch {
+	case r.Method == http.MethodGet:
+		services, err := h.catalog.Services()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if visible := visibleTeams(r); visible != nil {
+			services = visibleCatalogServices(services, visible)
+		}
+		if name == "" {
+			writeJSON(w, http.StatusOK, services)
+			return
+		}
+		for _, service := range services {
+			if service.Name == name {
+				writeJSON(w, http.StatusOK, service)
+				return
+			}
+		}
+		http.Error(w, "Service not found", http.StatusNotFound)
+
+	case r.Method == http.MethodPut && name != "":
+		if !requireAdmin(w, r) {
+			return
+		}
+		var service models.Service
+		if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		service.Name = name
+		if err := h.catalog.SaveService(&service); err != nil {
+			writeCatalogError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, service)
+
+	case r.Method == http.MethodDelete && name != "":
+		if !requireAdmin(w, r) {
+			return
+		}
+		deleted, err := h.catalog.DeleteService(name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Service not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		if name == "" {
+			w.Header().Set("Allow", "GET")
+		} else {
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// visibleCatalogTeams keeps the teams named in visible.
+func visibleCatalogTeams(teams []models.Team, visible []string) []models.Team {
+	kept := []models.Team{}
+	for _, team := range teams {
+		if allows(visible, team.Name) {
+			kept = append(kept, team)
+		}
+	}
+	return kept
+}
+
+// visibleCatalogServices keeps the services owned by a team named in visible.
+func visibleCatalogServices(services []models.Service, visible []string) []models.Service {
+	kept := []models.Service{}
+	for _, service := range services {
+		if allows(visible, service.Team) {
+			kept = append(kept, service)
+		}
+	}
+	return kept
+}
+
+// requireAdmin turns away callers without the admin scope, for the write
+// methods of routes every caller may read.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if principal := auth.FromContext(r.Context()); principal != nil && !principal.HasScope(auth.ScopeAdmin) {
+		http.Error(w, "Forbidden: requires scope "+auth.ScopeAdmin, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// readCatalog decodes a catalog from a JSON body, or a YAML one when the
+// content type says so.
+func readCatalog(r *http.Request) (*models.Catalog, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxCatalogSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if isYAML(r.Header.Get("Content-Type")) {
+		return catalog.Parse(data)
+	}
+
+	var c models.Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if err := catalog.Validate(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func writeCatalog(w http.ResponseWriter, r *http.Request, c *models.Catalog) {
+	if r.URL.Query().Get("format") != "yaml" && !isYAML(r.Header.Get("Accept")) {
+		writeJSON(w, http.StatusOK, c)
+		return
+	}
+
+	data, err := catalog.Marshal(c)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Encoding error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// writeCatalogError answers invalid catalog entries with 400 and anything
+// else with 500.
+func writeCatalogError(w http.ResponseWriter, err error) {
+	if errors.Is(err, catalog.ErrInvalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+}
+
+func isYAML(header string) bool {
+	for _, value := range strings.Split(header, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
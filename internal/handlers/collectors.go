@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code-pulse/internal/models"
+)
+
+func (h *Handlers) GetCollectors(w http.ResponseWriter, r *http.Request) {
+	checkHealth := r.URL.Query().Get("health") == "true"
+
+	query := `
+		SELECT id, collector, started_at, finished_at, status, error
+		FROM collection_runs
+		WHERE collector = $1
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	var statuses []models.CollectorStatus
+	for _, c := range h.registry.All() {
+		status := models.CollectorStatus{
+			Name:     c.Name(),
+			Enabled:  h.config.CollectorEnabled(c.Name()),
+			Schedule: h.config.CollectorSchedule(c.Name()),
+		}
+
+		if err := c.Validate(); err != nil {
+			status.Enabled = false
+			status.ValidationError = err.Error()
+		}
+
+		var run models.CollectionRun
+		err := h.db.QueryRow(query, c.Name()).Scan(&run.ID, &run.Collector, &run.StartedAt,
+			&run.FinishedAt, &run.Status, &run.Error)
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			status.LastRun = &run
+		}
+
+		if checkHealth && status.Enabled {
+			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+			healthErr := c.Health(ctx)
+			cancel()
+
+			healthy := healthErr == nil
+			status.Healthy = &healthy
+			if healthErr != nil {
+				status.HealthError = healthErr.Error()
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"code-pulse/internal/dora"
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetCISummary aggregates workflow runs across all CI providers. Success rate
+// only counts runs that reached a final state.
+func (h *Handlers) GetCISummary(w http.ResponseWriter, r *http.Request) {
+	repository := r.URL.Query().Get("repository")
+	provider := r.URL.Query().Get("provider")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT provider, repository, workflow_name,
+			COUNT(*) AS runs,
+			COUNT(*) FILTER (WHERE status = 'success') AS successes,
+			COUNT(*) FILTER (WHERE status = 'failure') AS failures,
+			COALESCE(COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION /
+				NULLIF(COUNT(*) FILTER (WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')), 0), 0) AS success_rate,
+			COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration), 0) AS median_duration,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY duration), 0) AS p95_duration
+		FROM github_workflows
+		WHERE ($1 = '' OR repository = $1)
+		AND ($2 = '' OR provider = $2)
+		AND created_at >= $3
+		AND created_at < $4
+		AND ($5::TEXT[] IS NULL OR repository = ANY($5))
+		GROUP BY provider, repository, workflow_name
+		ORDER BY provider, repository, workflow_name`
+
+	rows, err := h.db.Query(query, repository, provider, window.From, window.To, pq.Array(scope.Repositories))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(summary *models.CISummary) error {
+		return rows.Scan(&summary.Provider, &summary.Repository, &summary.WorkflowName, &summary.Runs,
+			&summary.Successes, &summary.Failures, &summary.SuccessRate, &summary.MedianDuration, &summary.P95Duration)
+	})
+}
+
+func (h *Handlers) GetDoraMetrics(w http.ResponseWriter, r *http.Request) {
+	repository := r.URL.Query().Get("repository")
+	environment := r.URL.Query().Get("environment")
+
+	if environment == "" {
+		environment = h.config.DoraEnvironment
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := dora.Filter{
+		Provider:      r.URL.Query().Get("provider"),
+		Environment:   environment,
+		IncidentLabel: h.config.DoraIncidentLabel,
+		From:          window.From,
+		To:            window.To,
+	}
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+	filter.JiraScopes = scope.JiraScopes
+	if repository != "" {
+		if !allows(scope.Repositories, repository) {
+			http.Error(w, "Forbidden: repository belongs to another team", http.StatusForbidden)
+			return
+		}
+		filter.Repositories = []string{repository}
+		filter.IncidentServices = []string{}
+	} else {
+		filter.Repositories = scope.Repositories
+		filter.IncidentServices = scope.IncidentServices
+	}
+
+	metrics, err := dora.Compute(h.db, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeRecord(w, r, metrics)
+}
+
+var incidentSort = listSort{
+	id: "i.id",
+	columns: map[string]string{
+		"triggered_at": "i.triggered_at",
+		"status":       "i.status",
+		"urgency":      "i.urgency",
+		"service_name": "i.service_name",
+	},
+	defaultSort: "-triggered_at",
+}
+
+func (h *Handlers) GetIncidents(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	repository := r.URL.Query().Get("repository")
+	status := r.URL.Query().Get("status")
+	urgency := r.URL.Query().Get("urgency")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `i.source, i.external_id, i.number, i.title, i.status, i.urgency, i.service_id, i.service_name,
+			NULLIF(s.repository, ''), i.triggered_at, i.acknowledged_at, i.resolved_at`
+	from := `FROM incidents i
+		LEFT JOIN incident_services s ON s.source = i.source AND s.service_id = i.service_id
+		WHERE ($1 = '' OR i.service_id = $1 OR i.service_name = $1)
+		AND ($2 = '' OR s.repository = $2)
+		AND ($3 = '' OR i.status = $3)
+		AND ($4 = '' OR i.urgency = $4)
+		AND i.triggered_at >= $5
+		AND i.triggered_at < $6
+		AND (($7::TEXT[] IS NULL AND $8::TEXT[] IS NULL) OR s.repository = ANY($7) OR i.service_id = ANY($8))`
+
+	args := []interface{}{service, repository, status, urgency, window.From, window.To,
+		pq.Array(scope.Repositories), pq.Array(scope.IncidentServices)}
+	rows := h.listRows(w, r, incidentSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(incident *models.Incident) error {
+		return rows.Scan(&incident.Source, &incident.ExternalID, &incident.Number, &incident.Title, &incident.Status,
+			&incident.Urgency, &incident.ServiceID, &incident.ServiceName, &incident.Repository,
+			&incident.TriggeredAt, &incident.AcknowledgedAt, &incident.ResolvedAt)
+	})
+}
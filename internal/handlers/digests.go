@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/digest"
+)
+
+type digestResult struct {
+	Team  string `json:"team"`
+	Error string `json:"error,omitempty"`
+}
+
+// Digests previews the weekly digest of a team on GET, as html (default) or
+// text per format, and on POST mails it to the team's recipients right away,
+// or every team's digest when team is left out. A failed delivery answers 502.
+func (h *Handlers) Digests(w http.ResponseWriter, r *http.Request) {
+	reporter := digest.New(h.db, h.config)
+	team := r.URL.Query().Get("team")
+
+	switch r.Method {
+	case http.MethodGet:
+		if team == "" {
+			http.Error(w, "team is required", http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "html" && format != "text" {
+			http.Error(w, fmt.Sprintf("invalid format %q, expected html or text", format), http.StatusBadRequest)
+			return
+		}
+
+		report, err := reporter.Build(team, time.Now())
+		if errors.Is(err, catalog.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("Unknown team %q", team), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		var body string
+		if format == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			body, err = report.Text()
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			body, err = report.HTML()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, body)
+
+	case http.MethodPost:
+		if !reporter.Enabled() {
+			http.Error(w, "Digests are not configured: set SMTP_HOST, SMTP_FROM and DIGEST_RECIPIENTS",
+				http.StatusServiceUnavailable)
+			return
+		}
+
+		teams := []string{team}
+		if team == "" {
+			teams = teams[:0]
+			for name := range h.config.DigestRecipients {
+				teams = append(teams, name)
+			}
+			sort.Strings(teams)
+		} else if len(h.config.DigestRecipients[team]) == 0 {
+			http.Error(w, fmt.Sprintf("No digest recipients for team %q", team), http.StatusNotFound)
+			return
+		}
+
+		status := http.StatusOK
+		results := []digestResult{}
+		for _, name := range teams {
+			result := digestResult{Team: name}
+			if err := reporter.Send(r.Context(), name); err != nil {
+				result.Error = err.Error()
+				status = http.StatusBadGateway
+			}
+			results = append(results, result)
+		}
+		writeJSON(w, status, results)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	formatJSON:   "application/json",
+	formatCSV:    "text/csv; charset=utf-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// responseFormat picks the output format from ?format=, then the Accept
+// header, defaulting to JSON.
+func responseFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := contentTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported format %q, expected json, csv or ndjson", format)
+		}
+		return format, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return formatCSV, nil
+		case "application/x-ndjson":
+			return formatNDJSON, nil
+		case "application/json":
+			return formatJSON, nil
+		}
+	}
+
+	return formatJSON, nil
+}
+
+// recordEncoder writes records of one type in a single format.
+type recordEncoder interface {
+	begin() error
+	encode(v interface{}) error
+	end() error
+}
+
+// newRecordEncoder returns an encoder of records of type t, limited to the
+// given top-level fields unless fields is nil.
+func newRecordEncoder(format string, w io.Writer, t reflect.Type, fields []string) recordEncoder {
+	switch format {
+	case formatCSV:
+		return &csvEncoder{w: csv.NewWriter(w), columns: selectColumns(csvColumns(t, "", "", nil), fields)}
+	case formatNDJSON:
+		return &ndjsonEncoder{w: w, fields: fields}
+	default:
+		return &jsonArrayEncoder{w: w, fields: fields}
+	}
+}
+
+// selectedFields parses the comma-separated fields parameter, which limits
+// records to the named JSON fields. Nested objects are selected as a whole.
+func selectedFields(r *http.Request, t reflect.Type) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	known := map[string]bool{}
+	for _, column := range csvColumns(t, "", "", nil) {
+		known[column.field] = true
+	}
+
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !known[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func selectColumns(columns []csvColumn, fields []string) []csvColumn {
+	if fields == nil {
+		return columns
+	}
+	var selected []csvColumn
+	for _, field := range fields {
+		for _, column := range columns {
+			if column.field == field {
+				selected = append(selected, column)
+			}
+		}
+	}
+	return selected
+}
+
+// marshalRecord encodes a record as JSON, keeping only the given fields in
+// the order they were asked for unless fields is nil.
+func marshalRecord(v interface{}, fields []string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || fields == nil {
+		return data, err
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// streamRows encodes every row of a query in the requested format while
+// reading it, instead of collecting the rows first. Errors before the first
+// row are reported with a status code; later ones can only end the response
+// early and are logged.
+func streamRows[T any](w http.ResponseWriter, r *http.Request, rows *sql.Rows, scan func(*T) error) {
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields, err := selectedFields(r, t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enc := newRecordEncoder(format, w, t, fields)
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		w.Header().Set("Content-Type", contentTypes[format])
+		return enc.begin()
+	}
+
+	fail := func(message string, err error) {
+		if !started {
+			http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Error streaming %s: %s: %v", r.URL.Path, message, err)
+	}
+
+	for rows.Next() {
+		var record T
+		if err := scan(&record); err != nil {
+			fail("Scan error", err)
+			return
+		}
+		if err := start(); err != nil {
+			fail("Write error", err)
+			return
+		}
+		if err := enc.encode(&record); err != nil {
+			fail("Write error", err)
+			return
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		fail("Database error", err)
+		return
+	}
+
+	if err := start(); err != nil {
+		fail("Write error", err)
+		return
+	}
+	if err := enc.end(); err != nil {
+		fail("Write error", err)
+	}
+}
+
+// writeRecords writes records computed in memory in the requested format,
+// like streamRows does for query results.
+func writeRecords[T any](w http.ResponseWriter, r *http.Request, records []T) {
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields, err := selectedFields(r, t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	enc := newRecordEncoder(format, w, t, fields)
+	if err := enc.begin(); err != nil {
+		log.Printf("Error writing %s: %v", r.URL.Path, err)
+		return
+	}
+	for i := range records {
+		if err := enc.encode(&records[i]); err != nil {
+			log.Printf("Error writing %s: %v", r.URL.Path, err)
+			return
+		}
+	}
+	if err := enc.end(); err != nil {
+		log.Printf("Error writing %s: %v", r.URL.Path, err)
+	}
+}
+
+// writeRecord writes a single record, as a one-row CSV or one NDJSON line
+// when those formats are requested.
+func writeRecord(w http.ResponseWriter, r *http.Request, v interface{}) {
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := selectedFields(r, reflect.TypeOf(v))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	if format == formatJSON {
+		data, err := marshalRecord(v, fields)
+		if err != nil {
+			log.Printf("Error writing %s: %v", r.URL.Path, err)
+			return
+		}
+		w.Write(append(data, '\n'))
+		return
+	}
+
+	enc := newRecordEncoder(format, w, reflect.TypeOf(v), fields)
+	if err := enc.begin(); err != nil {
+		log.Printf("Error writing %s: %v", r.URL.Path, err)
+		return
+	}
+	if err := enc.encode(v); err != nil {
+		log.Printf("Error writing %s: %v", r.URL.Path, err)
+		return
+	}
+	if err := enc.end(); err != nil {
+		log.Printf("Error writing %s: %v", r.URL.Path, err)
+	}
+}
+
+type jsonArrayEncoder struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
+
+func (e *jsonArrayEncoder) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonArrayEncoder) encode(v interface{}) error {
+	data, err := marshalRecord(v, e.fields)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonArrayEncoder) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+type ndjsonEncoder struct {
+	w      io.Writer
+	fields []string
+}
+
+func (e *ndjsonEncoder) begin() error { return nil }
+
+func (e *ndjsonEncoder) encode(v interface{}) error {
+	data, err := marshalRecord(v, e.fields)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+func (e *ndjsonEncoder) end() error { return nil }
+
+// csvColumn reaches a value through a path of field indexes from the record.
+// field is the top-level JSON field the column belongs to.
+type csvColumn struct {
+	name  string
+	field string
+	path  []int
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []csvColumn
+}
+
+func (e *csvEncoder) begin() error {
+	header := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = column.name
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) encode(v interface{}) error {
+	record := reflect.ValueOf(v)
+	row := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		row[i] = csvValue(record, column.path)
+	}
+	if err := e.w.Write(row); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvColumns flattens a struct into columns named after its JSON fields.
+// Embedded structs contribute their fields directly; other nested structs are
+// prefixed with their field name. Slices and maps stay in one column.
+func csvColumns(t reflect.Type, prefix, field string, path []int) []csvColumn {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return []csvColumn{{name: strings.TrimSuffix(prefix, "_"), field: field, path: path}}
+	}
+
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		name := structField.Name
+		if tag := structField.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+
+		fieldPath := append(path[:len(path):len(path)], i)
+		fieldType := structField.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		topLevel := field
+		if topLevel == "" {
+			topLevel = name
+		}
+
+		switch {
+		case structField.Anonymous && fieldType.Kind() == reflect.Struct:
+			columns = append(columns, csvColumns(fieldType, prefix, field, fieldPath)...)
+		case fieldType.Kind() == reflect.Struct && fieldType != timeType:
+			columns = append(columns, csvColumns(fieldType, prefix+name+"_", topLevel, fieldPath)...)
+		default:
+			columns = append(columns, csvColumn{name: prefix + name, field: topLevel, path: fieldPath})
+		}
+	}
+	return columns
+}
+
+func csvValue(v reflect.Value, path []int) string {
+	for _, index := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			values := make([]string, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).String()
+			}
+			return strings.Join(values, ";")
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
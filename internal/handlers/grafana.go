@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The Grafana JSON datasource protocol: /search lists targets, /query returns
+// one series per repository, project or ticket source of each target, and
+// /annotations marks deployments. Ad hoc filters narrow queries by the keys
+// from /tag-keys.
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type grafanaQueryRequest struct {
+	Range      grafanaRange `json:"range"`
+	IntervalMs int64        `json:"intervalMs"`
+	Targets    []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	} `json:"targets"`
+	AdhocFilters []grafanaFilter `json:"adhocFilters"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaAnnotation struct {
+	Time  int64    `json:"time"`
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Tags  []string `json:"tags"`
+}
+
+type grafanaTagKey struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type grafanaTagValue struct {
+	Text string `json:"text"`
+}
+
+// grafanaTarget selects (group, bucket start in epoch seconds, value) rows.
+// $1 and $2 bound the time range and $3 is the bucket width in seconds;
+// filters are the ad hoc filter keys bound to $4 onwards.
+type grafanaTarget struct {
+	query   string
+	filters []string
+}
+
+const grafanaBucket = `FLOOR(EXTRACT(EPOCH FROM %s) / $3) * $3`
+
+func grafanaWorkflowTarget(value string) grafanaTarget {
+	return grafanaTarget{
+		query: `
+			SELECT repository, ` + fmt.Sprintf(grafanaBucket, "created_at") + ` AS bucket, ` + value + `
+			FROM github_workflows
+			WHERE created_at >= $1 AND created_at < $2
+			AND ($4 = '' OR repository = $4)
+			AND ($5 = '' OR provider = $5)
+			AND ($6 = '' OR workflow_name = $6)
+			GROUP BY repository, bucket
+			ORDER BY repository, bucket`,
+		filters: []string{"repository", "provider", "workflow"},
+	}
+}
+
+var grafanaTargets = map[string]grafanaTarget{
+	"workflow.runs":     grafanaWorkflowTarget("COUNT(*)"),
+	"workflow.failures": grafanaWorkflowTarget("COUNT(*) FILTER (WHERE status = 'failure')"),
+	"workflow.success_rate": grafanaWorkflowTarget(`COALESCE(COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION /
+		NULLIF(COUNT(*) FILTER (WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')), 0), 0)`),
+	"workflow.duration":       grafanaWorkflowTarget("AVG(duration)"),
+	"workflow.queue_duration": grafanaWorkflowTarget("AVG(queue_duration)"),
+	"deployments.count": {
+		query: `
+			SELECT repository, ` + fmt.Sprintf(grafanaBucket, "created_at") + ` AS bucket, COUNT(*)
+			FROM deployments
+			WHERE created_at >= $1 AND created_at < $2
+			AND status = 'success'
+			AND ($4 = '' OR repository = $4)
+			AND ($5 = '' OR provider = $5)
+			AND ($6 = '' OR environment = $6)
+			GROUP BY repository, bucket
+			ORDER BY repository, bucket`,
+		filters: []string{"repository", "provider", "environment"},
+	},
+	"tickets.created": {
+		query: `
+			SELECT source, ` + fmt.Sprintf(grafanaBucket, "created_at") + ` AS bucket, COUNT(*)
+			FROM jira_tickets
+			WHERE created_at >= $1 AND created_at < $2
+			AND ($4 = '' OR source = $4)
+			GROUP BY source, bucket
+			ORDER BY source, bucket`,
+		filters: []string{"source"},
+	},
+	"tickets.resolved": {
+		query: `
+			SELECT source, ` + fmt.Sprintf(grafanaBucket, "resolved_at") + ` AS bucket, COUNT(*)
+			FROM jira_tickets
+			WHERE resolved_at >= $1 AND resolved_at < $2
+			AND ($4 = '' OR source = $4)
+			GROUP BY source, bucket
+			ORDER BY source, bucket`,
+		filters: []string{"source"},
+	},
+	"tickets.lead_time_hours": {
+		query: `
+			SELECT source, ` + fmt.Sprintf(grafanaBucket, "resolved_at") + ` AS bucket,
+				AVG(EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600
+			FROM jira_tickets
+			WHERE resolved_at >= $1 AND resolved_at < $2
+			AND ($4 = '' OR source = $4)
+			GROUP BY source, bucket
+			ORDER BY source, bucket`,
+		filters: []string{"source"},
+	},
+}
+
+// grafanaSonarqubePrefix targets are sonarqube.<metric_key>, averaging the
+// project-level measure of the main branch per bucket.
+const grafanaSonarqubePrefix = "sonarqube."
+
+var grafanaSonarqubeTarget = grafanaTarget{
+	query: `
+		SELECT project_key, ` + fmt.Sprintf(grafanaBucket, "collected_at") + ` AS bucket, AVG(numeric_value)
+		FROM sonarqube_metrics
+		WHERE collected_at >= $1 AND collected_at < $2
+		AND metric_key = $4
+		AND branch = '' AND pull_request = '' AND qualifier = 'TRK'
+		AND numeric_value IS NOT NULL
+		AND ($5 = '' OR project_key = $5)
+		GROUP BY project_key, bucket
+		ORDER BY project_key, bucket`,
+	filters: []string{"metric", "project"},
+}
+
+var grafanaTagKeys = []string{"repository", "provider", "workflow", "environment", "project", "source"}
+
+var grafanaTagValueQueries = map[string]string{
+	"repository":  `SELECT DISTINCT repository FROM github_workflows UNION SELECT DISTINCT repository FROM deployments ORDER BY 1`,
+	"provider":    `SELECT DISTINCT provider FROM github_workflows ORDER BY 1`,
+	"workflow":    `SELECT DISTINCT workflow_name FROM github_workflows ORDER BY 1`,
+	"environment": `SELECT DISTINCT environment FROM deployments ORDER BY 1`,
+	"project":     `SELECT DISTINCT project_key FROM sonarqube_metrics ORDER BY 1`,
+	"source":      `SELECT DISTINCT source FROM jira_tickets ORDER BY 1`,
+}
+
+// GrafanaTest answers the datasource connection test.
+func (h *Handlers) GrafanaTest(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/grafana/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handlers) GrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Target string `json:"target"`
+	}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	names := make([]string, 0, len(grafanaTargets))
+	for name := range grafanaTargets {
+		names = append(names, name)
+	}
+
+	rows, err := h.db.Query(`SELECT DISTINCT metric_key FROM sonarqube_metrics WHERE numeric_value IS NOT NULL`)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var metricKey string
+		if err := rows.Scan(&metricKey); err != nil {
+			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		names = append(names, grafanaSonarqubePrefix+metricKey)
+	}
+
+	matches := []string{}
+	for _, name := range names {
+		if strings.Contains(name, request.Target) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+func (h *Handlers) GrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var request grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// $__interval in whole seconds; Grafana sends it as intervalMs.
+	interval := request.IntervalMs / 1000
+	if interval < 1 {
+		interval = 1
+	}
+
+	filters := make(map[string]string)
+	for _, filter := range request.AdhocFilters {
+		if filter.Operator == "=" || filter.Operator == "" {
+			filters[filter.Key] = filter.Value
+		}
+	}
+
+	series := []grafanaSeries{}
+	for _, requested := range request.Targets {
+		if requested.Target == "" {
+			continue
+		}
+
+		target, ok := grafanaTargets[requested.Target]
+		targetFilters := filters
+		if metricKey, isSonarqube := strings.CutPrefix(requested.Target, grafanaSonarqubePrefix); isSonarqube {
+			target, ok = grafanaSonarqubeTarget, true
+			targetFilters = map[string]string{"metric": metricKey, "project": filters["project"]}
+		}
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown target: %s", requested.Target), http.StatusBadRequest)
+			return
+		}
+
+		args := []interface{}{request.Range.From, request.Range.To, interval}
+		for _, key := range target.filters {
+			args = append(args, targetFilters[key])
+		}
+
+		targetSeries, err := h.grafanaSeries(requested.Target, target, args)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		series = append(series, targetSeries...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *Handlers) grafanaSeries(name string, target grafanaTarget, args []interface{}) ([]grafanaSeries, error) {
+	rows, err := h.db.Query(target.query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []grafanaSeries
+	for rows.Next() {
+		var group string
+		var bucket, value float64
+		if err := rows.Scan(&group, &bucket, &value); err != nil {
+			return nil, err
+		}
+
+		label := name + " " + group
+		if len(series) == 0 || series[len(series)-1].Target != label {
+			series = append(series, grafanaSeries{Target: label, Datapoints: [][2]float64{}})
+		}
+		current := &series[len(series)-1]
+		current.Datapoints = append(current.Datapoints, [2]float64{value, bucket * 1000})
+	}
+
+	return series, rows.Err()
+}
+
+// GrafanaAnnotations marks successful deployments in the range. The
+// annotation query takes URL-style filters, e.g.
+// environment=production&repository=org/repo; the environment defaults to
+// DORA_ENVIRONMENT.
+func (h *Handlers) GrafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Range      grafanaRange `json:"range"`
+		Annotation struct {
+			Query string `json:"query"`
+		} `json:"annotation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	params, err := url.ParseQuery(request.Annotation.Query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid annotation query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	environment := params.Get("environment")
+	if environment == "" {
+		environment = h.config.DoraEnvironment
+	}
+
+	query := `
+		SELECT provider, repository, environment, ref, sha, created_at
+		FROM deployments
+		WHERE created_at >= $1 AND created_at < $2
+		AND status = 'success'
+		AND environment = $3
+		AND ($4 = '' OR repository = $4)
+		AND ($5 = '' OR provider = $5)
+		ORDER BY created_at`
+
+	rows, err := h.db.Query(query, request.Range.From, request.Range.To, environment,
+		params.Get("repository"), params.Get("provider"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	annotations := []grafanaAnnotation{}
+	for rows.Next() {
+		var provider, repository, env, ref, sha string
+		var createdAt time.Time
+		if err := rows.Scan(&provider, &repository, &env, &ref, &sha, &createdAt); err != nil {
+			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		annotations = append(annotations, grafanaAnnotation{
+			Time:  createdAt.UnixMilli(),
+			Title: fmt.Sprintf("Deployed %s to %s", repository, env),
+			Text:  fmt.Sprintf("%s %s", ref, sha),
+			Tags:  []string{"deployment", provider, repository, env},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotations)
+}
+
+func (h *Handlers) GrafanaTagKeys(w http.ResponseWriter, r *http.Request) {
+	keys := make([]grafanaTagKey, 0, len(grafanaTagKeys))
+	for _, key := range grafanaTagKeys {
+		keys = append(keys, grafanaTagKey{Type: "string", Text: key})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (h *Handlers) GrafanaTagValues(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	query, ok := grafanaTagValueQueries[request.Key]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown tag key: %s", request.Key), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	values := []grafanaTagValue{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		values = append(values, grafanaTagValue{Text: value})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(values)
+}
@@ -8,15 +8,23 @@ import (
 	"strconv"
 	"time"
 
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/collector"
+	"code-pulse/internal/config"
 	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type Handlers struct {
-	db *sql.DB
+	db       *sql.DB
+	registry *collector.Registry
+	config   *config.Config
+	catalog  *catalog.Store
 }
 
-func New(db *sql.DB) *Handlers {
-	return &Handlers{db: db}
+func New(db *sql.DB, registry *collector.Registry, config *config.Config) *Handlers {
+	return &Handlers{db: db, registry: registry, config: config, catalog: catalog.NewStore(db)}
 }
 
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
@@ -24,132 +32,660 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+var workflowSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"created_at":     "created_at",
+		"completed_at":   "completed_at",
+		"duration":       "duration",
+		"queue_duration": "queue_duration",
+		"repository":     "repository",
+		"workflow_name":  "workflow_name",
+		"status":         "status",
+	},
+	defaultSort: "-created_at",
+}
+
+// GetGithubMetrics lists CI runs of every provider, also served as
+// /api/metrics/ci; pass provider to restrict it to one.
 func (h *Handlers) GetGithubMetrics(w http.ResponseWriter, r *http.Request) {
 	repository := r.URL.Query().Get("repository")
-	daysStr := r.URL.Query().Get("days")
+	provider := r.URL.Query().Get("provider")
 	
-	days := 30
-	if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil {
-			days = d
-		}
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
 	}
 
-	query := `
-		SELECT repository, workflow_name, status, duration, created_at, completed_at
-		FROM github_workflows
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `provider, external_id, repository, workflow_name, status, duration,
+			head_branch, head_sha, queue_duration, cause, created_at, completed_at`
+	from := `FROM github_workflows
 		WHERE ($1 = '' OR repository = $1)
-		AND created_at >= $2
-		ORDER BY created_at DESC`
+		AND ($2 = '' OR provider = $2)
+		AND created_at >= $3
+		AND created_at < $4
+		AND ($5::TEXT[] IS NULL OR repository = ANY($5))`
 
-	since := time.Now().AddDate(0, 0, -days)
-	rows, err := h.db.Query(query, repository, since)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+	args := []interface{}{repository, provider, window.From, window.To, pq.Array(scope.Repositories)}
+	rows := h.listRows(w, r, workflowSort, columns, from, args)
+	if rows == nil {
 		return
 	}
 	defer rows.Close()
 
-	var workflows []models.GithubWorkflow
-	for rows.Next() {
-		var workflow models.GithubWorkflow
-		err := rows.Scan(&workflow.Repository, &workflow.WorkflowName, &workflow.Status,
-			&workflow.Duration, &workflow.CreatedAt, &workflow.CompletedAt)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
-			return
-		}
-		workflows = append(workflows, workflow)
-	}
+	streamRows(w, r, rows, func(workflow *models.GithubWorkflow) error {
+		return rows.Scan(&workflow.Provider, &workflow.ExternalID, &workflow.Repository, &workflow.WorkflowName,
+			&workflow.Status, &workflow.Duration, &workflow.HeadBranch, &workflow.HeadSHA, &workflow.QueueDuration,
+			&workflow.Cause, &workflow.CreatedAt, &workflow.CompletedAt)
+	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workflows)
+// Measures without a numeric value sort below every number.
+var sonarqubeMetricSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"collected_at":  "collected_at",
+		"project_key":   "project_key",
+		"metric_key":    "metric_key",
+		"numeric_value": "COALESCE(numeric_value, '-Infinity')",
+	},
+	defaultSort: "-collected_at",
 }
 
 func (h *Handlers) GetSonarqubeMetrics(w http.ResponseWriter, r *http.Request) {
 	projectKey := r.URL.Query().Get("project_key")
 	metricKey := r.URL.Query().Get("metric_key")
-	daysStr := r.URL.Query().Get("days")
+	branch := r.URL.Query().Get("branch")
+	pullRequest := r.URL.Query().Get("pull_request")
+	qualifier := r.URL.Query().Get("qualifier")
 	
-	days := 30
-	if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil {
-			days = d
-		}
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
 	}
 
-	query := `
-		SELECT project_key, metric_key, value, component, collected_at
-		FROM sonarqube_metrics
+	if qualifier == "" {
+		qualifier = "TRK"
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minValue, err := parseOptionalFloat(r.URL.Query().Get("min"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid min value: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	maxValue, err := parseOptionalFloat(r.URL.Query().Get("max"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid max value: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	columns := `project_key, branch, pull_request, metric_key, value, numeric_value, component, qualifier, path, collected_at`
+	from := `FROM sonarqube_metrics
 		WHERE ($1 = '' OR project_key = $1)
 		AND ($2 = '' OR metric_key = $2)
-		AND collected_at >= $3
-		ORDER BY collected_at DESC`
+		AND ($3 = '' OR branch = $3)
+		AND ($4 = '' OR pull_request = $4)
+		AND ($5::DOUBLE PRECISION IS NULL OR numeric_value >= $5)
+		AND ($6::DOUBLE PRECISION IS NULL OR numeric_value <= $6)
+		AND qualifier = $7
+		AND collected_at >= $8
+		AND collected_at < $9
+		AND ($10::TEXT[] IS NULL OR project_key = ANY($10))`
 
-	since := time.Now().AddDate(0, 0, -days)
-	rows, err := h.db.Query(query, projectKey, metricKey, since)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+	args := []interface{}{projectKey, metricKey, branch, pullRequest, minValue, maxValue, qualifier,
+		window.From, window.To, pq.Array(scope.SonarqubeProjects)}
+	rows := h.listRows(w, r, sonarqubeMetricSort, columns, from, args)
+	if rows == nil {
 		return
 	}
 	defer rows.Close()
 
-	var metrics []models.SonarqubeMetric
-	for rows.Next() {
-		var metric models.SonarqubeMetric
-		err := rows.Scan(&metric.ProjectKey, &metric.MetricKey, &metric.Value,
-			&metric.Component, &metric.CollectedAt)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
-			return
-		}
-		metrics = append(metrics, metric)
-	}
+	streamRows(w, r, rows, func(metric *models.SonarqubeMetric) error {
+		return rows.Scan(&metric.ProjectKey, &metric.Branch, &metric.PullRequest, &metric.MetricKey, &metric.Value,
+			&metric.NumericValue, &metric.Component, &metric.Qualifier, &metric.Path, &metric.CollectedAt)
+	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(metrics)
+var ticketSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"ticket_key": "ticket_key",
+		"status":     "status",
+		"priority":   "priority",
+	},
+	defaultSort: "-created_at",
 }
 
+// GetJiraMetrics lists tickets of every issue tracker; pass source (jira,
+// linear) to restrict it to one. assignee takes an account id, person the
+// email of a person, matching the accounts linked to them in every tracker.
 func (h *Handlers) GetJiraMetrics(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 	assignee := r.URL.Query().Get("assignee")
-	daysStr := r.URL.Query().Get("days")
+	source := r.URL.Query().Get("source")
+	person := r.URL.Query().Get("person")
 	
-	days := 30
-	if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil {
-			days = d
-		}
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
 	}
 
-	query := `
-		SELECT ticket_key, summary, status, priority, assignee, created_at, updated_at, resolved_at
-		FROM jira_tickets
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `source, ticket_key, summary, status, status_category, priority, assignee, assignee_name, estimate,
+			sprint_id, created_at, updated_at, resolved_at, labels, components`
+	from := `FROM jira_tickets
 		WHERE ($1 = '' OR status = $1)
 		AND ($2 = '' OR assignee = $2)
-		AND created_at >= $3
-		ORDER BY created_at DESC`
+		AND ($3 = '' OR source = $3)
+		AND created_at >= $4
+		AND created_at < $5
+		AND ticket_in_scope(ticket_key, components, $6)
+		AND ($7 = '' OR EXISTS (
+			SELECT 1 FROM identities i
+			JOIN people p ON p.id = i.person_id
+			WHERE i.system = jira_tickets.source
+			AND i.account_id = jira_tickets.assignee
+			AND p.email = lower($7)
+		))`
+
+	args := []interface{}{status, assignee, source, window.From, window.To, pq.Array(scope.JiraScopes), person}
+	rows := h.listRows(w, r, ticketSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
 
-	since := time.Now().AddDate(0, 0, -days)
-	rows, err := h.db.Query(query, status, assignee, since)
+	streamRows(w, r, rows, func(ticket *models.JiraTicket) error {
+		return rows.Scan(&ticket.Source, &ticket.TicketKey, &ticket.Summary, &ticket.Status, &ticket.StatusCategory,
+			&ticket.Priority, &ticket.Assignee, &ticket.AssigneeName, &ticket.Estimate, &ticket.SprintID, &ticket.CreatedAt,
+			&ticket.UpdatedAt, &ticket.ResolvedAt, pq.Array(&ticket.Labels), pq.Array(&ticket.Components))
+	})
+}
+
+func (h *Handlers) GetSonarqubeQualityGates(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	branch := r.URL.Query().Get("branch")
+	pullRequest := r.URL.Query().Get("pull_request")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bucket, err := parseBucket(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT date_trunc($6, collected_at AT TIME ZONE $7) AT TIME ZONE $7 AS day,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE status = 'OK') AS passed
+		FROM sonarqube_quality_gates
+		WHERE ($1 = '' OR project_key = $1)
+		AND ($2 = '' OR branch = $2)
+		AND ($3 = '' OR pull_request = $3)
+		AND collected_at >= $4
+		AND collected_at < $5
+		AND ($8::TEXT[] IS NULL OR project_key = ANY($8))
+		GROUP BY day
+		ORDER BY day`
+
+	rows, err := h.db.Query(query, projectKey, branch, pullRequest, window.From, window.To,
+		bucket, window.Location.String(), pq.Array(scope.SonarqubeProjects))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var tickets []models.JiraTicket
-	for rows.Next() {
-		var ticket models.JiraTicket
-		err := rows.Scan(&ticket.TicketKey, &ticket.Summary, &ticket.Status,
-			&ticket.Priority, &ticket.Assignee, &ticket.CreatedAt, &ticket.UpdatedAt, &ticket.ResolvedAt)
+	streamRows(w, r, rows, func(passRate *models.QualityGatePassRate) error {
+		if err := rows.Scan(&passRate.Date, &passRate.Total, &passRate.Passed); err != nil {
+			return err
+		}
+		passRate.Date = passRate.Date.In(window.Location)
+		if passRate.Total > 0 {
+			passRate.PassRate = float64(passRate.Passed) / float64(passRate.Total)
+		}
+		return nil
+	})
+}
+
+func (h *Handlers) GetFailingQualityGates(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	// Conditions are aggregated per gate so that they are read with the gate
+	// rather than with a query per row.
+	query := `
+		SELECT latest.id, latest.project_key, latest.branch, latest.pull_request, latest.status, latest.collected_at,
+			conditions.conditions
+		FROM (
+			SELECT DISTINCT ON (project_key, branch, pull_request) id, project_key, branch, pull_request, status, collected_at
+			FROM sonarqube_quality_gates
+			WHERE ($1 = '' OR project_key = $1)
+			AND ($2::TEXT[] IS NULL OR project_key = ANY($2))
+			ORDER BY project_key, branch, pull_request, collected_at DESC
+		) latest
+		LEFT JOIN LATERAL (
+			SELECT json_agg(json_build_object(
+				'metric_key', metric_key,
+				'comparator', comparator,
+				'error_threshold', error_threshold,
+				'actual_value', actual_value,
+				'status', status
+			) ORDER BY metric_key) AS conditions
+			FROM sonarqube_quality_gate_conditions
+			WHERE quality_gate_id = latest.id
+		) conditions ON TRUE
+		WHERE latest.status = 'ERROR'
+		ORDER BY latest.project_key, latest.branch, latest.pull_request`
+
+	rows, err := h.db.Query(query, projectKey, pq.Array(scope.SonarqubeProjects))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(gate *models.SonarqubeQualityGate) error {
+		var conditions []byte
+		if err := rows.Scan(&gate.ID, &gate.ProjectKey, &gate.Branch, &gate.PullRequest, &gate.Status, &gate.CollectedAt,
+			&conditions); err != nil {
+			return err
+		}
+
+		if conditions != nil {
+			if err := json.Unmarshal(conditions, &gate.Conditions); err != nil {
+				return fmt.Errorf("failed to decode conditions: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+var sonarqubePullRequestSort = listSort{
+	id: "sp.id",
+	columns: map[string]string{
+		"analysis_date": "sp.analysis_date",
+		"project_key":   "sp.project_key",
+		"repository":    "sp.repository",
+		"pull_request":  "sp.pull_request",
+	},
+	defaultSort: "-analysis_date",
+}
+
+func (h *Handlers) GetSonarqubePullRequests(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	repository := r.URL.Query().Get("repository")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `sp.project_key, sp.repository, sp.pull_request, sp.title, sp.branch, sp.base_branch,
+			sp.quality_gate_status, sp.analysis_date, coverage.numeric_value,
+			gp.id, gp.number, gp.title, gp.author, gp.state, gp.created_at, gp.updated_at, gp.closed_at, gp.merged_at`
+	from := `FROM sonarqube_pull_requests sp
+		LEFT JOIN LATERAL (
+			SELECT numeric_value
+			FROM sonarqube_metrics
+			WHERE project_key = sp.project_key
+			AND pull_request = sp.pull_request
+			AND metric_key = 'new_coverage'
+			ORDER BY collected_at DESC
+			LIMIT 1
+		) coverage ON TRUE
+		LEFT JOIN github_pull_requests gp
+			ON gp.repository = sp.repository AND gp.number::text = sp.pull_request
+		WHERE ($1 = '' OR sp.project_key = $1)
+		AND ($2 = '' OR sp.repository = $2)
+		AND sp.analysis_date >= $3
+		AND sp.analysis_date < $4
+		AND ($5::TEXT[] IS NULL OR sp.project_key = ANY($5))`
+
+	args := []interface{}{projectKey, repository, window.From, window.To, pq.Array(scope.SonarqubeProjects)}
+	rows := h.listRows(w, r, sonarqubePullRequestSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(pr *models.PullRequestQuality) error {
+		var githubID, githubNumber sql.NullInt64
+		var githubTitle, githubAuthor, githubState sql.NullString
+		var githubCreatedAt, githubUpdatedAt sql.NullTime
+		var githubClosedAt, githubMergedAt *time.Time
+
+		err := rows.Scan(&pr.ProjectKey, &pr.Repository, &pr.PullRequest, &pr.Title, &pr.Branch, &pr.BaseBranch,
+			&pr.QualityGateStatus, &pr.AnalysisDate, &pr.NewCoverage,
+			&githubID, &githubNumber, &githubTitle, &githubAuthor, &githubState,
+			&githubCreatedAt, &githubUpdatedAt, &githubClosedAt, &githubMergedAt)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
-			return
+			return err
+		}
+
+		if githubID.Valid {
+			pr.Github = &models.GithubPullRequest{
+				ID:         int(githubID.Int64),
+				Repository: pr.Repository,
+				Number:     int(githubNumber.Int64),
+				Title:      githubTitle.String,
+				Author:     githubAuthor.String,
+				State:      githubState.String,
+				HeadBranch: pr.Branch,
+				BaseBranch: pr.BaseBranch,
+				CreatedAt:  githubCreatedAt.Time,
+				UpdatedAt:  githubUpdatedAt.Time,
+				ClosedAt:   githubClosedAt,
+				MergedAt:   githubMergedAt,
+			}
 		}
-		tickets = append(tickets, ticket)
+
+		return nil
+	})
+}
+
+func (h *Handlers) GetSonarqubeIssueFlow(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	issueType := r.URL.Query().Get("type")
+	severity := r.URL.Query().Get("severity")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tickets)
-}
\ No newline at end of file
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bucket, err := parseBucket(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		WITH filtered AS (
+			SELECT created_at, closed_at
+			FROM sonarqube_issues
+			WHERE ($1 = '' OR project_key = $1)
+			AND ($2 = '' OR type = $2)
+			AND ($3 = '' OR severity = $3)
+			AND ($8::TEXT[] IS NULL OR project_key = ANY($8))
+		),
+		opened AS (
+			SELECT date_trunc($6, created_at AT TIME ZONE $7) AS day, COUNT(*) AS count
+			FROM filtered
+			WHERE created_at >= $4 AND created_at < $5
+			GROUP BY day
+		),
+		closed AS (
+			SELECT date_trunc($6, closed_at AT TIME ZONE $7) AS day, COUNT(*) AS count
+			FROM filtered
+			WHERE closed_at >= $4 AND closed_at < $5
+			GROUP BY day
+		)
+		SELECT COALESCE(opened.day, closed.day) AT TIME ZONE $7 AS day,
+			COALESCE(opened.count, 0), COALESCE(closed.count, 0)
+		FROM opened
+		FULL OUTER JOIN closed ON opened.day = closed.day
+		ORDER BY day`
+
+	rows, err := h.db.Query(query, projectKey, issueType, severity, window.From, window.To,
+		bucket, window.Location.String(), pq.Array(scope.SonarqubeProjects))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(day *models.IssueFlow) error {
+		if err := rows.Scan(&day.Date, &day.Opened, &day.Closed); err != nil {
+			return err
+		}
+		day.Date = day.Date.In(window.Location)
+		return nil
+	})
+}
+
+func (h *Handlers) GetSonarqubeTimeToFix(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	issueType := r.URL.Query().Get("type")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	if issueType == "" {
+		issueType = "VULNERABILITY"
+	}
+
+	window, err := parseTimeRange(r, 90)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT severity,
+			COUNT(*) AS fixed,
+			AVG(EXTRACT(EPOCH FROM closed_at - created_at)) / 3600 AS mean_hours,
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM closed_at - created_at)) / 3600 AS median_hours
+		FROM sonarqube_issues
+		WHERE ($1 = '' OR project_key = $1)
+		AND type = $2
+		AND resolution = 'FIXED'
+		AND closed_at >= $3
+		AND closed_at < $4
+		AND ($5::TEXT[] IS NULL OR project_key = ANY($5))
+		GROUP BY severity
+		ORDER BY severity`
+
+	rows, err := h.db.Query(query, projectKey, issueType, window.From, window.To,
+		pq.Array(scope.SonarqubeProjects))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(timeToFix *models.TimeToFix) error {
+		return rows.Scan(&timeToFix.Severity, &timeToFix.Fixed, &timeToFix.MeanHours, &timeToFix.MedianHours)
+	})
+}
+
+var componentSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"value":        "numeric_value",
+		"component":    "component",
+		"path":         "path",
+		"collected_at": "collected_at",
+	},
+	defaultSort: "-value",
+}
+
+// GetSonarqubeComponents lists the latest value of a metric for each file or
+// directory of a project, highest first by default.
+func (h *Handlers) GetSonarqubeComponents(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	metricKey := r.URL.Query().Get("metric_key")
+	qualifier := r.URL.Query().Get("qualifier")
+
+	if projectKey == "" || metricKey == "" {
+		http.Error(w, "project_key and metric_key are required", http.StatusBadRequest)
+		return
+	}
+
+	if qualifier == "" {
+		qualifier = "FIL"
+	}
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+	if !allows(scope.SonarqubeProjects, projectKey) {
+		http.Error(w, "Forbidden: project belongs to another team", http.StatusForbidden)
+		return
+	}
+
+	columns := `component, path, qualifier, metric_key, numeric_value, collected_at`
+	from := `FROM (
+			SELECT DISTINCT ON (component) id, component, path, qualifier, metric_key, numeric_value, collected_at
+			FROM sonarqube_metrics
+			WHERE project_key = $1
+			AND metric_key = $2
+			AND pull_request = ''
+			AND numeric_value IS NOT NULL
+			ORDER BY component, collected_at DESC
+		) latest
+		WHERE qualifier = $3`
+
+	rows := h.listRows(w, r, componentSort, columns, from, []interface{}{projectKey, metricKey, qualifier})
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(measure *models.ComponentMeasure) error {
+		return rows.Scan(&measure.Component, &measure.Path, &measure.Qualifier, &measure.MetricKey,
+			&measure.Value, &measure.CollectedAt)
+	})
+}
+
+var churnComplexitySort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"risk":          "complexity * commits",
+		"complexity":    "complexity",
+		"coverage":      "COALESCE(coverage, '-Infinity')",
+		"commits":       "commits",
+		"lines_changed": "lines_changed",
+		"path":          "path",
+	},
+	defaultSort: "-risk",
+}
+
+// GetChurnComplexity lists the files of a project changed in the window with
+// their latest complexity and coverage. By default the files that are both
+// complex and often changed come first.
+func (h *Handlers) GetChurnComplexity(w http.ResponseWriter, r *http.Request) {
+	projectKey := r.URL.Query().Get("project_key")
+	repository := r.URL.Query().Get("repository")
+
+	if projectKey == "" || repository == "" {
+		http.Error(w, "project_key and repository are required", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+	if !allows(scope.SonarqubeProjects, projectKey) || !allows(scope.Repositories, repository) {
+		http.Error(w, "Forbidden: project or repository belongs to another team", http.StatusForbidden)
+		return
+	}
+
+	window, err := parseTimeRange(r, 90)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `path, complexity, coverage, commits, lines_changed`
+	from := `FROM (
+			WITH latest AS (
+				SELECT DISTINCT ON (path, metric_key) id, path, metric_key, numeric_value
+				FROM sonarqube_metrics
+				WHERE project_key = $1
+				AND qualifier = 'FIL'
+				AND pull_request = ''
+				AND metric_key IN ('complexity', 'coverage')
+				ORDER BY path, metric_key, collected_at DESC
+			),
+			churn AS (
+				SELECT path, COUNT(DISTINCT sha) AS commits, SUM(additions + deletions) AS lines_changed
+				FROM github_commit_files
+				WHERE repository = $2
+				AND committed_at >= $3
+				AND committed_at < $4
+				GROUP BY path
+			)
+			SELECT complexity.id, complexity.path, complexity.numeric_value AS complexity,
+				coverage.numeric_value AS coverage, churn.commits, churn.lines_changed
+			FROM latest complexity
+			JOIN churn ON churn.path = complexity.path
+			LEFT JOIN latest coverage ON coverage.path = complexity.path AND coverage.metric_key = 'coverage'
+			WHERE complexity.metric_key = 'complexity'
+		) files
+		WHERE complexity IS NOT NULL`
+
+	rows := h.listRows(w, r, churnComplexitySort, columns, from,
+		[]interface{}{projectKey, repository, window.From, window.To})
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(file *models.ChurnComplexity) error {
+		return rows.Scan(&file.Path, &file.Complexity, &file.Coverage, &file.Commits, &file.LinesChanged)
+	})
+}
+
+func parseOptionalFloat(valueStr string) (*float64, error) {
+	if valueStr == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
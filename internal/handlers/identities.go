@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var identitySort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"system":       "system",
+		"account_id":   "account_id",
+		"display_name": "display_name",
+		"last_seen_at": "last_seen_at",
+	},
+	defaultSort: "system",
+}
+
+var personSort = listSort{
+	id: "p.id",
+	columns: map[string]string{
+		"name":       "p.name",
+		"email":      "COALESCE(p.email, '')",
+		"created_at": "p.created_at",
+	},
+	defaultSort: "name",
+}
+
+// GetPeople lists people with their accounts and the catalog teams they are
+// members of; team restricts it to the members of one team. Team-restricted
+// callers only see the members of their own teams.
+func (h *Handlers) GetPeople(w http.ResponseWriter, r *http.Request) {
+	team := r.URL.Query().Get("team")
+
+	// Accounts are aggregated per person so that they are read with the
+	// person rather than with a query per row.
+	columns := `p.id, p.name, p.email, p.created_at,
+			COALESCE(ARRAY(
+				SELECT t.name FROM team_members m JOIN teams t ON t.id = m.team_id
+				WHERE m.email = p.email
+				AND ($2::TEXT[] IS NULL OR t.name = ANY($2))
+				ORDER BY t.name
+			), '{}'),
+			(SELECT json_agg(json_build_object(
+				'id', i.id,
+				'system', i.system,
+				'account_id', i.account_id,
+				'display_name', i.display_name,
+				'email', i.email,
+				'person_id', i.person_id,
+				'manual', i.manual,
+				'first_seen_at', i.first_seen_at,
+				'last_seen_at', i.last_seen_at
+			) ORDER BY i.system, i.account_id)
+			FROM identities i
+			WHERE i.person_id = p.id)`
+	from := `FROM people p
+		WHERE ($1 = '' OR EXISTS (
+			SELECT 1 FROM team_members m JOIN teams t ON t.id = m.team_id
+			WHERE m.email = p.email AND t.name = $1
+		))
+		AND ($2::TEXT[] IS NULL OR EXISTS (
+			SELECT 1 FROM team_members m JOIN teams t ON t.id = m.team_id
+			WHERE m.email = p.email AND t.name = ANY($2)
+		))`
+
+	rows := h.listRows(w, r, personSort, columns, from, []interface{}{team, pq.Array(visibleTeams(r))})
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(person *models.Person) error {
+		var identities []byte
+		if err := rows.Scan(&person.ID, &person.Name, &person.Email, &person.CreatedAt, pq.Array(&person.Teams),
+			&identities); err != nil {
+			return err
+		}
+
+		person.Identities = []models.Identity{}
+		if identities != nil {
+			if err := json.Unmarshal(identities, &person.Identities); err != nil {
+				return fmt.Errorf("failed to decode identities: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetIdentities lists accounts seen in every system; pass system to restrict
+// it to one and unlinked=true to list the accounts not linked to a person.
+// Accounts belong to no team, so the route is closed to team-restricted
+// callers.
+func (h *Handlers) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	system := r.URL.Query().Get("system")
+	unlinked := r.URL.Query().Get("unlinked") == "true"
+
+	columns := `id, system, account_id, display_name, email, person_id, manual, first_seen_at, last_seen_at`
+	from := `FROM identities
+		WHERE ($1 = '' OR system = $1)
+		AND (NOT $2 OR person_id IS NULL)`
+
+	rows := h.listRows(w, r, identitySort, columns, from, []interface{}{system, unlinked})
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(identity *models.Identity) error {
+		return scanIdentity(rows, identity)
+	})
+}
+
+var errPersonNotFound = errors.New("person not found")
+
+type linkIdentityRequest struct {
+	PersonID *int   `json:"person_id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+}
+
+// Identity links an account to a person by hand on PUT
+// /api/identities/{system}/{account_id}, given the person's id or email; a
+// person is created for an unknown email. DELETE removes the manual link
+// and falls back to matching by email.
+func (h *Handlers) Identity(w http.ResponseWriter, r *http.Request) {
+	system, accountID, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/identities/"), "/")
+	if system == "" || accountID == "" {
+		http.Error(w, "Expected /api/identities/{system}/{account_id}", http.StatusNotFound)
+		return
+	}
+
+	var result sql.Result
+	var err error
+	switch r.Method {
+	case http.MethodPut:
+		if !requireAdmin(w, r) {
+			return
+		}
+		var req linkIdentityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.PersonID == nil && strings.TrimSpace(req.Email) == "" {
+			http.Error(w, "person_id or email is required", http.StatusBadRequest)
+			return
+		}
+		var exists bool
+		if err := h.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM identities WHERE system = $1 AND account_id = $2)`,
+			system, accountID).Scan(&exists); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Identity not found", http.StatusNotFound)
+			return
+		}
+
+		var personID int
+		personID, err = h.person(req)
+		if errors.Is(err, errPersonNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		result, err = h.db.Exec(`
+			UPDATE identities SET person_id = $3, manual = TRUE
+			WHERE system = $1 AND account_id = $2`, system, accountID, personID)
+
+	case http.MethodDelete:
+		if !requireAdmin(w, r) {
+			return
+		}
+		result, err = h.db.Exec(`
+			UPDATE identities SET manual = FALSE,
+				person_id = (SELECT id FROM people WHERE email = NULLIF(identities.email, ''))
+			WHERE system = $1 AND account_id = $2`, system, accountID)
+
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+
+	var identity models.Identity
+	row := h.db.QueryRow(`
+		SELECT id, system, account_id, display_name, email, person_id, manual, first_seen_at, last_seen_at
+		FROM identities
+		WHERE system = $1 AND account_id = $2`, system, accountID)
+	if err := scanIdentity(row, &identity); err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, identity)
+}
+
+// person returns the id of the person a link request names, creating one
+// for an email not seen before.
+func (h *Handlers) person(req linkIdentityRequest) (int, error) {
+	if req.PersonID != nil {
+		var exists bool
+		if err := h.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM people WHERE id = $1)`, *req.PersonID).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("failed to look up person: %w", err)
+		}
+		if !exists {
+			return 0, fmt.Errorf("%w: %d", errPersonNotFound, *req.PersonID)
+		}
+		return *req.PersonID, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	var id int
+	err := h.db.QueryRow(`
+		INSERT INTO people (name, email)
+		VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE SET name = CASE WHEN $1 = '' THEN people.name ELSE $1 END
+		RETURNING id`, req.Name, email).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save person: %w", err)
+	}
+	return id, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIdentity(row rowScanner, identity *models.Identity) error {
+	return row.Scan(&identity.ID, &identity.System, &identity.AccountID, &identity.DisplayName, &identity.Email,
+		&identity.PersonID, &identity.Manual, &identity.FirstSeenAt, &identity.LastSeenAt)
+}
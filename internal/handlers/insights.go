@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-pulse/internal/anomaly"
+	"code-pulse/internal/auth"
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// anomalySeries is a daily series per key that anomalies are looked for in.
+// Queries take the start and end of the range, the time zone days are cut
+// in, and the repositories or Jira scopes the caller may see. Counting
+// series are dense: days without data count as zero from a key's first day.
+type anomalySeries struct {
+	name   string
+	scope  string
+	counts bool
+	query  string
+}
+
+var anomalySeriesList = []anomalySeries{
+	{
+		name:  "workflow_duration",
+		scope: auth.ScopeGithub,
+		query: `
+			SELECT repository, (created_at AT TIME ZONE $3)::DATE,
+				PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration)
+			FROM github_workflows
+			WHERE created_at >= $1 AND created_at < $2
+			AND ($4::TEXT[] IS NULL OR repository = ANY($4))
+			GROUP BY 1, 2
+			ORDER BY 1, 2`,
+	},
+	{
+		name:   "workflow_failures",
+		scope:  auth.ScopeGithub,
+		counts: true,
+		query: `
+			SELECT repository, (created_at AT TIME ZONE $3)::DATE,
+				COUNT(*) FILTER (WHERE status = 'failure')
+			FROM github_workflows
+			WHERE created_at >= $1 AND created_at < $2
+			AND ($4::TEXT[] IS NULL OR repository = ANY($4))
+			GROUP BY 1, 2
+			ORDER BY 1, 2`,
+	},
+	{
+		name:   "ticket_inflow",
+		scope:  auth.ScopeJira,
+		counts: true,
+		query: `
+			SELECT split_part(ticket_key, '-', 1), (created_at AT TIME ZONE $3)::DATE, COUNT(*)
+			FROM jira_tickets
+			WHERE created_at >= $1 AND created_at < $2
+			AND ticket_in_scope(ticket_key, components, $4)
+			GROUP BY 1, 2
+			ORDER BY 1, 2`,
+	},
+}
+
+// GetAnomalies flags the whole days in the window on which workflow durations,
+// workflow failures or ticket inflow strayed from their baseline, per
+// repository or project. Baselines are the same weekday of the previous
+// weeks, or the previous days with seasonality=none; threshold sets the
+// score beyond which a day is flagged. series picks some of them, by
+// default every one the caller's scopes allow.
+func (h *Handlers) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	seasonal := true
+	switch seasonality := r.URL.Query().Get("seasonality"); seasonality {
+	case "", "weekday":
+	case "none":
+		seasonal = false
+	default:
+		http.Error(w, fmt.Sprintf("invalid seasonality %q, expected weekday or none", seasonality), http.StatusBadRequest)
+		return
+	}
+
+	threshold := anomaly.DefaultThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 {
+			http.Error(w, fmt.Sprintf("invalid threshold %q: must be a positive number", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	principal := auth.FromContext(r.Context())
+	var series []anomalySeries
+	for _, s := range anomalySeriesList {
+		if principal == nil || principal.HasScope(s.scope) {
+			series = append(series, s)
+		}
+	}
+	if names := r.URL.Query().Get("series"); names != "" {
+		series = nil
+		for _, name := range strings.Split(names, ",") {
+			s, ok := findAnomalySeries(strings.TrimSpace(name))
+			if !ok {
+				http.Error(w, fmt.Sprintf("invalid series %q, expected one of %s", name, anomalySeriesNames()),
+					http.StatusBadRequest)
+				return
+			}
+			if principal != nil && !principal.HasScope(s.scope) {
+				http.Error(w, "Forbidden: requires scope "+s.scope, http.StatusForbidden)
+				return
+			}
+			series = append(series, s)
+		}
+	}
+
+	// Only whole days are scored, so that today's partial counts are not
+	// taken for a drop.
+	loc := window.Location
+	start := midnight(window.From, loc)
+	end := midnight(window.To, loc)
+	from := start.AddDate(0, 0, -anomaly.HistoryDays(seasonal))
+
+	anomalies := []models.Anomaly{}
+	for _, s := range series {
+		filter := pq.Array(scope.Repositories)
+		if s.scope == auth.ScopeJira {
+			filter = pq.Array(scope.JiraScopes)
+		}
+		points, err := h.dailySeries(s, from, end, loc, filter)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		for key, values := range points {
+			for _, score := range anomaly.Detect(values, seasonal) {
+				if score.Day.Before(start) || !score.Day.Before(end) || math.Abs(score.Score) < threshold {
+					continue
+				}
+				direction := "up"
+				if score.Score < 0 {
+					direction = "down"
+				}
+				anomalies = append(anomalies, models.Anomaly{
+					Series:    s.name,
+					Key:       key,
+					Day:       score.Day,
+					Value:     score.Value,
+					Expected:  score.Expected,
+					Deviation: score.Deviation,
+					Score:     score.Score,
+					Direction: direction,
+				})
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.Series != b.Series {
+			return a.Series < b.Series
+		}
+		return a.Key < b.Key
+	})
+
+	writeRecords(w, r, anomalies)
+}
+
+func findAnomalySeries(name string) (anomalySeries, bool) {
+	for _, s := range anomalySeriesList {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return anomalySeries{}, false
+}
+
+func anomalySeriesNames() string {
+	names := make([]string, len(anomalySeriesList))
+	for i, s := range anomalySeriesList {
+		names[i] = s.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// dailySeries runs the query of a series over [from, to), to being a
+// midnight, returning the points of each key in day order.
+func (h *Handlers) dailySeries(s anomalySeries, from, to time.Time, loc *time.Location,
+	filter interface{}) (map[string][]anomaly.Point, error) {
+	rows, err := h.db.Query(s.query, from, to, loc.String(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make(map[string][]anomaly.Point)
+	for rows.Next() {
+		var key string
+		var day time.Time
+		var value float64
+		if err := rows.Scan(&key, &day, &value); err != nil {
+			return nil, err
+		}
+		point := anomaly.Point{Day: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc), Value: value}
+
+		// Fill the days without rows of a counting series with zeros.
+		if previous := points[key]; s.counts && len(previous) > 0 {
+			for d := previous[len(previous)-1].Day.AddDate(0, 0, 1); d.Before(point.Day); d = d.AddDate(0, 0, 1) {
+				points[key] = append(points[key], anomaly.Point{Day: d})
+			}
+		}
+		points[key] = append(points[key], point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.counts {
+		for key, values := range points {
+			for d := values[len(values)-1].Day.AddDate(0, 0, 1); d.Before(to); d = d.AddDate(0, 0, 1) {
+				values = append(values, anomaly.Point{Day: d})
+			}
+			points[key] = values
+		}
+	}
+	return points, nil
+}
+
+func midnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
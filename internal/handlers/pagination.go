@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// listSort describes how a list endpoint can be ordered. Every column is a
+// non-NULL SQL expression so that cursors can compare against it, and id
+// breaks ties between rows with the same value.
+type listSort struct {
+	id          string
+	columns     map[string]string
+	defaultSort string
+}
+
+// pageCursor marks the last row of a page. It is handed to clients as an
+// opaque token and only valid for the sort it was issued for.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+// listPage is the window of a list endpoint selected by the limit, sort and
+// cursor query parameters. Sort keys are column names, prefixed with - for
+// descending order.
+type listPage struct {
+	limit  int
+	sort   string
+	expr   string
+	id     string
+	desc   bool
+	cursor *pageCursor
+}
+
+func parseListPage(r *http.Request, s listSort) (*listPage, error) {
+	limit, err := parsePositiveInt(r, "limit", defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if limit > maxPageSize {
+		return nil, fmt.Errorf("invalid limit: must be at most %d", maxPageSize)
+	}
+
+	order := r.URL.Query().Get("sort")
+	if order == "" {
+		order = s.defaultSort
+	}
+	expr, ok := s.columns[strings.TrimPrefix(order, "-")]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort %q, expected one of %s", order, strings.Join(sortKeys(s.columns), ", "))
+	}
+
+	page := &listPage{
+		limit: limit,
+		sort:  order,
+		expr:  expr,
+		id:    s.id,
+		desc:  strings.HasPrefix(order, "-"),
+	}
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		cursor, err := decodeCursor(token)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != order {
+			return nil, fmt.Errorf("cursor was issued for sort %q", cursor.Sort)
+		}
+		page.cursor = cursor
+	}
+
+	return page, nil
+}
+
+func sortKeys(columns map[string]string) []string {
+	keys := make([]string, 0, len(columns))
+	for key := range columns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// after returns the condition restricting from to rows past the cursor,
+// numbering its parameters after args.
+func (p *listPage) after(from string, args []interface{}) (string, []interface{}) {
+	if p.cursor == nil {
+		return from, args
+	}
+	comparator := ">"
+	if p.desc {
+		comparator = "<"
+	}
+	from += fmt.Sprintf("\n\t\tAND (%s, %s) %s ($%d, $%d)", p.expr, p.id, comparator, len(args)+1, len(args)+2)
+	return from, append(args[:len(args):len(args)], p.cursor.Value, p.cursor.ID)
+}
+
+func (p *listPage) orderBy() string {
+	direction := "ASC"
+	if p.desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("\n\t\tORDER BY %s %s, %s %s", p.expr, direction, p.id, direction)
+}
+
+// query selects one page of columns from the rows of from, a FROM clause
+// ending in its WHERE conditions.
+func (p *listPage) query(columns, from string, args []interface{}) (string, []interface{}) {
+	from, args = p.after(from, args)
+	query := "\n\t\tSELECT " + columns + "\n\t\t" + from + p.orderBy() + fmt.Sprintf("\n\t\tLIMIT $%d", len(args)+1)
+	return query, append(args, p.limit)
+}
+
+// writePageHeaders reports the number of rows matching from in X-Total-Count
+// and, when rows remain past this page, the cursor of the next one in
+// X-Next-Cursor and a Link header. Headers carry them so that every response
+// format keeps its shape.
+func (h *Handlers) writePageHeaders(w http.ResponseWriter, r *http.Request, p *listPage, from string, args []interface{}) error {
+	var total int
+	if err := h.db.QueryRow("SELECT COUNT(*) "+from, args...).Scan(&total); err != nil {
+		return fmt.Errorf("failed to count rows: %w", err)
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	pageFrom, pageArgs := p.after(from, args)
+	query := fmt.Sprintf("SELECT (%s)::TEXT, %s %s%s OFFSET $%d LIMIT 2",
+		p.expr, p.id, pageFrom, p.orderBy(), len(pageArgs)+1)
+	rows, err := h.db.Query(query, append(pageArgs, p.limit-1)...)
+	if err != nil {
+		return fmt.Errorf("failed to find next cursor: %w", err)
+	}
+	defer rows.Close()
+
+	var last pageCursor
+	var count int
+	for rows.Next() {
+		count++
+		if count > 1 {
+			break
+		}
+		if err := rows.Scan(&last.Value, &last.ID); err != nil {
+			return fmt.Errorf("failed to scan next cursor: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to find next cursor: %w", err)
+	}
+	if count < 2 {
+		return nil
+	}
+
+	last.Sort = p.sort
+	token := encodeCursor(last)
+	next := *r.URL
+	values := next.Query()
+	values.Set("cursor", token)
+	next.RawQuery = values.Encode()
+	w.Header().Set("X-Next-Cursor", token)
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	return nil
+}
+
+// listRows runs one page of a list query after writing its page headers.
+// Errors are written to the response, in which case rows is nil.
+func (h *Handlers) listRows(w http.ResponseWriter, r *http.Request, s listSort, columns, from string, args []interface{}) *sql.Rows {
+	page, err := parseListPage(r, s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if err := h.writePageHeaders(w, r, page, from, args); err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+
+	query, queryArgs := page.query(columns, from, args)
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+	return rows
+}
+
+// parsePositiveInt reads an integer query parameter that must be at least
+// one, returning defaultValue when it is absent.
+func parsePositiveInt(r *http.Request, name string, defaultValue int) (int, error) {
+	valueStr := r.URL.Query().Get(name)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, valueStr)
+	}
+	return value, nil
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code-pulse/internal/collector"
+	"code-pulse/internal/exposition"
+)
+
+var (
+	workflowDurationBuckets   = []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200}
+	collectionDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+)
+
+type workflowKey struct {
+	provider, repository, workflow string
+}
+
+// GetPrometheusMetrics serves stored data and collector self-metrics in the
+// Prometheus text format. Rates and histograms cover the last
+// METRICS_WINDOW_DAYS days.
+func (h *Handlers) GetPrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().AddDate(0, 0, -h.config.MetricsWindowDays)
+
+	var buf bytes.Buffer
+	out := exposition.NewWriter(&buf)
+
+	writers := []func(*exposition.Writer, time.Time) error{
+		h.writeWorkflowMetrics,
+		h.writeSonarqubeMetrics,
+		h.writeTicketMetrics,
+		h.writeCollectionMetrics,
+	}
+	for _, write := range writers {
+		if err := write(out, since); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.writeRateLimitMetrics(out)
+
+	if err := out.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Encoding error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (h *Handlers) writeWorkflowMetrics(out *exposition.Writer, since time.Time) error {
+	rows, err := h.db.Query(`
+		SELECT provider, repository, workflow_name,
+			COALESCE(COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION /
+				NULLIF(COUNT(*) FILTER (WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')), 0), 0)
+		FROM github_workflows
+		WHERE created_at >= $1
+		GROUP BY provider, repository, workflow_name
+		ORDER BY provider, repository, workflow_name`, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	out.Family("codepulse_workflow_success_ratio", "Share of completed workflow runs that succeeded.", exposition.Gauge)
+	for rows.Next() {
+		var key workflowKey
+		var ratio float64
+		if err := rows.Scan(&key.provider, &key.repository, &key.workflow, &ratio); err != nil {
+			return err
+		}
+		out.Sample("codepulse_workflow_success_ratio", ratio,
+			"provider", key.provider, "repository", key.repository, "workflow", key.workflow)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	latest, err := h.db.Query(`
+		SELECT DISTINCT ON (provider, repository, workflow_name)
+			provider, repository, workflow_name, duration, status = 'success'
+		FROM github_workflows
+		WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')
+		ORDER BY provider, repository, workflow_name, created_at DESC`)
+	if err != nil {
+		return err
+	}
+	defer latest.Close()
+
+	type latestRun struct {
+		key      workflowKey
+		duration float64
+		success  bool
+	}
+	var runs []latestRun
+	for latest.Next() {
+		var run latestRun
+		if err := latest.Scan(&run.key.provider, &run.key.repository, &run.key.workflow,
+			&run.duration, &run.success); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	if err := latest.Err(); err != nil {
+		return err
+	}
+
+	out.Family("codepulse_workflow_last_duration_seconds", "Duration of the latest completed workflow run.", exposition.Gauge)
+	for _, run := range runs {
+		out.Sample("codepulse_workflow_last_duration_seconds", run.duration,
+			"provider", run.key.provider, "repository", run.key.repository, "workflow", run.key.workflow)
+	}
+
+	out.Family("codepulse_workflow_last_success", "Whether the latest completed workflow run succeeded (1) or not (0).", exposition.Gauge)
+	for _, run := range runs {
+		value := 0.0
+		if run.success {
+			value = 1
+		}
+		out.Sample("codepulse_workflow_last_success", value,
+			"provider", run.key.provider, "repository", run.key.repository, "workflow", run.key.workflow)
+	}
+
+	durations, err := h.db.Query(`
+		SELECT provider, repository, workflow_name, duration
+		FROM github_workflows
+		WHERE created_at >= $1
+		AND status IN ('success', 'failure', 'cancelled', 'timed_out')
+		ORDER BY provider, repository, workflow_name`, since)
+	if err != nil {
+		return err
+	}
+	defer durations.Close()
+
+	var keys []workflowKey
+	histograms := make(map[workflowKey]*exposition.Buckets)
+	for durations.Next() {
+		var key workflowKey
+		var duration float64
+		if err := durations.Scan(&key.provider, &key.repository, &key.workflow, &duration); err != nil {
+			return err
+		}
+		if histograms[key] == nil {
+			histograms[key] = exposition.NewBuckets(workflowDurationBuckets...)
+			keys = append(keys, key)
+		}
+		histograms[key].Observe(duration)
+	}
+	if err := durations.Err(); err != nil {
+		return err
+	}
+
+	out.Family("codepulse_workflow_duration_seconds", "Durations of completed workflow runs.", exposition.Histogram)
+	for _, key := range keys {
+		out.Histogram("codepulse_workflow_duration_seconds", histograms[key],
+			"provider", key.provider, "repository", key.repository, "workflow", key.workflow)
+	}
+
+	return nil
+}
+
+// writeSonarqubeMetrics exposes the latest project-level coverage and ratings
+// of the main branch.
+func (h *Handlers) writeSonarqubeMetrics(out *exposition.Writer, since time.Time) error {
+	rows, err := h.db.Query(`
+		SELECT DISTINCT ON (project_key, metric_key) project_key, metric_key, numeric_value
+		FROM sonarqube_metrics
+		WHERE metric_key IN ('coverage', 'reliability_rating', 'security_rating', 'sqale_rating')
+		AND branch = '' AND pull_request = '' AND qualifier = 'TRK'
+		AND numeric_value IS NOT NULL
+		ORDER BY project_key, metric_key, collected_at DESC`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type measure struct {
+		project, metric string
+		value           float64
+	}
+	var coverage, ratings []measure
+	for rows.Next() {
+		var m measure
+		if err := rows.Scan(&m.project, &m.metric, &m.value); err != nil {
+			return err
+		}
+		if m.metric == "coverage" {
+			coverage = append(coverage, m)
+		} else {
+			ratings = append(ratings, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	out.Family("codepulse_sonarqube_coverage_percent", "Latest test coverage of the project.", exposition.Gauge)
+	for _, m := range coverage {
+		out.Sample("codepulse_sonarqube_coverage_percent", m.value, "project", m.project)
+	}
+
+	out.Family("codepulse_sonarqube_rating", "Latest SonarQube rating of the project, from 1 (A) to 5 (E).", exposition.Gauge)
+	for _, m := range ratings {
+		out.Sample("codepulse_sonarqube_rating", m.value, "project", m.project, "metric", m.metric)
+	}
+
+	return nil
+}
+
+func (h *Handlers) writeTicketMetrics(out *exposition.Writer, since time.Time) error {
+	rows, err := h.db.Query(`
+		SELECT source, status, COUNT(*)
+		FROM jira_tickets
+		WHERE resolved_at IS NULL
+		GROUP BY source, status
+		ORDER BY source, status`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	out.Family("codepulse_tickets_open", "Unresolved tickets per status.", exposition.Gauge)
+	for rows.Next() {
+		var source, status string
+		var count float64
+		if err := rows.Scan(&source, &status, &count); err != nil {
+			return err
+		}
+		out.Sample("codepulse_tickets_open", count, "source", source, "status", status)
+	}
+
+	return rows.Err()
+}
+
+func (h *Handlers) writeCollectionMetrics(out *exposition.Writer, since time.Time) error {
+	rows, err := h.db.Query(`
+		SELECT collector,
+			COUNT(*) FILTER (WHERE status = 'error'),
+			EXTRACT(EPOCH FROM MAX(started_at) FILTER (WHERE status = 'success'))
+		FROM collection_runs
+		GROUP BY collector
+		ORDER BY collector`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type collectorRuns struct {
+		name        string
+		errors      float64
+		lastSuccess *float64
+	}
+	var collectors []collectorRuns
+	for rows.Next() {
+		var c collectorRuns
+		if err := rows.Scan(&c.name, &c.errors, &c.lastSuccess); err != nil {
+			return err
+		}
+		collectors = append(collectors, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	out.Family("codepulse_collection_errors_total", "Collection runs that failed.", exposition.Counter)
+	for _, c := range collectors {
+		out.Sample("codepulse_collection_errors_total", c.errors, "collector", c.name)
+	}
+
+	out.Family("codepulse_collection_last_success_timestamp_seconds", "Start time of the latest successful collection run.", exposition.Gauge)
+	for _, c := range collectors {
+		if c.lastSuccess != nil {
+			out.Sample("codepulse_collection_last_success_timestamp_seconds", *c.lastSuccess, "collector", c.name)
+		}
+	}
+
+	durations, err := h.db.Query(`
+		SELECT collector, EXTRACT(EPOCH FROM finished_at - started_at)
+		FROM collection_runs
+		WHERE started_at >= $1
+		ORDER BY collector`, since)
+	if err != nil {
+		return err
+	}
+	defer durations.Close()
+
+	var names []string
+	histograms := make(map[string]*exposition.Buckets)
+	for durations.Next() {
+		var name string
+		var duration float64
+		if err := durations.Scan(&name, &duration); err != nil {
+			return err
+		}
+		if histograms[name] == nil {
+			histograms[name] = exposition.NewBuckets(collectionDurationBuckets...)
+			names = append(names, name)
+		}
+		histograms[name].Observe(duration)
+	}
+	if err := durations.Err(); err != nil {
+		return err
+	}
+
+	out.Family("codepulse_collection_duration_seconds", "Durations of collection runs.", exposition.Histogram)
+	for _, name := range names {
+		out.Histogram("codepulse_collection_duration_seconds", histograms[name], "collector", name)
+	}
+
+	return nil
+}
+
+// writeRateLimitMetrics reports the quota left as seen by the last API
+// response of each rate-limited collector since the server started.
+func (h *Handlers) writeRateLimitMetrics(out *exposition.Writer) {
+	out.Family("codepulse_api_rate_limit_remaining", "API requests left in the current rate-limit window.", exposition.Gauge)
+	for _, c := range h.registry.All() {
+		limited, ok := c.(collector.RateLimited)
+		if !ok {
+			continue
+		}
+		if remaining, known := limited.RateLimitRemaining(); known {
+			out.Sample("codepulse_api_rate_limit_remaining", float64(remaining), "source", c.Name())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code-pulse/internal/auth"
+	"code-pulse/internal/catalog"
+	"code-pulse/internal/models"
+	"code-pulse/internal/scorecard"
+)
+
+const maxScorecardHistory = 12
+
+type scorecardSubject struct {
+	kind      string
+	name      string
+	resources func(string) (*catalog.Resources, error)
+}
+
+// GetScorecards grades every catalog team and service, or the one team or
+// service asks for, over the window and the history windows of the same
+// length before it. Restricted callers only see their teams and the services
+// those own.
+func (h *Handlers) GetScorecards(w http.ResponseWriter, r *http.Request) {
+	metrics, err := scorecard.Metrics(h.config.ScorecardMetrics)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid scorecard configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := parsePositiveInt(r, "history", 3)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if history > maxScorecardHistory {
+		http.Error(w, fmt.Sprintf("invalid history: must be at most %d", maxScorecardHistory), http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.access(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	subjects, err := h.scorecardSubjects(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	length := window.To.Sub(window.From)
+	scorecards := []models.Scorecard{}
+	for _, subject := range subjects {
+		resources, err := subject.resources(subject.name)
+		if errors.Is(err, catalog.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("Unknown %s %q", subject.kind, subject.name), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		allowed := a.narrow(resources)
+		filter := scorecard.Filter{
+			Repositories:      allowed.Repositories,
+			SonarqubeProjects: allowed.SonarqubeProjects,
+			JiraScopes:        allowed.JiraScopes,
+			IncidentServices:  allowed.IncidentServices,
+			Environment:       h.config.DoraEnvironment,
+			IncidentLabel:     h.config.DoraIncidentLabel,
+			From:              window.From,
+			To:                window.To,
+		}
+		period, results, err := scorecard.Compute(h.db, metrics, filter)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		card := models.Scorecard{
+			Kind:    subject.kind,
+			Name:    subject.name,
+			From:    window.From,
+			To:      window.To,
+			Score:   period.Score,
+			Grade:   period.Grade,
+			Metrics: results,
+			History: []models.ScorecardPeriod{},
+		}
+		for i := 1; i <= history; i++ {
+			filter.From = window.From.Add(-length * time.Duration(i))
+			filter.To = window.To.Add(-length * time.Duration(i))
+			past, _, err := scorecard.Compute(h.db, metrics, filter)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+				return
+			}
+			card.History = append(card.History, *past)
+		}
+		card.Trend = scorecard.Trend(card.Score, card.History[0].Score)
+
+		scorecards = append(scorecards, card)
+	}
+
+	writeJSON(w, http.StatusOK, scorecards)
+}
+
+// scorecardSubjects lists the teams and services to grade: the ones the team
+// and service parameters name, or the whole catalog.
+func (h *Handlers) scorecardSubjects(r *http.Request) ([]scorecardSubject, error) {
+	team := r.URL.Query().Get("team")
+	service := r.URL.Query().Get("service")
+	principal := auth.FromContext(r.Context())
+	visible := func(team string) bool {
+		return principal == nil || !principal.Restricted || principal.InTeam(team)
+	}
+
+	var subjects []scorecardSubject
+	if team != "" || service != "" {
+		if team != "" && visible(team) {
+			subjects = append(subjects, scorecardSubject{"team", team, h.catalog.TeamResources})
+		}
+		if service != "" {
+			services, err := h.catalog.Services()
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range services {
+				if s.Name == service && !visible(s.Team) {
+					return subjects, nil
+				}
+			}
+			subjects = append(subjects, scorecardSubject{"service", service, h.catalog.ServiceResources})
+		}
+		return subjects, nil
+	}
+
+	teams, err := h.catalog.Teams()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		if visible(t.Name) {
+			subjects = append(subjects, scorecardSubject{"team", t.Name, h.catalog.TeamResources})
+		}
+	}
+	services, err := h.catalog.Services()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if visible(s.Team) {
+			subjects = append(subjects, scorecardSubject{"service", s.Name, h.catalog.ServiceResources})
+		}
+	}
+	return subjects, nil
+}
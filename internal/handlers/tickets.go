@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetFlowMetrics reports throughput, work in progress, lead and cycle time
+// and time spent per status over tickets of every tracker. Cycle time runs
+// from the first move into an in-progress status to resolution.
+func (h *Handlers) GetFlowMetrics(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flow := models.FlowMetrics{Source: source, From: window.From, To: window.To}
+	scopes := scope.JiraScopes
+
+	var leadTime sql.NullFloat64
+	err = h.db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE resolved_at >= $2 AND resolved_at < $3 AND status_category = 'done'),
+			COUNT(*) FILTER (WHERE status_category = 'in_progress'),
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM resolved_at - created_at))
+				FILTER (WHERE resolved_at >= $2 AND resolved_at < $3) / 3600
+		FROM jira_tickets
+		WHERE ($1 = '' OR source = $1)
+		AND ticket_in_scope(ticket_key, components, $4)`,
+		source, window.From, window.To, pq.Array(scopes)).Scan(&flow.Throughput, &flow.WorkInProgress, &leadTime)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	flow.LeadTimeHours = nullableFloat(leadTime)
+
+	var cycleTime, cycleTimeP85 sql.NullFloat64
+	err = h.db.QueryRow(`
+		SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY hours),
+			PERCENTILE_CONT(0.85) WITHIN GROUP (ORDER BY hours)
+		FROM (
+			SELECT EXTRACT(EPOCH FROM t.resolved_at - MIN(tr.transitioned_at)) / 3600 AS hours
+			FROM jira_tickets t
+			JOIN ticket_transitions tr ON tr.source = t.source AND tr.ticket_key = t.ticket_key
+			WHERE tr.to_category = 'in_progress'
+			AND ($1 = '' OR t.source = $1)
+			AND t.resolved_at >= $2
+			AND t.resolved_at < $3
+			AND ticket_in_scope(t.ticket_key, t.components, $4)
+			GROUP BY t.id, t.resolved_at
+		) cycle_times`, source, window.From, window.To, pq.Array(scopes)).Scan(&cycleTime, &cycleTimeP85)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	flow.CycleTimeHours = nullableFloat(cycleTime)
+	flow.CycleTimeP85 = nullableFloat(cycleTimeP85)
+
+	// Each transition lasts until the next one of the same ticket; the
+	// current status of a ticket has no end yet and is left out.
+	rows, err := h.db.Query(`
+		SELECT status, COUNT(*), PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY hours), AVG(hours)
+		FROM (
+			SELECT tr.to_status AS status,
+				EXTRACT(EPOCH FROM LEAD(tr.transitioned_at) OVER (
+					PARTITION BY tr.source, tr.ticket_key ORDER BY tr.transitioned_at
+				) - tr.transitioned_at) / 3600 AS hours
+			FROM ticket_transitions tr
+			JOIN jira_tickets t ON t.source = tr.source AND t.ticket_key = tr.ticket_key
+			WHERE ($1 = '' OR t.source = $1)
+			AND t.resolved_at >= $2
+			AND t.resolved_at < $3
+			AND ticket_in_scope(t.ticket_key, t.components, $4)
+		) durations
+		WHERE hours IS NOT NULL
+		GROUP BY status
+		ORDER BY status`, source, window.From, window.To, pq.Array(scopes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	flow.TimeInStatus = []models.StatusTime{}
+	for rows.Next() {
+		var statusTime models.StatusTime
+		if err := rows.Scan(&statusTime.Status, &statusTime.Transitions, &statusTime.MedianHours,
+			&statusTime.AverageHours); err != nil {
+			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		flow.TimeInStatus = append(flow.TimeInStatus, statusTime)
+	}
+
+	writeRecord(w, r, flow)
+}
+
+// GetVelocity reports committed and completed estimates of the most recent
+// started sprints (Jira sprints and Linear cycles). A ticket counts towards the
+// sprint it is currently in and is completed when it was resolved before the
+// sprint ended.
+func (h *Handlers) GetVelocity(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := parsePositiveInt(r, "limit", 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT s.source, s.external_id, s.name, s.state, s.starts_at, s.ends_at, s.completed_at,
+			COUNT(t.id),
+			COUNT(t.id) FILTER (WHERE t.status_category = 'done' AND t.resolved_at <= COALESCE(s.completed_at, s.ends_at)),
+			COALESCE(SUM(t.estimate), 0),
+			COALESCE(SUM(t.estimate) FILTER (WHERE t.status_category = 'done' AND t.resolved_at <= COALESCE(s.completed_at, s.ends_at)), 0)
+		FROM sprints s
+		LEFT JOIN jira_tickets t ON t.source = s.source AND t.sprint_id = s.external_id
+			AND ticket_in_scope(t.ticket_key, t.components, $3)
+		WHERE ($1 = '' OR s.source = $1)
+		AND s.state <> 'future'
+		GROUP BY s.id
+		HAVING $3::TEXT[] IS NULL OR COUNT(t.id) > 0
+		ORDER BY s.starts_at DESC NULLS LAST
+		LIMIT $2`
+
+	rows, err := h.db.Query(query, source, limit, pq.Array(scope.JiraScopes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(sprint *models.SprintVelocity) error {
+		return rows.Scan(&sprint.Source, &sprint.ExternalID, &sprint.Name, &sprint.State, &sprint.StartsAt,
+			&sprint.EndsAt, &sprint.CompletedAt, &sprint.Tickets, &sprint.CompletedTickets,
+			&sprint.CommittedEstimate, &sprint.CompletedEstimate)
+	})
+}
+
+func nullableFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// timeRange is the window a request covers. from and to take RFC3339
+// timestamps or dates, which start at midnight in tz (default UTC); without
+// from the window is the days before to, which defaults to now.
+type timeRange struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+func parseTimeRange(r *http.Request, defaultDays int) (timeRange, error) {
+	loc := time.UTC
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		var err error
+		// Local names the server's zone, which the database does not know.
+		if loc, err = time.LoadLocation(tz); err != nil || tz == "Local" {
+			return timeRange{}, fmt.Errorf("invalid tz %q, expected an IANA time zone", tz)
+		}
+	}
+
+	window := timeRange{To: time.Now(), Location: loc}
+	if to := r.URL.Query().Get("to"); to != "" {
+		t, err := parseTimeParam(to, loc)
+		if err != nil {
+			return timeRange{}, fmt.Errorf("invalid to %q: %w", to, err)
+		}
+		window.To = t
+	}
+
+	if from := r.URL.Query().Get("from"); from != "" {
+		t, err := parseTimeParam(from, loc)
+		if err != nil {
+			return timeRange{}, fmt.Errorf("invalid from %q: %w", from, err)
+		}
+		window.From = t
+	} else {
+		days, err := parsePositiveInt(r, "days", defaultDays)
+		if err != nil {
+			return timeRange{}, err
+		}
+		window.From = window.To.AddDate(0, 0, -days)
+	}
+
+	if !window.From.Before(window.To) {
+		return timeRange{}, fmt.Errorf("invalid range: from must be before to")
+	}
+	return window, nil
+}
+
+func parseTimeParam(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or YYYY-MM-DD")
+	}
+	return t, nil
+}
+
+// parseBucket reads the bucket parameter sizing aggregated series.
+func parseBucket(r *http.Request) (string, error) {
+	switch bucket := r.URL.Query().Get("bucket"); bucket {
+	case "":
+		return "day", nil
+	case "day", "week", "month":
+		return bucket, nil
+	default:
+		return "", fmt.Errorf("invalid bucket %q, expected day, week or month", bucket)
+	}
+}
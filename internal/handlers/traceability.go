@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Tickets not deployed yet sort after every deployed one.
+var ticketLeadTimeSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"created_at":      "created_at",
+		"ticket_key":      "ticket_key",
+		"deployed_at":     "COALESCE(deployed_at, 'infinity')",
+		"lead_time_hours": "COALESCE(lead_time_hours, 'Infinity')",
+	},
+	defaultSort: "-created_at",
+}
+
+// GetTicketLeadTimes traces tickets created in the window to production:
+// the first commit and pull request referencing them, the last merge, and
+// when every merged pull request reached the DORA environment. Lead time
+// runs from ticket creation to that deployment. Pass deployed=true to list
+// only deployed tickets.
+func (h *Handlers) GetTicketLeadTimes(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	deployed := r.URL.Query().Get("deployed") == "true"
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `source, ticket_key, summary, status, created_at, first_commit_at, first_pull_request_at,
+			merged_at, deployed_at, lead_time_hours, commits, pull_requests`
+	from := `FROM (
+			SELECT t.id, t.source, t.ticket_key, t.summary, t.status, t.created_at,
+				c.first_commit_at, c.commits, p.first_pull_request_at, p.merged_at, p.deployed_at, p.pull_requests,
+				EXTRACT(EPOCH FROM p.deployed_at - t.created_at) / 3600 AS lead_time_hours
+			FROM jira_tickets t
+			CROSS JOIN LATERAL (
+				SELECT MIN(gc.committed_at) AS first_commit_at, COUNT(*) AS commits
+				FROM ticket_links l
+				JOIN github_commits gc ON gc.repository = l.repository AND gc.sha = l.ref
+				WHERE l.ticket_key = t.ticket_key
+				AND l.kind = 'commit'
+				AND ($5::TEXT[] IS NULL OR l.repository = ANY($5))
+			) c
+			CROSS JOIN LATERAL (
+				SELECT MIN(pr.created_at) AS first_pull_request_at,
+					MAX(pr.merged_at) AS merged_at,
+					CASE WHEN bool_and(pd.deployed_at IS NOT NULL) FILTER (WHERE pr.merged_at IS NOT NULL)
+						THEN MAX(pd.deployed_at) END AS deployed_at,
+					COUNT(*) AS pull_requests
+				FROM ticket_links l
+				JOIN github_pull_requests pr ON pr.provider = l.provider
+					AND pr.repository = l.repository
+					AND pr.number::TEXT = l.ref
+				LEFT JOIN pull_request_deployments pd ON pd.provider = pr.provider
+					AND pd.repository = pr.repository
+					AND pd.number = pr.number
+					AND pd.environment = $6
+				WHERE l.ticket_key = t.ticket_key
+				AND l.kind = 'pull_request'
+				AND ($5::TEXT[] IS NULL OR l.repository = ANY($5))
+			) p
+			WHERE ($1 = '' OR t.source = $1)
+			AND t.created_at >= $2
+			AND t.created_at < $3
+			AND ticket_in_scope(t.ticket_key, t.components, $4)
+		) lead_times
+		WHERE (NOT $7 OR deployed_at IS NOT NULL)`
+
+	args := []interface{}{source, window.From, window.To, pq.Array(scope.JiraScopes), pq.Array(scope.Repositories),
+		h.config.DoraEnvironment, deployed}
+	rows := h.listRows(w, r, ticketLeadTimeSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(ticket *models.TicketLeadTime) error {
+		return rows.Scan(&ticket.Source, &ticket.TicketKey, &ticket.Summary, &ticket.Status, &ticket.CreatedAt,
+			&ticket.FirstCommitAt, &ticket.FirstPullRequestAt, &ticket.MergedAt, &ticket.DeployedAt,
+			&ticket.LeadTimeHours, &ticket.Commits, &ticket.PullRequests)
+	})
+}
+
+var ticketLinkSort = listSort{
+	id: "l.id",
+	columns: map[string]string{
+		"ticket_key": "l.ticket_key",
+		"repository": "l.repository",
+	},
+	defaultSort: "ticket_key",
+}
+
+// GetTicketLinks lists the pull requests and commits referencing tickets,
+// with when each pull request was merged and deployed to the DORA
+// environment; ticket_key restricts it to one ticket. Keys of projects no
+// stored ticket belongs to, such as RELEASE-2 from a release-2 branch, are
+// left out.
+func (h *Handlers) GetTicketLinks(w http.ResponseWriter, r *http.Request) {
+	ticketKey := r.URL.Query().Get("ticket_key")
+	repository := r.URL.Query().Get("repository")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	columns := `l.ticket_key, l.kind, l.provider, l.repository, l.ref,
+			COALESCE(pr.title, split_part(gc.message, E'\n', 1), ''),
+			COALESCE(pr.created_at, gc.committed_at), pr.merged_at, pd.deployed_at`
+	from := `FROM ticket_links l
+		LEFT JOIN github_pull_requests pr ON l.kind = 'pull_request'
+			AND pr.provider = l.provider
+			AND pr.repository = l.repository
+			AND pr.number::TEXT = l.ref
+		LEFT JOIN github_commits gc ON l.kind = 'commit'
+			AND gc.repository = l.repository
+			AND gc.sha = l.ref
+		LEFT JOIN pull_request_deployments pd ON pd.provider = pr.provider
+			AND pd.repository = pr.repository
+			AND pd.number = pr.number
+			AND pd.environment = $3
+		WHERE ($1 = '' OR l.ticket_key = upper($1))
+		AND ($2 = '' OR l.repository = $2)
+		AND ($4::TEXT[] IS NULL OR l.repository = ANY($4))
+		AND ($5::TEXT[] IS NULL OR EXISTS (
+			SELECT 1 FROM jira_tickets t
+			WHERE t.ticket_key = l.ticket_key
+			AND ticket_in_scope(t.ticket_key, t.components, $5)
+		))
+		AND EXISTS (
+			SELECT 1 FROM jira_tickets t
+			WHERE split_part(t.ticket_key, '-', 1) = split_part(l.ticket_key, '-', 1)
+		)`
+
+	args := []interface{}{ticketKey, repository, h.config.DoraEnvironment, pq.Array(scope.Repositories),
+		pq.Array(scope.JiraScopes)}
+	rows := h.listRows(w, r, ticketLinkSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(link *models.TicketLink) error {
+		return rows.Scan(&link.TicketKey, &link.Kind, &link.Provider, &link.Repository, &link.Ref, &link.Title,
+			&link.CreatedAt, &link.MergedAt, &link.DeployedAt)
+	})
+}
+
+var unlinkedPullRequestSort = listSort{
+	id: "id",
+	columns: map[string]string{
+		"merged_at":  "merged_at",
+		"repository": "repository",
+		"author":     "author",
+	},
+	defaultSort: "-merged_at",
+}
+
+// GetUnlinkedPullRequests lists pull requests merged in the window whose
+// title and head branch reference no ticket. Keys of projects no stored
+// ticket belongs to, such as UTF-8, do not count as references.
+func (h *Handlers) GetUnlinkedPullRequests(w http.ResponseWriter, r *http.Request) {
+	repository := r.URL.Query().Get("repository")
+	provider := r.URL.Query().Get("provider")
+
+	scope, ok := h.scope(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseTimeRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := `provider, repository, number, title, author, state, head_branch, base_branch,
+			created_at, updated_at, closed_at, merged_at`
+	from := `FROM github_pull_requests pr
+		WHERE ($1 = '' OR repository = $1)
+		AND ($2 = '' OR provider = $2)
+		AND merged_at >= $3
+		AND merged_at < $4
+		AND ($5::TEXT[] IS NULL OR repository = ANY($5))
+		AND NOT EXISTS (
+			SELECT 1 FROM ticket_links l
+			WHERE l.kind = 'pull_request'
+			AND l.provider = pr.provider
+			AND l.repository = pr.repository
+			AND l.ref = pr.number::TEXT
+			AND EXISTS (
+				SELECT 1 FROM jira_tickets t
+				WHERE split_part(t.ticket_key, '-', 1) = split_part(l.ticket_key, '-', 1)
+			)
+		)`
+
+	args := []interface{}{repository, provider, window.From, window.To, pq.Array(scope.Repositories)}
+	rows := h.listRows(w, r, unlinkedPullRequestSort, columns, from, args)
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	streamRows(w, r, rows, func(pr *models.GithubPullRequest) error {
+		return rows.Scan(&pr.Provider, &pr.Repository, &pr.Number, &pr.Title, &pr.Author, &pr.State,
+			&pr.HeadBranch, &pr.BaseBranch, &pr.CreatedAt, &pr.UpdatedAt, &pr.ClosedAt, &pr.MergedAt)
+	})
+}
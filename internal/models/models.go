@@ -5,32 +5,528 @@ import (
 )
 
 type GithubWorkflow struct {
-	ID           int       `json:"id" db:"id"`
-	Repository   string    `json:"repository" db:"repository"`
-	WorkflowName string    `json:"workflow_name" db:"workflow_name"`
-	Status       string    `json:"status" db:"status"`
-	Duration     int       `json:"duration" db:"duration"` // Duration in seconds
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	CompletedAt  time.Time `json:"completed_at" db:"completed_at"`
+	ID            int       `json:"id" db:"id"`
+	Provider      string    `json:"provider" db:"provider"`
+	ExternalID    string    `json:"external_id" db:"external_id"`
+	Repository    string    `json:"repository" db:"repository"`
+	WorkflowName  string    `json:"workflow_name" db:"workflow_name"`
+	Status        string    `json:"status" db:"status"`
+	Duration      int       `json:"duration" db:"duration"` // Duration in seconds
+	HeadBranch    string    `json:"head_branch" db:"head_branch"`
+	HeadSHA       string    `json:"head_sha" db:"head_sha"`
+	QueueDuration int       `json:"queue_duration" db:"queue_duration"` // Queue time in seconds
+	Cause         string    `json:"cause" db:"cause"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	CompletedAt   time.Time `json:"completed_at" db:"completed_at"`
 }
 
 type SonarqubeMetric struct {
-	ID          int       `json:"id" db:"id"`
-	ProjectKey  string    `json:"project_key" db:"project_key"`
-	MetricKey   string    `json:"metric_key" db:"metric_key"`
-	Value       string    `json:"value" db:"value"`
-	Component   string    `json:"component" db:"component"`
-	CollectedAt time.Time `json:"collected_at" db:"collected_at"`
+	ID           int       `json:"id" db:"id"`
+	ProjectKey   string    `json:"project_key" db:"project_key"`
+	Branch       string    `json:"branch" db:"branch"`
+	PullRequest  string    `json:"pull_request" db:"pull_request"`
+	MetricKey    string    `json:"metric_key" db:"metric_key"`
+	Value        string    `json:"value" db:"value"`
+	NumericValue *float64  `json:"numeric_value" db:"numeric_value"`
+	Component    string    `json:"component" db:"component"`
+	Qualifier    string    `json:"qualifier" db:"qualifier"`
+	Path         string    `json:"path" db:"path"`
+	CollectedAt  time.Time `json:"collected_at" db:"collected_at"`
 }
 
+// JiraTicket is a ticket of any issue tracker; Source tells them apart.
 type JiraTicket struct {
+	ID             int                `json:"id" db:"id"`
+	Source         string             `json:"source" db:"source"`
+	TicketKey      string             `json:"ticket_key" db:"ticket_key"`
+	Summary        string             `json:"summary" db:"summary"`
+	Status         string             `json:"status" db:"status"`
+	StatusCategory string             `json:"status_category" db:"status_category"` // todo, in_progress, done or canceled
+	Priority       string             `json:"priority" db:"priority"`
+	Assignee       string             `json:"assignee" db:"assignee"` // account id
+	AssigneeName   string             `json:"assignee_name" db:"assignee_name"`
+	Estimate       *float64           `json:"estimate" db:"estimate"`
+	SprintID       string             `json:"sprint_id,omitempty" db:"sprint_id"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
+	ResolvedAt     *time.Time         `json:"resolved_at" db:"resolved_at"`
+	Labels         []string           `json:"labels" db:"labels"`
+	Components     []string           `json:"components" db:"components"`
+	Transitions    []TicketTransition `json:"transitions,omitempty"`
+}
+
+type TicketTransition struct {
+	ID             int       `json:"id" db:"id"`
+	Source         string    `json:"source" db:"source"`
+	TicketKey      string    `json:"ticket_key" db:"ticket_key"`
+	FromStatus     string    `json:"from_status" db:"from_status"`
+	ToStatus       string    `json:"to_status" db:"to_status"`
+	ToCategory     string    `json:"to_category" db:"to_category"`
+	Author         string    `json:"author" db:"author"` // account id, see identities
+	TransitionedAt time.Time `json:"transitioned_at" db:"transitioned_at"`
+}
+
+// Sprint is a Jira sprint or a Linear cycle.
+type Sprint struct {
+	ID          int        `json:"id" db:"id"`
+	Source      string     `json:"source" db:"source"`
+	ExternalID  string     `json:"external_id" db:"external_id"`
+	Name        string     `json:"name" db:"name"`
+	State       string     `json:"state" db:"state"`
+	StartsAt    *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at" db:"ends_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+type SonarqubeQualityGate struct {
+	ID          int                             `json:"id" db:"id"`
+	ProjectKey  string                          `json:"project_key" db:"project_key"`
+	Branch      string                          `json:"branch" db:"branch"`
+	PullRequest string                          `json:"pull_request" db:"pull_request"`
+	Status      string                          `json:"status" db:"status"`
+	CollectedAt time.Time                       `json:"collected_at" db:"collected_at"`
+	Conditions  []SonarqubeQualityGateCondition `json:"conditions"`
+}
+
+type SonarqubeQualityGateCondition struct {
+	MetricKey      string `json:"metric_key" db:"metric_key"`
+	Comparator     string `json:"comparator" db:"comparator"`
+	ErrorThreshold string `json:"error_threshold" db:"error_threshold"`
+	ActualValue    string `json:"actual_value" db:"actual_value"`
+	Status         string `json:"status" db:"status"`
+}
+
+type QualityGatePassRate struct {
+	Date     time.Time `json:"date"`
+	Total    int       `json:"total"`
+	Passed   int       `json:"passed"`
+	PassRate float64   `json:"pass_rate"`
+}
+
+type SonarqubeBranch struct {
+	ProjectKey        string     `json:"project_key" db:"project_key"`
+	Name              string     `json:"name" db:"name"`
+	IsMain            bool       `json:"is_main" db:"is_main"`
+	Type              string     `json:"type" db:"type"`
+	QualityGateStatus string     `json:"quality_gate_status" db:"quality_gate_status"`
+	AnalysisDate      *time.Time `json:"analysis_date" db:"analysis_date"`
+}
+
+type SonarqubePullRequest struct {
+	ProjectKey        string     `json:"project_key" db:"project_key"`
+	Repository        string     `json:"repository" db:"repository"`
+	PullRequest       string     `json:"pull_request" db:"pull_request"`
+	Title             string     `json:"title" db:"title"`
+	Branch            string     `json:"branch" db:"branch"`
+	BaseBranch        string     `json:"base_branch" db:"base_branch"`
+	QualityGateStatus string     `json:"quality_gate_status" db:"quality_gate_status"`
+	AnalysisDate      *time.Time `json:"analysis_date" db:"analysis_date"`
+}
+
+type GithubPullRequest struct {
 	ID         int        `json:"id" db:"id"`
-	TicketKey  string     `json:"ticket_key" db:"ticket_key"`
-	Summary    string     `json:"summary" db:"summary"`
+	Provider   string     `json:"provider" db:"provider"`
+	Repository string     `json:"repository" db:"repository"`
+	Number     int        `json:"number" db:"number"`
+	Title      string     `json:"title" db:"title"`
+	Author     string     `json:"author" db:"author"`
+	State      string     `json:"state" db:"state"`
+	HeadBranch string     `json:"head_branch" db:"head_branch"`
+	BaseBranch string     `json:"base_branch" db:"base_branch"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	ClosedAt   *time.Time `json:"closed_at" db:"closed_at"`
+	MergedAt   *time.Time `json:"merged_at" db:"merged_at"`
+}
+
+type PullRequestActivity struct {
+	ID         int       `json:"id" db:"id"`
+	Provider   string    `json:"provider" db:"provider"`
+	Repository string    `json:"repository" db:"repository"`
+	Number     int       `json:"number" db:"number"`
+	Action     string    `json:"action" db:"action"`
+	Author     string    `json:"author" db:"author"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type PullRequestQuality struct {
+	SonarqubePullRequest
+	NewCoverage *float64           `json:"new_coverage"`
+	Github      *GithubPullRequest `json:"github"`
+}
+
+type SonarqubeIssue struct {
+	IssueKey   string     `json:"issue_key" db:"issue_key"`
+	ProjectKey string     `json:"project_key" db:"project_key"`
+	Rule       string     `json:"rule" db:"rule"`
+	Severity   string     `json:"severity" db:"severity"`
+	Type       string     `json:"type" db:"type"`
+	File       string     `json:"file" db:"file"`
+	Line       *int       `json:"line" db:"line"`
 	Status     string     `json:"status" db:"status"`
-	Priority   string     `json:"priority" db:"priority"`
-	Assignee   string     `json:"assignee" db:"assignee"`
+	Resolution string     `json:"resolution" db:"resolution"`
+	Author     string     `json:"author" db:"author"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
-	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+	ClosedAt   *time.Time `json:"closed_at" db:"closed_at"`
+}
+
+type SonarqubeHotspot struct {
+	HotspotKey               string     `json:"hotspot_key" db:"hotspot_key"`
+	ProjectKey               string     `json:"project_key" db:"project_key"`
+	Rule                     string     `json:"rule" db:"rule"`
+	SecurityCategory         string     `json:"security_category" db:"security_category"`
+	VulnerabilityProbability string     `json:"vulnerability_probability" db:"vulnerability_probability"`
+	File                     string     `json:"file" db:"file"`
+	Line                     *int       `json:"line" db:"line"`
+	Status                   string     `json:"status" db:"status"`
+	Resolution               string     `json:"resolution" db:"resolution"`
+	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at" db:"updated_at"`
+	ClosedAt                 *time.Time `json:"closed_at" db:"closed_at"`
+}
+
+type IssueFlow struct {
+	Date   time.Time `json:"date"`
+	Opened int       `json:"opened"`
+	Closed int       `json:"closed"`
+}
+
+type TimeToFix struct {
+	Severity    string  `json:"severity"`
+	Fixed       int     `json:"fixed"`
+	MeanHours   float64 `json:"mean_hours"`
+	MedianHours float64 `json:"median_hours"`
+}
+
+type GithubCommit struct {
+	Repository  string             `json:"repository" db:"repository"`
+	SHA         string             `json:"sha" db:"sha"`
+	Author      string             `json:"author" db:"author"`
+	AuthorEmail string             `json:"author_email" db:"author_email"`
+	Message     string             `json:"message" db:"message"`
+	CommittedAt time.Time          `json:"committed_at" db:"committed_at"`
+	Files       []GithubCommitFile `json:"files"`
+}
+
+type GithubCommitFile struct {
+	Path      string `json:"path" db:"path"`
+	Additions int    `json:"additions" db:"additions"`
+	Deletions int    `json:"deletions" db:"deletions"`
+}
+
+type ComponentMeasure struct {
+	Component   string    `json:"component"`
+	Path        string    `json:"path"`
+	Qualifier   string    `json:"qualifier"`
+	MetricKey   string    `json:"metric_key"`
+	Value       float64   `json:"value"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+type ChurnComplexity struct {
+	Path         string   `json:"path"`
+	Complexity   float64  `json:"complexity"`
+	Coverage     *float64 `json:"coverage"`
+	Commits      int      `json:"commits"`
+	LinesChanged int      `json:"lines_changed"`
+}
+
+type CollectionRun struct {
+	ID         int       `json:"id" db:"id"`
+	Collector  string    `json:"collector" db:"collector"`
+	StartedAt  time.Time `json:"started_at" db:"started_at"`
+	FinishedAt time.Time `json:"finished_at" db:"finished_at"`
+	Status     string    `json:"status" db:"status"`
+	Error      string    `json:"error" db:"error"`
+}
+
+type CollectorStatus struct {
+	Name            string         `json:"name"`
+	Enabled         bool           `json:"enabled"`
+	ValidationError string         `json:"validation_error,omitempty"`
+	Schedule        string         `json:"schedule"`
+	Healthy         *bool          `json:"healthy,omitempty"`
+	HealthError     string         `json:"health_error,omitempty"`
+	LastRun         *CollectionRun `json:"last_run"`
+}
+
+type Repository struct {
+	Provider      string    `json:"provider" db:"provider"`
+	Repository    string    `json:"repository" db:"repository"`
+	DefaultBranch string    `json:"default_branch" db:"default_branch"`
+	WebURL        string    `json:"web_url" db:"web_url"`
+	Archived      bool      `json:"archived" db:"archived"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CIJob struct {
+	Provider       string     `json:"provider" db:"provider"`
+	Repository     string     `json:"repository" db:"repository"`
+	PipelineID     string     `json:"pipeline_id" db:"pipeline_id"`
+	ExternalID     string     `json:"external_id" db:"external_id"`
+	Name           string     `json:"name" db:"name"`
+	Stage          string     `json:"stage" db:"stage"`
+	Status         string     `json:"status" db:"status"`
+	Duration       int        `json:"duration" db:"duration"`
+	QueuedDuration int        `json:"queued_duration" db:"queued_duration"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	StartedAt      *time.Time `json:"started_at" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at" db:"finished_at"`
+}
+
+type Incident struct {
+	ID             int        `json:"id" db:"id"`
+	Source         string     `json:"source" db:"source"`
+	ExternalID     string     `json:"external_id" db:"external_id"`
+	Number         int        `json:"number" db:"number"`
+	Title          string     `json:"title" db:"title"`
+	Status         string     `json:"status" db:"status"`
+	Urgency        string     `json:"urgency" db:"urgency"`
+	ServiceID      string     `json:"service_id" db:"service_id"`
+	ServiceName    string     `json:"service_name" db:"service_name"`
+	Repository     *string    `json:"repository,omitempty" db:"repository"`
+	TriggeredAt    time.Time  `json:"triggered_at" db:"triggered_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	ResolvedAt     *time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+type IncidentService struct {
+	Source      string    `json:"source" db:"source"`
+	ServiceID   string    `json:"service_id" db:"service_id"`
+	ServiceName string    `json:"service_name" db:"service_name"`
+	Repository  string    `json:"repository" db:"repository"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Deployment struct {
+	ID          int        `json:"id" db:"id"`
+	Provider    string     `json:"provider" db:"provider"`
+	Repository  string     `json:"repository" db:"repository"`
+	ExternalID  string     `json:"external_id" db:"external_id"`
+	Environment string     `json:"environment" db:"environment"`
+	Ref         string     `json:"ref" db:"ref"`
+	SHA         string     `json:"sha" db:"sha"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at" db:"finished_at"`
+}
+
+type CISummary struct {
+	Provider       string  `json:"provider"`
+	Repository     string  `json:"repository"`
+	WorkflowName   string  `json:"workflow_name"`
+	Runs           int     `json:"runs"`
+	Successes      int     `json:"successes"`
+	Failures       int     `json:"failures"`
+	SuccessRate    float64 `json:"success_rate"`
+	MedianDuration float64 `json:"median_duration"`
+	P95Duration    float64 `json:"p95_duration"`
+}
+
+type DoraMetrics struct {
+	From                time.Time `json:"from"`
+	To                  time.Time `json:"to"`
+	Deployments         int       `json:"deployments"`
+	DeploymentsPerDay   float64   `json:"deployments_per_day"`
+	DeploymentFrequency string    `json:"deployment_frequency_tier"`
+	LeadTimeHours       *float64  `json:"lead_time_hours"`
+	LeadTime            string    `json:"lead_time_tier,omitempty"`
+	ChangeFailureRate   *float64  `json:"change_failure_rate"`
+	ChangeFailure       string    `json:"change_failure_rate_tier,omitempty"`
+	TimeToRestoreHours  *float64  `json:"time_to_restore_hours"`
+	TimeToRestore       string    `json:"time_to_restore_tier,omitempty"`
+	IncidentSource      string    `json:"incident_source"`
+}
+
+type StatusTime struct {
+	Status       string  `json:"status"`
+	Transitions  int     `json:"transitions"`
+	MedianHours  float64 `json:"median_hours"`
+	AverageHours float64 `json:"average_hours"`
+}
+
+type FlowMetrics struct {
+	Source         string       `json:"source,omitempty"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Throughput     int          `json:"throughput"`
+	WorkInProgress int          `json:"work_in_progress"`
+	LeadTimeHours  *float64     `json:"lead_time_hours"`
+	CycleTimeHours *float64     `json:"cycle_time_hours"`
+	CycleTimeP85   *float64     `json:"cycle_time_p85_hours"`
+	TimeInStatus   []StatusTime `json:"time_in_status"`
+}
+
+type SprintVelocity struct {
+	Sprint
+	Tickets           int     `json:"tickets"`
+	CompletedTickets  int     `json:"completed_tickets"`
+	CommittedEstimate float64 `json:"committed_estimate"`
+	CompletedEstimate float64 `json:"completed_estimate"`
+}
+
+// TicketLink is a pull request or commit referencing a ticket. Ref is the
+// pull request number or commit sha; DeployedAt is when a merged pull
+// request first reached the DORA environment.
+type TicketLink struct {
+	TicketKey  string     `json:"ticket_key"`
+	Kind       string     `json:"kind"`
+	Provider   string     `json:"provider"`
+	Repository string     `json:"repository"`
+	Ref        string     `json:"ref"`
+	Title      string     `json:"title"`
+	CreatedAt  *time.Time `json:"created_at"`
+	MergedAt   *time.Time `json:"merged_at"`
+	DeployedAt *time.Time `json:"deployed_at"`
+}
+
+// TicketLeadTime traces a ticket from creation to production through the
+// commits and pull requests referencing it. A ticket counts as deployed once
+// every merged pull request of it is.
+type TicketLeadTime struct {
+	Source             string     `json:"source"`
+	TicketKey          string     `json:"ticket_key"`
+	Summary            string     `json:"summary"`
+	Status             string     `json:"status"`
+	CreatedAt          time.Time  `json:"created_at"`
+	FirstCommitAt      *time.Time `json:"first_commit_at"`
+	FirstPullRequestAt *time.Time `json:"first_pull_request_at"`
+	MergedAt           *time.Time `json:"merged_at"`
+	DeployedAt         *time.Time `json:"deployed_at"`
+	LeadTimeHours      *float64   `json:"lead_time_hours"`
+	Commits            int        `json:"commits"`
+	PullRequests       int        `json:"pull_requests"`
+}
+
+// Scorecard grades a catalog team or service. Score weighs the tiers of the
+// metrics with data, from 1 (low) to 4 (elite); Trend compares it with the
+// first period of History, the windows before.
+type Scorecard struct {
+	Kind    string            `json:"kind"`
+	Name    string            `json:"name"`
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Score   *float64          `json:"score"`
+	Grade   string            `json:"grade,omitempty"`
+	Trend   string            `json:"trend,omitempty"`
+	Metrics []ScorecardMetric `json:"metrics"`
+	History []ScorecardPeriod `json:"history"`
+}
+
+type ScorecardMetric struct {
+	Name   string   `json:"name"`
+	Value  *float64 `json:"value"`
+	Tier   string   `json:"tier,omitempty"`
+	Weight float64  `json:"weight"`
+}
+
+type ScorecardPeriod struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Score *float64  `json:"score"`
+	Grade string    `json:"grade,omitempty"`
+}
+
+// Alert is raised by an alert rule while it fires. Notified tells whether its
+// channels were told about the current status.
+type Alert struct {
+	ID          int        `json:"id"`
+	Rule        string     `json:"rule"`
+	Status      string     `json:"status"`
+	Value       *float64   `json:"value"`
+	Baseline    *float64   `json:"baseline"`
+	Message     string     `json:"message"`
+	StartedAt   time.Time  `json:"started_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	EvaluatedAt time.Time  `json:"evaluated_at"`
+	Notified    bool       `json:"notified"`
+}
+
+// Anomaly is a day on which a series strayed from its baseline. Expected is
+// the baseline median, Deviation its robust spread and Score how many of
+// those the value lies from it.
+type Anomaly struct {
+	Series    string    `json:"series"`
+	Key       string    `json:"key"`
+	Day       time.Time `json:"day"`
+	Value     float64   `json:"value"`
+	Expected  float64   `json:"expected"`
+	Deviation float64   `json:"deviation"`
+	Score     float64   `json:"score"`
+	Direction string    `json:"direction"`
+}
+
+// APIKey describes an API key without its secret, which is only shown once
+// when the key is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Catalog is the set of teams and services, as edited through the API or the
+// CATALOG_FILE YAML file.
+type Catalog struct {
+	Teams    []Team    `json:"teams" yaml:"teams"`
+	Services []Service `json:"services" yaml:"services"`
+}
+
+type Team struct {
+	ID          int          `json:"id" yaml:"-"`
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description" yaml:"description,omitempty"`
+	Members     []TeamMember `json:"members" yaml:"members,omitempty"`
+	UpdatedAt   time.Time    `json:"updated_at" yaml:"-"`
+}
+
+type TeamMember struct {
+	Name  string `json:"name" yaml:"name,omitempty"`
+	Email string `json:"email" yaml:"email"`
+	Role  string `json:"role" yaml:"role,omitempty"`
+}
+
+// Service maps a service to the resources it is made of. Jira components
+// narrow the Jira projects to tickets with one of the components.
+type Service struct {
+	ID                int       `json:"id" yaml:"-"`
+	Name              string    `json:"name" yaml:"name"`
+	Team              string    `json:"team" yaml:"team,omitempty"`
+	Description       string    `json:"description" yaml:"description,omitempty"`
+	Repositories      []string  `json:"repositories" yaml:"repositories,omitempty"`
+	SonarqubeProjects []string  `json:"sonarqube_projects" yaml:"sonarqube_projects,omitempty"`
+	JiraProjects      []string  `json:"jira_projects" yaml:"jira_projects,omitempty"`
+	JiraComponents    []string  `json:"jira_components" yaml:"jira_components,omitempty"`
+	IncidentServices  []string  `json:"incident_services" yaml:"incident_services,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at" yaml:"-"`
+}
+
+// Person is someone known under accounts in one or more systems.
+type Person struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Email      *string    `json:"email"`
+	Teams      []string   `json:"teams"`
+	Identities []Identity `json:"identities"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Identity is an account in one system (github, gitlab, jira, linear,
+// sonarqube). Manual identities were linked through the API and are never
+// relinked by email.
+type Identity struct {
+	ID          int       `json:"id"`
+	System      string    `json:"system"`
+	AccountID   string    `json:"account_id"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	PersonID    *int      `json:"person_id"`
+	Manual      bool      `json:"manual"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
 }
@@ -1,105 +1,159 @@
 package scheduler
 
 import (
+	"context"
 	"log"
+	"sync"
 	"time"
 
+	"code-pulse/internal/alerting"
+	"code-pulse/internal/collector"
 	"code-pulse/internal/config"
+	"code-pulse/internal/digest"
+	"code-pulse/internal/models"
 	"code-pulse/internal/services"
-	
+
 	"github.com/robfig/cron/v3"
 )
 
 type Scheduler struct {
 	cron           *cron.Cron
+	registry       *collector.Registry
 	metricsService *services.MetricsService
+	alerts         *alerting.Evaluator
+	digests        *digest.Reporter
 	config         *config.Config
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.Map
 }
 
-func New(metricsService *services.MetricsService, config *config.Config) *Scheduler {
+func New(registry *collector.Registry, metricsService *services.MetricsService, alerts *alerting.Evaluator,
+	digests *digest.Reporter, config *config.Config) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Scheduler{
 		cron:           cron.New(),
+		registry:       registry,
 		metricsService: metricsService,
+		alerts:         alerts,
+		digests:        digests,
 		config:         config,
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 }
 
 func (s *Scheduler) Start() error {
-	if s.config.CollectionSchedule == "" {
-		log.Println("No collection schedule configured, skipping scheduled data collection")
-		return nil
+	var scheduled []collector.Collector
+
+	for _, c := range s.registry.All() {
+		if !s.config.CollectorEnabled(c.Name()) {
+			log.Printf("Collector %s disabled, skipping", c.Name())
+			continue
+		}
+
+		if err := c.Validate(); err != nil {
+			log.Printf("Collector %s configuration incomplete, skipping: %v", c.Name(), err)
+			continue
+		}
+
+		schedule := s.config.CollectorSchedule(c.Name())
+		if schedule == "" {
+			log.Printf("No collection schedule configured for %s, skipping scheduled collection", c.Name())
+			continue
+		}
+
+		c := c
+		if _, err := s.cron.AddFunc(schedule, func() { s.run(c) }); err != nil {
+			return err
+		}
+
+		log.Printf("Scheduled collector %s with schedule: %s", c.Name(), schedule)
+		scheduled = append(scheduled, c)
 	}
 
-	_, err := s.cron.AddFunc(s.config.CollectionSchedule, s.collectMetrics)
-	if err != nil {
-		return err
+	digests := s.digests.Enabled()
+	if digests {
+		if _, err := s.cron.AddFunc(s.config.DigestSchedule, s.sendDigests); err != nil {
+			return err
+		}
+		log.Printf("Scheduled weekly digests with schedule: %s", s.config.DigestSchedule)
+	}
+
+	if len(scheduled) == 0 && !digests {
+		log.Println("No collectors scheduled, skipping scheduled data collection")
+		return nil
 	}
 
 	s.cron.Start()
-	log.Printf("Scheduler started with schedule: %s", s.config.CollectionSchedule)
-	
+	log.Println("Scheduler started")
+
 	log.Println("Running initial metrics collection...")
-	go s.collectMetrics()
-	
+	go func() {
+		for _, c := range scheduled {
+			s.run(c)
+		}
+	}()
+
 	return nil
 }
 
 func (s *Scheduler) Stop() {
-	s.cron.Stop()
+	s.cancel()
+	<-s.cron.Stop().Done()
 	log.Println("Scheduler stopped")
 }
 
-func (s *Scheduler) collectMetrics() {
-	log.Println("Starting scheduled metrics collection...")
-	startTime := time.Now()
-
-	s.collectGithubMetrics()
-	s.collectSonarqubeMetrics()
-	s.collectJiraMetrics()
-
-	duration := time.Since(startTime)
-	log.Printf("Metrics collection completed in %v", duration)
+func (s *Scheduler) sendDigests() {
+	log.Println("Sending weekly digests...")
+	if err := s.digests.SendAll(s.ctx); err != nil {
+		log.Printf("Error sending weekly digests: %v", err)
+	}
 }
 
-func (s *Scheduler) collectGithubMetrics() {
-	if s.config.GithubToken == "" || s.config.GithubOrg == "" {
-		log.Println("GitHub configuration incomplete, skipping GitHub metrics collection")
+// run collects from a single source, picking up from its last successful
+// run, and records the outcome. Overlapping runs of one collector are skipped.
+func (s *Scheduler) run(c collector.Collector) {
+	if _, busy := s.running.LoadOrStore(c.Name(), true); busy {
+		log.Printf("Collector %s is still running, skipping", c.Name())
 		return
 	}
+	defer s.running.Delete(c.Name())
 
-	log.Println("Collecting GitHub metrics...")
-	
-	for _, repo := range s.config.GithubRepos {
-		for _, workflow := range repo.Workflows {
-			log.Printf("Collecting metrics for %s/%s workflow: %s", s.config.GithubOrg, repo.Name, workflow)
-			
-			if err := s.metricsService.CollectGithubMetricsByWorkflow(s.config.GithubOrg, repo.Name, workflow); err != nil {
-				log.Printf("Error collecting GitHub metrics for %s/%s workflow %s: %v", 
-					s.config.GithubOrg, repo.Name, workflow, err)
-				continue
-			}
-			
-			time.Sleep(1 * time.Second)
-		}
+	since, err := s.metricsService.LastSuccessfulRun(c.Name())
+	if err != nil {
+		log.Printf("Error reading last run of %s, collecting everything: %v", c.Name(), err)
 	}
-	
-	log.Println("GitHub metrics collection completed")
-}
 
-func (s *Scheduler) collectSonarqubeMetrics() {
-	if s.config.SonarqubeURL == "" || s.config.SonarqubeToken == "" {
-		log.Println("SonarQube configuration incomplete, skipping SonarQube metrics collection")
-		return
+	log.Printf("Collecting %s metrics...", c.Name())
+	run := &models.CollectionRun{
+		Collector: c.Name(),
+		StartedAt: time.Now(),
+		Status:    "success",
 	}
 
-	log.Println("SonarQube metrics collection not yet implemented in scheduler")
-}
+	if err := c.Collect(s.ctx, since); err != nil {
+		run.Status = "error"
+		run.Error = err.Error()
+	}
+	run.FinishedAt = time.Now()
 
-func (s *Scheduler) collectJiraMetrics() {
-	if s.config.JiraURL == "" || s.config.JiraEmail == "" || s.config.JiraToken == "" {
-		log.Println("Jira configuration incomplete, skipping Jira metrics collection")
-		return
+	if err := s.metricsService.RecordCollectionRun(run); err != nil {
+		log.Printf("Error recording %s collection run: %v", c.Name(), err)
 	}
 
-	log.Println("Jira metrics collection not yet implemented in scheduler")
-}
\ No newline at end of file
+	log.Printf("%s metrics collection completed in %v (%s)", c.Name(), run.FinishedAt.Sub(run.StartedAt), run.Status)
+
+	linked, err := s.metricsService.LinkDeployments(s.config.DoraEnvironment)
+	if err != nil {
+		log.Printf("Error linking pull requests to deployments: %v", err)
+	} else if linked > 0 {
+		log.Printf("Linked %d pull requests to %s deployments", linked, s.config.DoraEnvironment)
+	}
+
+	if err := s.alerts.Evaluate(s.ctx); err != nil {
+		log.Printf("Error evaluating alert rules: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package scorecard
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/dora"
+	"code-pulse/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Metric grades one measure into the DORA tiers. Values reaching the elite,
+// high or medium threshold earn that tier; thresholds are minimums when
+// higher is better and maximums otherwise.
+type Metric struct {
+	Name           string
+	HigherIsBetter bool
+	Weight         float64
+	Thresholds     [3]float64
+}
+
+// Defaults follow the State of DevOps clusters for the DORA metrics and
+// common targets for the rest.
+var Defaults = []Metric{
+	{Name: "ci_success_rate", HigherIsBetter: true, Weight: 1, Thresholds: [3]float64{0.95, 0.90, 0.80}},
+	{Name: "ci_median_duration_minutes", Weight: 1, Thresholds: [3]float64{10, 20, 40}},
+	{Name: "quality_gate_pass_rate", HigherIsBetter: true, Weight: 1, Thresholds: [3]float64{1, 0.90, 0.75}},
+	{Name: "coverage", HigherIsBetter: true, Weight: 1, Thresholds: [3]float64{80, 70, 50}},
+	{Name: "deployment_frequency", HigherIsBetter: true, Weight: 1, Thresholds: [3]float64{1, 1.0 / 7, 1.0 / 30}},
+	{Name: "lead_time_hours", Weight: 1, Thresholds: [3]float64{24, 24 * 7, 24 * 30}},
+	{Name: "change_failure_rate", Weight: 1, Thresholds: [3]float64{0.05, 0.10, 0.15}},
+	{Name: "time_to_restore_hours", Weight: 1, Thresholds: [3]float64{1, 24, 24 * 7}},
+	{Name: "flow_lead_time_hours", Weight: 1, Thresholds: [3]float64{24 * 7, 24 * 14, 24 * 30}},
+	{Name: "cycle_time_hours", Weight: 1, Thresholds: [3]float64{48, 24 * 5, 24 * 10}},
+}
+
+// points turns tiers into the numbers weighted into a score.
+var points = map[string]float64{dora.Elite: 4, dora.High: 3, dora.Medium: 2, dora.Low: 1}
+
+// Metrics applies the SCORECARD_METRICS overrides to the defaults, leaving
+// out metrics weighted zero.
+func Metrics(overrides map[string]config.ScorecardMetricConfig) ([]Metric, error) {
+	known := make(map[string]bool)
+	for _, m := range Defaults {
+		known[m.Name] = true
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown scorecard metric %q", name)
+		}
+	}
+
+	var metrics []Metric
+	for _, m := range Defaults {
+		override, ok := overrides[m.Name]
+		if ok && override.Weight != nil {
+			if *override.Weight < 0 {
+				return nil, fmt.Errorf("scorecard metric %s: weight must not be negative", m.Name)
+			}
+			m.Weight = *override.Weight
+		}
+		if ok && override.Thresholds != nil {
+			if len(override.Thresholds) != 3 {
+				return nil, fmt.Errorf("scorecard metric %s: expected elite, high and medium thresholds", m.Name)
+			}
+			copy(m.Thresholds[:], override.Thresholds)
+			if !m.ordered() {
+				return nil, fmt.Errorf("scorecard metric %s: thresholds must run from elite to medium", m.Name)
+			}
+		}
+		if m.Weight > 0 {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (m Metric) ordered() bool {
+	t := m.Thresholds
+	if m.HigherIsBetter {
+		return t[0] >= t[1] && t[1] >= t[2]
+	}
+	return t[0] <= t[1] && t[1] <= t[2]
+}
+
+// Tier grades a value of the metric.
+func (m Metric) Tier(value float64) string {
+	tiers := []string{dora.Elite, dora.High, dora.Medium}
+	for i, threshold := range m.Thresholds {
+		if (m.HigherIsBetter && value >= threshold) || (!m.HigherIsBetter && value <= threshold) {
+			return tiers[i]
+		}
+	}
+	return dora.Low
+}
+
+// Points returns what a tier weighs in a score, zero for no tier.
+func Points(tier string) float64 {
+	return points[tier]
+}
+
+// Grade turns a score between 1 (low) and 4 (elite) into a tier.
+func Grade(score float64) string {
+	switch {
+	case score >= 3.5:
+		return dora.Elite
+	case score >= 2.5:
+		return dora.High
+	case score >= 1.5:
+		return dora.Medium
+	default:
+		return dora.Low
+	}
+}
+
+// Filter is what a scorecard covers. Unlike elsewhere, empty lists leave the
+// metrics of that kind out instead of matching nothing, so a team without
+// SonarQube projects is not graded on coverage.
+type Filter struct {
+	Repositories      []string
+	SonarqubeProjects []string
+	JiraScopes        []string
+	IncidentServices  []string
+	Environment       string
+	IncidentLabel     string
+	From              time.Time
+	To                time.Time
+}
+
+// Compute grades each metric with data in the window and weighs the tiers
+// into a score. Score and grade are absent when no metric has data.
+func Compute(db *sql.DB, metrics []Metric, f Filter) (*models.ScorecardPeriod, []models.ScorecardMetric, error) {
+	values, err := measure(db, f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	period := &models.ScorecardPeriod{From: f.From, To: f.To}
+	results := make([]models.ScorecardMetric, 0, len(metrics))
+	var total, weights float64
+	for _, m := range metrics {
+		result := models.ScorecardMetric{Name: m.Name, Weight: m.Weight, Value: values[m.Name]}
+		if result.Value != nil {
+			result.Tier = m.Tier(*result.Value)
+			total += points[result.Tier] * m.Weight
+			weights += m.Weight
+		}
+		results = append(results, result)
+	}
+	if weights > 0 {
+		score := total / weights
+		period.Score = &score
+		period.Grade = Grade(score)
+	}
+	return period, results, nil
+}
+
+// Trend compares a score with the one of the period before: up, down or flat.
+func Trend(current, previous *float64) string {
+	if current == nil || previous == nil {
+		return ""
+	}
+	switch diff := *current - *previous; {
+	case diff > 0.05:
+		return "up"
+	case diff < -0.05:
+		return "down"
+	default:
+		return "flat"
+	}
+}
+
+func measure(db *sql.DB, f Filter) (map[string]*float64, error) {
+	values := make(map[string]*float64)
+	set := func(name string, value sql.NullFloat64) {
+		if value.Valid {
+			v := value.Float64
+			values[name] = &v
+		}
+	}
+
+	if f.Repositories == nil || len(f.Repositories) > 0 {
+		var successRate, duration sql.NullFloat64
+		err := db.QueryRow(`
+			SELECT COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION /
+					NULLIF(COUNT(*) FILTER (WHERE status IN ('success', 'failure', 'cancelled', 'timed_out')), 0),
+				PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration) / 60
+			FROM github_workflows
+			WHERE created_at >= $1
+			AND created_at < $2
+			AND ($3::TEXT[] IS NULL OR repository = ANY($3))`,
+			f.From, f.To, pq.Array(f.Repositories)).Scan(&successRate, &duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure CI runs: %w", err)
+		}
+		set("ci_success_rate", successRate)
+		set("ci_median_duration_minutes", duration)
+
+		// Time to restore covers the incidents of the same team or service:
+		// those of its incident services and repositories, or its incident
+		// tickets.
+		metrics, err := dora.Compute(db, dora.Filter{
+			Repositories:     f.Repositories,
+			JiraScopes:       f.JiraScopes,
+			IncidentServices: f.IncidentServices,
+			Environment:      f.Environment,
+			IncidentLabel:    f.IncidentLabel,
+			From:             f.From,
+			To:               f.To,
+		})
+		if err != nil {
+			return nil, err
+		}
+		set("deployment_frequency", sql.NullFloat64{Float64: metrics.DeploymentsPerDay, Valid: true})
+		set("lead_time_hours", nullFloat(metrics.LeadTimeHours))
+		set("change_failure_rate", nullFloat(metrics.ChangeFailureRate))
+		set("time_to_restore_hours", nullFloat(metrics.TimeToRestoreHours))
+	}
+
+	if f.SonarqubeProjects == nil || len(f.SonarqubeProjects) > 0 {
+		// The main branch as it stood at the end of the window.
+		var passRate, coverage sql.NullFloat64
+		err := db.QueryRow(`
+			SELECT
+				(SELECT COUNT(*) FILTER (WHERE status = 'OK')::DOUBLE PRECISION / NULLIF(COUNT(*), 0)
+				FROM (
+					SELECT DISTINCT ON (project_key) status
+					FROM sonarqube_quality_gates
+					WHERE branch = '' AND pull_request = ''
+					AND collected_at < $1
+					AND ($2::TEXT[] IS NULL OR project_key = ANY($2))
+					ORDER BY project_key, collected_at DESC
+				) gates),
+				(SELECT AVG(numeric_value)
+				FROM (
+					SELECT DISTINCT ON (project_key) numeric_value
+					FROM sonarqube_metrics
+					WHERE metric_key = 'coverage'
+					AND branch = '' AND pull_request = '' AND qualifier = 'TRK'
+					AND numeric_value IS NOT NULL
+					AND collected_at < $1
+					AND ($2::TEXT[] IS NULL OR project_key = ANY($2))
+					ORDER BY project_key, collected_at DESC
+				) coverage)`,
+			f.To, pq.Array(f.SonarqubeProjects)).Scan(&passRate, &coverage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure SonarQube projects: %w", err)
+		}
+		set("quality_gate_pass_rate", passRate)
+		set("coverage", coverage)
+	}
+
+	if f.JiraScopes == nil || len(f.JiraScopes) > 0 {
+		var leadTime, cycleTime sql.NullFloat64
+		err := db.QueryRow(`
+			SELECT
+				(SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600
+				FROM jira_tickets
+				WHERE resolved_at >= $1 AND resolved_at < $2
+				AND ticket_in_scope(ticket_key, components, $3)),
+				(SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY hours)
+				FROM (
+					SELECT EXTRACT(EPOCH FROM t.resolved_at - MIN(tr.transitioned_at)) / 3600 AS hours
+					FROM jira_tickets t
+					JOIN ticket_transitions tr ON tr.source = t.source AND tr.ticket_key = t.ticket_key
+					WHERE tr.to_category = 'in_progress'
+					AND t.resolved_at >= $1 AND t.resolved_at < $2
+					AND ticket_in_scope(t.ticket_key, t.components, $3)
+					GROUP BY t.id, t.resolved_at
+				) cycle_times)`,
+			f.From, f.To, pq.Array(f.JiraScopes)).Scan(&leadTime, &cycleTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure ticket flow: %w", err)
+		}
+		set("flow_lead_time_hours", leadTime)
+		set("cycle_time_hours", cycleTime)
+	}
+
+	return values, nil
+}
+
+func nullFloat(value *float64) sql.NullFloat64 {
+	if value == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *value, Valid: true}
+}
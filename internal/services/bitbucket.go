@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/bitbucket"
+)
+
+type BitbucketCollector struct {
+	metrics *MetricsService
+	client  *bitbucket.Client
+	config  *config.Config
+}
+
+func NewBitbucketCollector(metrics *MetricsService, cfg *config.Config) *BitbucketCollector {
+	return &BitbucketCollector{
+		metrics: metrics,
+		client:  bitbucket.NewClient(cfg.BitbucketURL, cfg.BitbucketUsername, cfg.BitbucketToken),
+		config:  cfg,
+	}
+}
+
+func (c *BitbucketCollector) Name() string {
+	return "bitbucket"
+}
+
+func (c *BitbucketCollector) Validate() error {
+	if c.config.BitbucketURL == "" || c.config.BitbucketToken == "" {
+		return fmt.Errorf("BITBUCKET_URL and BITBUCKET_TOKEN are required")
+	}
+	if len(c.config.BitbucketRepositories) == 0 && len(c.config.BitbucketWorkspaces) == 0 {
+		return fmt.Errorf("BITBUCKET_REPOSITORIES or BITBUCKET_WORKSPACES must be set")
+	}
+	return nil
+}
+
+func (c *BitbucketCollector) Health(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
+
+// Collect stores pipelines created and pull requests updated since the given
+// time, looking back 90 days on the first run, and refreshes the pipelines
+// that were still running. Data Center has no Pipelines, so only pull requests
+// are collected there.
+func (c *BitbucketCollector) Collect(ctx context.Context, since time.Time) error {
+	if since.IsZero() {
+		since = time.Now().AddDate(0, 0, -90)
+	}
+
+	repositories, err := c.repositories()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, repo := range repositories {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.Printf("Collecting metrics for Bitbucket repository: %s", repo.FullName)
+
+		repository := &models.Repository{
+			Provider:      "bitbucket",
+			Repository:    repo.FullName,
+			DefaultBranch: repo.DefaultBranch,
+			WebURL:        repo.WebURL,
+			UpdatedAt:     time.Now(),
+		}
+		if err := c.metrics.saveRepository(repository); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save repository %s: %w", repo.FullName, err))
+			continue
+		}
+
+		if c.client.IsCloud() {
+			if err := c.collectPipelines(ctx, repo.FullName, since); err != nil {
+				log.Printf("Error collecting Bitbucket pipelines for %s: %v", repo.FullName, err)
+				errs = append(errs, err)
+			}
+		}
+
+		if err := c.collectPullRequests(ctx, repo.FullName, since); err != nil {
+			log.Printf("Error collecting Bitbucket pull requests for %s: %v", repo.FullName, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// repositories resolves BITBUCKET_REPOSITORIES and every repository of
+// BITBUCKET_WORKSPACES.
+func (c *BitbucketCollector) repositories() ([]bitbucket.Repository, error) {
+	seen := make(map[string]bool)
+	var repositories []bitbucket.Repository
+
+	for _, fullName := range c.config.BitbucketRepositories {
+		repo, err := c.client.GetRepository(fullName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repository %s: %w", fullName, err)
+		}
+		if !seen[repo.FullName] {
+			seen[repo.FullName] = true
+			repositories = append(repositories, *repo)
+		}
+	}
+
+	for _, workspace := range c.config.BitbucketWorkspaces {
+		workspaceRepos, err := c.client.GetRepositories(workspace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repositories of %s: %w", workspace, err)
+		}
+		for _, repo := range workspaceRepos {
+			if !seen[repo.FullName] {
+				seen[repo.FullName] = true
+				repositories = append(repositories, repo)
+			}
+		}
+	}
+
+	return repositories, nil
+}
+
+func (c *BitbucketCollector) collectPipelines(ctx context.Context, repository string, since time.Time) error {
+	// Pipelines still running at the last collection are fetched again.
+	from, err := c.metrics.collectFrom("bitbucket", repository, "", since)
+	if err != nil {
+		return err
+	}
+	pipelines, err := c.client.GetPipelines(repository, from)
+	if err != nil {
+		return fmt.Errorf("failed to get pipelines: %w", err)
+	}
+
+	for _, pipeline := range pipelines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		completedAt := pipeline.CreatedAt
+		if pipeline.CompletedAt != nil {
+			completedAt = *pipeline.CompletedAt
+		}
+
+		name := pipeline.Selector
+		if name == "" {
+			name = "default"
+		}
+
+		workflow := &models.GithubWorkflow{
+			Provider:     "bitbucket",
+			ExternalID:   fmt.Sprintf("%s#%d", repository, pipeline.BuildNumber),
+			Repository:   repository,
+			WorkflowName: name,
+			Status:       normalizeStatus(pipeline.State),
+			Duration:     pipeline.DurationInSeconds,
+			HeadBranch:   pipeline.RefName,
+			HeadSHA:      pipeline.Commit,
+			Cause:        strings.ToLower(pipeline.Trigger),
+			CreatedAt:    pipeline.CreatedAt,
+			CompletedAt:  completedAt,
+		}
+		if err := c.metrics.saveGithubWorkflow(workflow); err != nil {
+			return fmt.Errorf("failed to save pipeline: %w", err)
+		}
+
+		steps, err := c.client.GetPipelineSteps(repository, pipeline.UUID)
+		if err != nil {
+			return fmt.Errorf("failed to get steps of pipeline %d: %w", pipeline.BuildNumber, err)
+		}
+
+		for _, step := range steps {
+			job := &models.CIJob{
+				Provider:   "bitbucket",
+				Repository: repository,
+				PipelineID: workflow.ExternalID,
+				ExternalID: step.UUID,
+				Name:       step.Name,
+				Status:     normalizeStatus(step.State),
+				CreatedAt:  pipeline.CreatedAt,
+				StartedAt:  step.StartedAt,
+				FinishedAt: step.CompletedAt,
+			}
+			if step.StartedAt != nil {
+				job.QueuedDuration = int(step.StartedAt.Sub(pipeline.CreatedAt).Seconds())
+				if step.CompletedAt != nil {
+					job.Duration = int(step.CompletedAt.Sub(*step.StartedAt).Seconds())
+				}
+			}
+			if err := c.metrics.saveCIJob(job); err != nil {
+				return fmt.Errorf("failed to save step: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *BitbucketCollector) collectPullRequests(ctx context.Context, repository string, since time.Time) error {
+	pullRequests, err := c.client.GetPullRequests(repository, since)
+	if err != nil {
+		return fmt.Errorf("failed to get pull requests: %w", err)
+	}
+
+	for _, pr := range pullRequests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		activities, err := c.client.GetPullRequestActivity(repository, pr.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get activity of pull request %d: %w", pr.ID, err)
+		}
+
+		// Bitbucket reports merged and declined pull requests in their own
+		// states; GitHub reports both as closed and sets merged_at.
+		pullRequest := &models.GithubPullRequest{
+			Provider:   "bitbucket",
+			Repository: repository,
+			Number:     pr.ID,
+			Title:      pr.Title,
+			Author:     pr.Author,
+			State:      "closed",
+			HeadBranch: pr.SourceBranch,
+			BaseBranch: pr.TargetBranch,
+			CreatedAt:  pr.CreatedAt,
+			UpdatedAt:  pr.UpdatedAt,
+			ClosedAt:   pr.ClosedAt,
+		}
+		if pr.State == "OPEN" {
+			pullRequest.State = "open"
+		}
+
+		for _, a := range activities {
+			if a.Action == "MERGED" {
+				mergedAt := a.CreatedAt
+				pullRequest.MergedAt = &mergedAt
+				pullRequest.ClosedAt = &mergedAt
+			}
+		}
+		if pr.State == "MERGED" && pullRequest.MergedAt == nil {
+			pullRequest.MergedAt = pr.ClosedAt
+		}
+
+		if err := c.metrics.saveGithubPullRequest(pullRequest); err != nil {
+			return fmt.Errorf("failed to save pull request: %w", err)
+		}
+
+		for _, a := range activities {
+			activity := &models.PullRequestActivity{
+				Provider:   "bitbucket",
+				Repository: repository,
+				Number:     pr.ID,
+				Action:     strings.ToLower(a.Action),
+				Author:     a.Author,
+				CreatedAt:  a.CreatedAt,
+			}
+			if err := c.metrics.savePullRequestActivity(activity); err != nil {
+				return fmt.Errorf("failed to save pull request activity: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/github"
+)
+
+type GithubCollector struct {
+	metrics *MetricsService
+	client  *github.Client
+	token   string
+	org     string
+	repos   []config.RepoConfig
+}
+
+func NewGithubCollector(metrics *MetricsService, cfg *config.Config) *GithubCollector {
+	return &GithubCollector{
+		metrics: metrics,
+		client:  github.NewClient(cfg.GithubToken),
+		token:   cfg.GithubToken,
+		org:     cfg.GithubOrg,
+		repos:   cfg.GithubRepos,
+	}
+}
+
+func (c *GithubCollector) Name() string {
+	return "github"
+}
+
+func (c *GithubCollector) Validate() error {
+	if c.token == "" || c.org == "" {
+		return fmt.Errorf("GITHUB_TOKEN and GITHUB_ORG are required")
+	}
+	if len(c.repos) == 0 {
+		return fmt.Errorf("GITHUB_REPOS is empty")
+	}
+	return nil
+}
+
+func (c *GithubCollector) Health(ctx context.Context) error {
+	rateLimit, err := c.client.GetRateLimit(ctx)
+	if err != nil {
+		return err
+	}
+	if rateLimit.Remaining == 0 {
+		return fmt.Errorf("rate limit exhausted until %s", time.Unix(rateLimit.Reset, 0).Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *GithubCollector) RateLimitRemaining() (int, bool) {
+	return c.client.RateLimitRemaining()
+}
+
+func (c *GithubCollector) Collect(ctx context.Context, since time.Time) error {
+	var errs []error
+
+	for _, repo := range c.repos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.collectPullRequests(c.org, repo.Name, since); err != nil {
+			log.Printf("Error collecting GitHub pull requests for %s/%s: %v", c.org, repo.Name, err)
+			errs = append(errs, err)
+		}
+
+		if err := c.collectCommits(c.org, repo.Name); err != nil {
+			log.Printf("Error collecting GitHub commits for %s/%s: %v", c.org, repo.Name, err)
+			errs = append(errs, err)
+		}
+
+		if err := c.collectDeployments(c.org, repo.Name, since); err != nil {
+			log.Printf("Error collecting GitHub deployments for %s/%s: %v", c.org, repo.Name, err)
+			errs = append(errs, err)
+		}
+
+		workflows := repo.Workflows
+		if len(workflows) == 0 {
+			workflows = []string{""}
+		}
+
+		for _, workflow := range workflows {
+			log.Printf("Collecting metrics for %s/%s workflow: %s", c.org, repo.Name, workflow)
+
+			if err := c.collectWorkflowRuns(c.org, repo.Name, workflow, since); err != nil {
+				log.Printf("Error collecting GitHub metrics for %s/%s workflow %s: %v",
+					c.org, repo.Name, workflow, err)
+				errs = append(errs, err)
+				continue
+			}
+
+			time.Sleep(1 * time.Second)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectWorkflowRuns stores the runs of the named workflow, or of every
+// workflow in the repository when workflowName is empty.
+func (c *GithubCollector) collectWorkflowRuns(owner, repo, workflowName string, since time.Time) error {
+	// Runs still queued or in progress at the last collection are fetched again.
+	from, err := c.metrics.collectFrom("github", fmt.Sprintf("%s/%s", owner, repo), workflowName, since)
+	if err != nil {
+		return err
+	}
+
+	var runs []github.WorkflowRun
+	if workflowName == "" {
+		runs, err = c.client.GetWorkflowRuns(owner, repo, 0, from)
+	} else {
+		runs, err = c.client.GetWorkflowRunsByName(owner, repo, workflowName, from)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get workflow runs for %s: %w", workflowName, err)
+	}
+
+	for _, run := range runs {
+		if err := c.saveWorkflowRun(owner, repo, run); err != nil {
+			return fmt.Errorf("failed to save workflow run: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *GithubCollector) collectPullRequests(owner, repo string, since time.Time) error {
+	pullRequests, err := c.client.GetPullRequests(owner, repo, since)
+	if err != nil {
+		return fmt.Errorf("failed to get pull requests: %w", err)
+	}
+
+	identities := c.metrics.newIdentityRecorder()
+	for _, pr := range pullRequests {
+		if err := identities.save("github", pr.User.Login, pr.User.Login, ""); err != nil {
+			return fmt.Errorf("failed to save github user: %w", err)
+		}
+
+		pullRequest := &models.GithubPullRequest{
+			Provider:   "github",
+			Repository: fmt.Sprintf("%s/%s", owner, repo),
+			Number:     pr.Number,
+			Title:      pr.Title,
+			Author:     pr.User.Login,
+			State:      pr.State,
+			HeadBranch: pr.Head.Ref,
+			BaseBranch: pr.Base.Ref,
+			CreatedAt:  pr.CreatedAt,
+			UpdatedAt:  pr.UpdatedAt,
+			ClosedAt:   pr.ClosedAt,
+			MergedAt:   pr.MergedAt,
+		}
+
+		if err := c.metrics.saveGithubPullRequest(pullRequest); err != nil {
+			return fmt.Errorf("failed to save pull request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// collectCommits stores the commits and changed files of a repository
+// since the newest commit already collected, or the last 90 days on first run.
+func (c *GithubCollector) collectCommits(owner, repo string) error {
+	repository := fmt.Sprintf("%s/%s", owner, repo)
+
+	since := time.Now().AddDate(0, 0, -90)
+	var latest sql.NullTime
+	if err := c.metrics.db.QueryRow(`SELECT MAX(committed_at) FROM github_commits WHERE repository = $1`, repository).Scan(&latest); err != nil {
+		return fmt.Errorf("failed to get latest commit: %w", err)
+	}
+	if latest.Valid {
+		since = latest.Time
+	}
+
+	commits, err := c.client.GetCommits(owner, repo, since)
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	identities := c.metrics.newIdentityRecorder()
+	for _, commit := range commits {
+		var exists bool
+		if err := c.metrics.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM github_commits WHERE repository = $1 AND sha = $2)`,
+			repository, commit.SHA).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check commit: %w", err)
+		}
+		if exists {
+			continue
+		}
+
+		detail, err := c.client.GetCommit(owner, repo, commit.SHA)
+		if err != nil {
+			return fmt.Errorf("failed to get commit %s: %w", commit.SHA, err)
+		}
+
+		githubCommit := &models.GithubCommit{
+			Repository:  repository,
+			SHA:         detail.SHA,
+			Author:      detail.Commit.Author.Name,
+			AuthorEmail: detail.Commit.Author.Email,
+			Message:     detail.Commit.Message,
+			CommittedAt: detail.Commit.Author.Date,
+		}
+		if detail.Author != nil {
+			githubCommit.Author = detail.Author.Login
+			// Commits tie a login to the email it commits with.
+			if err := identities.save("github", detail.Author.Login, detail.Commit.Author.Name,
+				detail.Commit.Author.Email); err != nil {
+				return fmt.Errorf("failed to save github user: %w", err)
+			}
+		}
+		for _, file := range detail.Files {
+			githubCommit.Files = append(githubCommit.Files, models.GithubCommitFile{
+				Path:      file.Filename,
+				Additions: file.Additions,
+				Deletions: file.Deletions,
+			})
+		}
+
+		if err := c.metrics.saveGithubCommit(githubCommit); err != nil {
+			return fmt.Errorf("failed to save commit: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// collectDeployments stores deployments updated since the given time along
+// with their latest status.
+func (c *GithubCollector) collectDeployments(owner, repo string, since time.Time) error {
+	deployments, err := c.client.GetDeployments(owner, repo, since)
+	if err != nil {
+		return fmt.Errorf("failed to get deployments: %w", err)
+	}
+
+	for _, d := range deployments {
+		status, err := c.client.GetLatestDeploymentStatus(owner, repo, d.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get deployment status: %w", err)
+		}
+
+		deployment := &models.Deployment{
+			Provider:    "github",
+			Repository:  fmt.Sprintf("%s/%s", owner, repo),
+			ExternalID:  fmt.Sprintf("%d", d.ID),
+			Environment: d.Environment,
+			Ref:         d.Ref,
+			SHA:         d.SHA,
+			Status:      "pending",
+			CreatedAt:   d.CreatedAt,
+		}
+		if status != nil {
+			deployment.Status = normalizeStatus(status.State)
+			if deployment.Status == "success" || deployment.Status == "failure" {
+				deployment.FinishedAt = &status.CreatedAt
+			}
+		}
+
+		if err := c.metrics.saveDeployment(deployment); err != nil {
+			return fmt.Errorf("failed to save deployment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *GithubCollector) saveWorkflowRun(owner, repo string, run github.WorkflowRun) error {
+	duration := run.GetDurationSeconds()
+
+	// Runs that have not started yet are stored as queued, which is what
+	// collectFrom looks for to fetch them again.
+	status := run.Status
+	switch {
+	case run.Conclusion != "":
+		status = run.Conclusion
+	case status == "waiting" || status == "requested" || status == "pending":
+		status = "queued"
+	}
+
+	workflow := &models.GithubWorkflow{
+		Provider:     "github",
+		ExternalID:   fmt.Sprintf("%d", run.ID),
+		Repository:   fmt.Sprintf("%s/%s", owner, repo),
+		WorkflowName: run.Name,
+		Status:       status,
+		Duration:     duration,
+		HeadBranch:   run.HeadBranch,
+		HeadSHA:      run.HeadSHA,
+		Cause:        run.Event,
+		CreatedAt:    run.CreatedAt,
+		CompletedAt:  run.UpdatedAt,
+	}
+
+	return c.metrics.saveGithubWorkflow(workflow)
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/gitlab"
+)
+
+type GitlabCollector struct {
+	metrics *MetricsService
+	client  *gitlab.Client
+	config  *config.Config
+}
+
+func NewGitlabCollector(metrics *MetricsService, cfg *config.Config) *GitlabCollector {
+	return &GitlabCollector{
+		metrics: metrics,
+		client:  gitlab.NewClient(cfg.GitlabURL, cfg.GitlabToken),
+		config:  cfg,
+	}
+}
+
+func (c *GitlabCollector) Name() string {
+	return "gitlab"
+}
+
+func (c *GitlabCollector) Validate() error {
+	if c.config.GitlabURL == "" || c.config.GitlabToken == "" {
+		return fmt.Errorf("GITLAB_URL and GITLAB_TOKEN are required")
+	}
+	if len(c.config.GitlabProjects) == 0 && len(c.config.GitlabGroups) == 0 {
+		return fmt.Errorf("GITLAB_PROJECTS or GITLAB_GROUPS must be set")
+	}
+	return nil
+}
+
+func (c *GitlabCollector) Health(ctx context.Context) error {
+	_, err := c.client.GetCurrentUser(ctx)
+	return err
+}
+
+func (c *GitlabCollector) RateLimitRemaining() (int, bool) {
+	return c.client.RateLimitRemaining()
+}
+
+// Collect stores pipelines, jobs, merge requests and deployments updated since
+// the given time, looking back 90 days on the first run.
+func (c *GitlabCollector) Collect(ctx context.Context, since time.Time) error {
+	if since.IsZero() {
+		since = time.Now().AddDate(0, 0, -90)
+	}
+
+	projects, err := c.projects()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, project := range projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.Printf("Collecting metrics for GitLab project: %s", project.PathWithNamespace)
+
+		repository := &models.Repository{
+			Provider:      "gitlab",
+			Repository:    project.PathWithNamespace,
+			DefaultBranch: project.DefaultBranch,
+			WebURL:        project.WebURL,
+			Archived:      project.Archived,
+			UpdatedAt:     time.Now(),
+		}
+		if err := c.metrics.saveRepository(repository); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save project %s: %w", project.PathWithNamespace, err))
+			continue
+		}
+
+		if err := c.collectPipelines(ctx, project, since); err != nil {
+			log.Printf("Error collecting GitLab pipelines for %s: %v", project.PathWithNamespace, err)
+			errs = append(errs, err)
+		}
+
+		if err := c.collectMergeRequests(project, since); err != nil {
+			log.Printf("Error collecting GitLab merge requests for %s: %v", project.PathWithNamespace, err)
+			errs = append(errs, err)
+		}
+
+		if err := c.collectDeployments(project, since); err != nil {
+			log.Printf("Error collecting GitLab deployments for %s: %v", project.PathWithNamespace, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// projects resolves GITLAB_PROJECTS and every project under GITLAB_GROUPS.
+func (c *GitlabCollector) projects() ([]gitlab.Project, error) {
+	seen := make(map[int]bool)
+	var projects []gitlab.Project
+
+	for _, path := range c.config.GitlabProjects {
+		project, err := c.client.GetProject(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project %s: %w", path, err)
+		}
+		if !seen[project.ID] {
+			seen[project.ID] = true
+			projects = append(projects, *project)
+		}
+	}
+
+	for _, group := range c.config.GitlabGroups {
+		groupProjects, err := c.client.GetGroupProjects(group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get projects of group %s: %w", group, err)
+		}
+		for _, project := range groupProjects {
+			if !seen[project.ID] {
+				seen[project.ID] = true
+				projects = append(projects, project)
+			}
+		}
+	}
+
+	return projects, nil
+}
+
+func (c *GitlabCollector) collectPipelines(ctx context.Context, project gitlab.Project, since time.Time) error {
+	pipelines, err := c.client.GetPipelines(project.ID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get pipelines: %w", err)
+	}
+
+	for _, summary := range pipelines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		pipeline, err := c.client.GetPipeline(project.ID, summary.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get pipeline %d: %w", summary.ID, err)
+		}
+
+		name := pipeline.Name
+		if name == "" {
+			name = "pipeline"
+		}
+
+		completedAt := pipeline.UpdatedAt
+		if pipeline.FinishedAt != nil {
+			completedAt = *pipeline.FinishedAt
+		}
+
+		workflow := &models.GithubWorkflow{
+			Provider:      "gitlab",
+			ExternalID:    fmt.Sprintf("%d", pipeline.ID),
+			Repository:    project.PathWithNamespace,
+			WorkflowName:  name,
+			Status:        normalizeStatus(pipeline.Status),
+			Duration:      pipeline.Duration,
+			HeadBranch:    pipeline.Ref,
+			HeadSHA:       pipeline.SHA,
+			QueueDuration: int(pipeline.QueuedDuration),
+			Cause:         pipeline.Source,
+			CreatedAt:     pipeline.CreatedAt,
+			CompletedAt:   completedAt,
+		}
+		if err := c.metrics.saveGithubWorkflow(workflow); err != nil {
+			return fmt.Errorf("failed to save pipeline: %w", err)
+		}
+
+		jobs, err := c.client.GetPipelineJobs(project.ID, pipeline.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get jobs of pipeline %d: %w", pipeline.ID, err)
+		}
+
+		for _, j := range jobs {
+			job := &models.CIJob{
+				Provider:       "gitlab",
+				Repository:     project.PathWithNamespace,
+				PipelineID:     workflow.ExternalID,
+				ExternalID:     fmt.Sprintf("%d", j.ID),
+				Name:           j.Name,
+				Stage:          j.Stage,
+				Status:         normalizeStatus(j.Status),
+				Duration:       int(j.Duration),
+				QueuedDuration: int(j.QueuedDuration),
+				CreatedAt:      j.CreatedAt,
+				StartedAt:      j.StartedAt,
+				FinishedAt:     j.FinishedAt,
+			}
+			if err := c.metrics.saveCIJob(job); err != nil {
+				return fmt.Errorf("failed to save job: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *GitlabCollector) collectMergeRequests(project gitlab.Project, since time.Time) error {
+	mergeRequests, err := c.client.GetMergeRequests(project.ID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get merge requests: %w", err)
+	}
+
+	identities := c.metrics.newIdentityRecorder()
+	for _, mr := range mergeRequests {
+		if err := identities.save("gitlab", mr.Author.Username, mr.Author.Name, ""); err != nil {
+			return fmt.Errorf("failed to save gitlab user: %w", err)
+		}
+
+		// GitLab distinguishes merged from closed; GitHub reports both as
+		// closed and sets merged_at.
+		state := mr.State
+		if state == "opened" || state == "locked" {
+			state = "open"
+		} else if state == "merged" {
+			state = "closed"
+		}
+
+		closedAt := mr.ClosedAt
+		if closedAt == nil {
+			closedAt = mr.MergedAt
+		}
+
+		pullRequest := &models.GithubPullRequest{
+			Provider:   "gitlab",
+			Repository: project.PathWithNamespace,
+			Number:     mr.IID,
+			Title:      mr.Title,
+			Author:     mr.Author.Username,
+			State:      state,
+			HeadBranch: mr.SourceBranch,
+			BaseBranch: mr.TargetBranch,
+			CreatedAt:  mr.CreatedAt,
+			UpdatedAt:  mr.UpdatedAt,
+			ClosedAt:   closedAt,
+			MergedAt:   mr.MergedAt,
+		}
+
+		if err := c.metrics.saveGithubPullRequest(pullRequest); err != nil {
+			return fmt.Errorf("failed to save merge request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *GitlabCollector) collectDeployments(project gitlab.Project, since time.Time) error {
+	deployments, err := c.client.GetDeployments(project.ID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get deployments: %w", err)
+	}
+
+	for _, d := range deployments {
+		deployment := &models.Deployment{
+			Provider:    "gitlab",
+			Repository:  project.PathWithNamespace,
+			ExternalID:  fmt.Sprintf("%d", d.ID),
+			Environment: d.Environment.Name,
+			Ref:         d.Ref,
+			SHA:         d.SHA,
+			Status:      normalizeStatus(d.Status),
+			CreatedAt:   d.CreatedAt,
+		}
+		if deployment.Status == "success" || deployment.Status == "failure" {
+			deployment.FinishedAt = &d.UpdatedAt
+		}
+
+		if err := c.metrics.saveDeployment(deployment); err != nil {
+			return fmt.Errorf("failed to save deployment: %w", err)
+		}
+	}
+
+	return nil
+}
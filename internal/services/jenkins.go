@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/jenkins"
+)
+
+type JenkinsCollector struct {
+	metrics *MetricsService
+	client  *jenkins.Client
+	config  *config.Config
+}
+
+func NewJenkinsCollector(metrics *MetricsService, cfg *config.Config) *JenkinsCollector {
+	return &JenkinsCollector{
+		metrics: metrics,
+		client:  jenkins.NewClient(cfg.JenkinsURL, cfg.JenkinsUsername, cfg.JenkinsToken),
+		config:  cfg,
+	}
+}
+
+func (c *JenkinsCollector) Name() string {
+	return "jenkins"
+}
+
+func (c *JenkinsCollector) Validate() error {
+	if c.config.JenkinsURL == "" || c.config.JenkinsUsername == "" || c.config.JenkinsToken == "" {
+		return fmt.Errorf("JENKINS_URL, JENKINS_USERNAME and JENKINS_TOKEN are required")
+	}
+	return nil
+}
+
+func (c *JenkinsCollector) Health(ctx context.Context) error {
+	_, err := c.client.GetInfo(ctx)
+	return err
+}
+
+// Collect stores builds started since the given time, looking back 90 days on
+// the first run, and refreshes the builds that were still running.
+func (c *JenkinsCollector) Collect(ctx context.Context, since time.Time) error {
+	if since.IsZero() {
+		since = time.Now().AddDate(0, 0, -90)
+	}
+
+	jobs, err := c.jobs()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.Printf("Collecting builds for Jenkins job: %s", job.FullName)
+		if err := c.collectBuilds(job, since); err != nil {
+			log.Printf("Error collecting Jenkins builds for %s: %v", job.FullName, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// jobs walks JENKINS_JOBS, or the whole instance when it is empty.
+func (c *JenkinsCollector) jobs() ([]jenkins.Job, error) {
+	roots := c.config.JenkinsJobs
+	if len(roots) == 0 {
+		roots = []string{""}
+	}
+
+	seen := make(map[string]bool)
+	var jobs []jenkins.Job
+	for _, root := range roots {
+		found, err := c.client.GetJobs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get jobs under %q: %w", root, err)
+		}
+		for _, job := range found {
+			if !seen[job.FullName] {
+				seen[job.FullName] = true
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+func (c *JenkinsCollector) collectBuilds(job jenkins.Job, since time.Time) error {
+	// A multibranch project is treated as the repository and each of its
+	// branch jobs as a workflow; other jobs stand on their own.
+	repository, workflowName, branch := job.FullName, job.Name, ""
+	if job.IsMultibranch() {
+		var err error
+		branch, err = url.PathUnescape(job.Name)
+		if err != nil {
+			branch = job.Name
+		}
+		repository, workflowName = job.Parent, branch
+	}
+
+	// Builds still running at the last collection are fetched again.
+	from, err := c.metrics.collectFrom("jenkins", repository, workflowName, since)
+	if err != nil {
+		return err
+	}
+	builds, err := c.client.GetBuilds(job.FullName, from)
+	if err != nil {
+		return fmt.Errorf("failed to get builds: %w", err)
+	}
+
+	for _, build := range builds {
+		startedAt := build.StartedAt()
+
+		workflow := &models.GithubWorkflow{
+			Provider:      "jenkins",
+			ExternalID:    fmt.Sprintf("%s/%s#%d", repository, workflowName, build.Number),
+			Repository:    repository,
+			WorkflowName:  workflowName,
+			Status:        normalizeStatus(build.Status()),
+			Duration:      build.GetDurationSeconds(),
+			HeadBranch:    branch,
+			QueueDuration: build.QueueDurationSeconds(),
+			Cause:         build.Cause(),
+			CreatedAt:     startedAt,
+			CompletedAt:   startedAt.Add(time.Duration(build.Duration) * time.Millisecond),
+		}
+
+		if revision := build.Revision(); revision != nil {
+			workflow.HeadSHA = revision.SHA1
+			if workflow.HeadBranch == "" && len(revision.Branch) > 0 {
+				workflow.HeadBranch = revision.Branch[0].Name
+			}
+		}
+
+		if err := c.metrics.saveGithubWorkflow(workflow); err != nil {
+			return fmt.Errorf("failed to save build: %w", err)
+		}
+	}
+
+	return nil
+}
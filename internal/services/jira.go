@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/jira"
+)
+
+type JiraCollector struct {
+	metrics *MetricsService
+	client  *jira.Client
+	config  *config.Config
+}
+
+func NewJiraCollector(metrics *MetricsService, cfg *config.Config) *JiraCollector {
+	return &JiraCollector{
+		metrics: metrics,
+		client:  jira.NewClient(cfg.JiraURL, cfg.JiraEmail, cfg.JiraToken),
+		config:  cfg,
+	}
+}
+
+func (c *JiraCollector) Name() string {
+	return "jira"
+}
+
+func (c *JiraCollector) Validate() error {
+	if c.config.JiraURL == "" || c.config.JiraEmail == "" || c.config.JiraToken == "" {
+		return fmt.Errorf("JIRA_URL, JIRA_EMAIL and JIRA_TOKEN are required")
+	}
+	return nil
+}
+
+func (c *JiraCollector) Health(ctx context.Context) error {
+	_, err := c.client.GetCurrentUser(ctx)
+	return err
+}
+
+// Collect stores the tickets matched by JIRA_JQL that were updated since the
+// given time, or all of them when since is zero.
+func (c *JiraCollector) Collect(ctx context.Context, since time.Time) error {
+	jql := c.config.JiraJQL
+	if !since.IsZero() {
+		updated := fmt.Sprintf(`updated >= "%s"`, since.Format("2006/01/02 15:04"))
+		if jql == "" {
+			jql = updated
+		} else {
+			jql = fmt.Sprintf("(%s) AND %s", jql, updated)
+		}
+	}
+
+	log.Printf("Collecting Jira tickets matching: %s", jql)
+
+	return c.collectTickets(jql)
+}
+
+func (c *JiraCollector) collectTickets(jql string) error {
+	statuses, err := c.client.GetStatuses()
+	if err != nil {
+		return fmt.Errorf("failed to get jira statuses: %w", err)
+	}
+
+	categories := make(map[string]string)
+	for _, status := range statuses {
+		categories[status.ID] = normalizeStatusCategory(status.StatusCategory.Key)
+	}
+
+	issues, err := c.client.SearchIssues(jql)
+	if err != nil {
+		return fmt.Errorf("failed to search jira issues: %w", err)
+	}
+
+	sprints := make(map[int]bool)
+	identities := c.metrics.newIdentityRecorder()
+
+	for _, issue := range issues {
+		assignee, assigneeName := "", ""
+		if user := issue.Fields.Assignee; user != nil {
+			assignee, assigneeName = user.ID(), user.DisplayName
+			if err := identities.save("jira", user.ID(), user.DisplayName, user.EmailAddress); err != nil {
+				return fmt.Errorf("failed to save jira user: %w", err)
+			}
+		}
+
+		components := make([]string, 0, len(issue.Fields.Components))
+		for _, component := range issue.Fields.Components {
+			components = append(components, component.Name)
+		}
+
+		ticket := &models.JiraTicket{
+			Source:         "jira",
+			TicketKey:      issue.Key,
+			Summary:        issue.Fields.Summary,
+			Status:         issue.Fields.Status.Name,
+			StatusCategory: normalizeStatusCategory(issue.Fields.Status.StatusCategory.Key),
+			Priority:       issue.Fields.Priority.Name,
+			Assignee:       assignee,
+			AssigneeName:   assigneeName,
+			Estimate:       issue.Fields.Float(c.config.JiraEstimateField),
+			CreatedAt:      issue.Fields.Created,
+			UpdatedAt:      issue.Fields.Updated,
+			ResolvedAt:     issue.Fields.Resolved,
+			Labels:         issue.Fields.Labels,
+			Components:     components,
+		}
+
+		for _, history := range issue.Changelog.Histories {
+			author := ""
+			if user := history.Author; user != nil {
+				author = user.ID()
+				if err := identities.save("jira", user.ID(), user.DisplayName, user.EmailAddress); err != nil {
+					return fmt.Errorf("failed to save jira user: %w", err)
+				}
+			}
+			for _, item := range history.Items {
+				if item.Field != "status" {
+					continue
+				}
+				ticket.Transitions = append(ticket.Transitions, models.TicketTransition{
+					FromStatus:     item.FromString,
+					ToStatus:       item.ToString,
+					ToCategory:     categories[item.To],
+					Author:         author,
+					TransitionedAt: history.Created,
+				})
+			}
+		}
+
+		if sprint := issue.Fields.Sprint(c.config.JiraSprintField); sprint != nil {
+			ticket.SprintID = fmt.Sprintf("%d", sprint.ID)
+
+			if !sprints[sprint.ID] {
+				sprints[sprint.ID] = true
+				if err := c.metrics.saveSprint(&models.Sprint{
+					Source:      "jira",
+					ExternalID:  ticket.SprintID,
+					Name:        sprint.Name,
+					State:       sprint.State,
+					StartsAt:    sprint.StartDate,
+					EndsAt:      sprint.EndDate,
+					CompletedAt: sprint.CompleteDate,
+				}); err != nil {
+					return fmt.Errorf("failed to save jira sprint: %w", err)
+				}
+			}
+		}
+
+		if err := c.metrics.saveJiraTicket(ticket); err != nil {
+			return fmt.Errorf("failed to save jira ticket: %w", err)
+		}
+	}
+
+	return nil
+}
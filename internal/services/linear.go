@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/linear"
+)
+
+type LinearCollector struct {
+	metrics *MetricsService
+	client  *linear.Client
+	config  *config.Config
+}
+
+func NewLinearCollector(metrics *MetricsService, cfg *config.Config) *LinearCollector {
+	return &LinearCollector{
+		metrics: metrics,
+		client:  linear.NewClient(cfg.LinearURL, cfg.LinearToken),
+		config:  cfg,
+	}
+}
+
+func (c *LinearCollector) Name() string {
+	return "linear"
+}
+
+func (c *LinearCollector) Validate() error {
+	if c.config.LinearURL == "" || c.config.LinearToken == "" {
+		return fmt.Errorf("LINEAR_URL and LINEAR_TOKEN are required")
+	}
+	return nil
+}
+
+func (c *LinearCollector) Health(ctx context.Context) error {
+	_, err := c.client.GetViewer(ctx)
+	return err
+}
+
+// Collect stores every cycle and the issues updated since the given time, or
+// all of them when since is zero. Issues are stored as tickets with source
+// linear and cycles as sprints.
+func (c *LinearCollector) Collect(ctx context.Context, since time.Time) error {
+	cycles, err := c.client.GetCycles(c.config.LinearTeams)
+	if err != nil {
+		return fmt.Errorf("failed to get linear cycles: %w", err)
+	}
+
+	for _, cycle := range cycles {
+		if err := c.saveCycle(cycle); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Collecting Linear issues updated since %s", since.Format(time.RFC3339))
+
+	issues, err := c.client.GetIssues(c.config.LinearTeams, since)
+	if err != nil {
+		return fmt.Errorf("failed to get linear issues: %w", err)
+	}
+
+	identities := c.metrics.newIdentityRecorder()
+	for _, issue := range issues {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.saveIssue(issue, identities); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveCycle stores a cycle with the sprint states Jira uses: future, active
+// and closed.
+func (c *LinearCollector) saveCycle(cycle linear.Cycle) error {
+	state := "future"
+	if cycle.CompletedAt != nil {
+		state = "closed"
+	} else if time.Now().After(cycle.StartsAt) {
+		state = "active"
+	}
+
+	sprint := &models.Sprint{
+		Source:      "linear",
+		ExternalID:  cycle.ID,
+		Name:        cycle.DisplayName(),
+		State:       state,
+		StartsAt:    &cycle.StartsAt,
+		EndsAt:      &cycle.EndsAt,
+		CompletedAt: cycle.CompletedAt,
+	}
+	if err := c.metrics.saveSprint(sprint); err != nil {
+		return fmt.Errorf("failed to save linear cycle: %w", err)
+	}
+
+	return nil
+}
+
+func (c *LinearCollector) saveIssue(issue linear.Issue, identities *identityRecorder) error {
+	assignee, assigneeName := "", ""
+	if user := issue.Assignee; user != nil {
+		assignee, assigneeName = user.ID, user.Name
+		if err := identities.save("linear", user.ID, user.Name, user.Email); err != nil {
+			return fmt.Errorf("failed to save linear user: %w", err)
+		}
+	}
+
+	labels := make([]string, 0, len(issue.Labels.Nodes))
+	for _, label := range issue.Labels.Nodes {
+		labels = append(labels, label.Name)
+	}
+
+	ticket := &models.JiraTicket{
+		Source:         "linear",
+		TicketKey:      issue.Identifier,
+		Summary:        issue.Title,
+		Status:         issue.State.Name,
+		StatusCategory: normalizeStatusCategory(issue.State.Type),
+		Priority:       issue.PriorityLabel,
+		Assignee:       assignee,
+		AssigneeName:   assigneeName,
+		Estimate:       issue.Estimate,
+		CreatedAt:      issue.CreatedAt,
+		UpdatedAt:      issue.UpdatedAt,
+		ResolvedAt:     issue.CompletedAt,
+		Labels:         labels,
+	}
+
+	if issue.Cycle != nil {
+		ticket.SprintID = issue.Cycle.ID
+	}
+
+	for _, entry := range issue.History.Nodes {
+		if entry.ToState == nil {
+			continue
+		}
+
+		transition := models.TicketTransition{
+			ToStatus:       entry.ToState.Name,
+			ToCategory:     normalizeStatusCategory(entry.ToState.Type),
+			TransitionedAt: entry.CreatedAt,
+		}
+		if entry.FromState != nil {
+			transition.FromStatus = entry.FromState.Name
+		}
+		if user := entry.Actor; user != nil {
+			transition.Author = user.ID
+			if err := identities.save("linear", user.ID, user.Name, user.Email); err != nil {
+				return fmt.Errorf("failed to save linear user: %w", err)
+			}
+		}
+		ticket.Transitions = append(ticket.Transitions, transition)
+	}
+
+	if err := c.metrics.saveJiraTicket(ticket); err != nil {
+		return fmt.Errorf("failed to save linear issue: %w", err)
+	}
+
+	return nil
+}
@@ -3,170 +3,489 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"code-pulse/internal/models"
-	"code-pulse/pkg/github"
-	"code-pulse/pkg/jira"
-	"code-pulse/pkg/sonarqube"
+
+	"github.com/lib/pq"
 )
 
 type MetricsService struct {
-	db             *sql.DB
-	githubClient   *github.Client
-	sonarClient    *sonarqube.Client
-	jiraClient     *jira.Client
+	db *sql.DB
 }
 
-func NewMetricsService(db *sql.DB, githubToken, sonarURL, sonarToken, jiraURL, jiraEmail, jiraToken string) *MetricsService {
-	return &MetricsService{
-		db:             db,
-		githubClient:   github.NewClient(githubToken),
-		sonarClient:    sonarqube.NewClient(sonarURL, sonarToken),
-		jiraClient:     jira.NewClient(jiraURL, jiraEmail, jiraToken),
-	}
+func NewMetricsService(db *sql.DB) *MetricsService {
+	return &MetricsService{db: db}
 }
 
-func (s *MetricsService) CollectGithubMetrics(owner, repo string) error {
-	runs, err := s.githubClient.GetWorkflowRuns(owner, repo, 0)
+// LastSuccessfulRun returns when the last successful run of a collector
+// started, or the zero time if it has never completed.
+func (s *MetricsService) LastSuccessfulRun(collector string) (time.Time, error) {
+	var startedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT MAX(started_at)
+		FROM collection_runs
+		WHERE collector = $1 AND status = 'success'`, collector).Scan(&startedAt)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow runs: %w", err)
+		return time.Time{}, err
 	}
 
-	for _, run := range runs {
-		if err := s.saveGithubWorkflowRun(owner, repo, run); err != nil {
-			return fmt.Errorf("failed to save workflow run: %w", err)
-		}
+	return startedAt.Time, nil
+}
+
+func (s *MetricsService) RecordCollectionRun(run *models.CollectionRun) error {
+	query := `
+		INSERT INTO collection_runs (collector, started_at, finished_at, status, error)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.Exec(query, run.Collector, run.StartedAt, run.FinishedAt, run.Status, run.Error)
+	return err
+}
+
+// normalizeStatus maps provider-specific run and deployment states onto the
+// GitHub vocabulary (success, failure, cancelled, ...) used across tables.
+func normalizeStatus(status string) string {
+	switch status {
+	case "failed", "error", "FAILURE", "UNSTABLE", "FAILED", "ERROR":
+		return "failure"
+	case "canceled", "ABORTED", "STOPPED":
+		return "cancelled"
+	case "SUCCESS", "SUCCESSFUL":
+		return "success"
+	case "IN_PROGRESS":
+		return "in_progress"
+	case "PENDING":
+		return "queued"
+	case "NOT_BUILT":
+		return "skipped"
+	case "inactive":
+		// GitHub marks superseded successful deployments inactive.
+		return "success"
+	default:
+		return status
 	}
+}
 
-	return nil
+// normalizeStatusCategory maps Jira status categories and Linear state types
+// onto todo, in_progress and done. Canceled Linear issues keep their own
+// category so that they count neither as open nor as delivered.
+func normalizeStatusCategory(category string) string {
+	switch category {
+	case "new", "triage", "backlog", "unstarted":
+		return "todo"
+	case "indeterminate", "started":
+		return "in_progress"
+	case "done", "completed":
+		return "done"
+	default:
+		return category
+	}
 }
 
-func (s *MetricsService) CollectGithubMetricsByWorkflow(owner, repo, workflowName string) error {
-	runs, err := s.githubClient.GetWorkflowRunsByName(owner, repo, workflowName)
+// saveGithubWorkflow upserts a run by provider and run id. A run stored before
+// run ids were kept is claimed first, so that it is updated rather than stored
+// twice.
+func (s *MetricsService) saveGithubWorkflow(workflow *models.GithubWorkflow) error {
+	_, err := s.db.Exec(`
+		UPDATE github_workflows SET external_id = $2
+		WHERE provider = $1 AND external_id = ''
+		AND repository = $3 AND workflow_name = $4 AND created_at = $5
+		AND NOT EXISTS (SELECT 1 FROM github_workflows WHERE provider = $1 AND external_id = $2)`,
+		workflow.Provider, workflow.ExternalID, workflow.Repository, workflow.WorkflowName, workflow.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow runs for %s: %w", workflowName, err)
+		return err
 	}
 
-	for _, run := range runs {
-		if err := s.saveGithubWorkflowRun(owner, repo, run); err != nil {
-			return fmt.Errorf("failed to save workflow run: %w", err)
-		}
-	}
+	query := `
+		INSERT INTO github_workflows (provider, external_id, repository, workflow_name, status, duration, head_branch, head_sha, queue_duration, cause, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (provider, external_id) WHERE external_id <> '' DO UPDATE SET
+			status = $5, duration = $6, head_sha = $8, queue_duration = $9, completed_at = $12`
 
-	return nil
+	_, err = s.db.Exec(query, workflow.Provider, workflow.ExternalID, workflow.Repository, workflow.WorkflowName,
+		workflow.Status, workflow.Duration, workflow.HeadBranch, workflow.HeadSHA, workflow.QueueDuration, workflow.Cause,
+		workflow.CreatedAt, workflow.CompletedAt)
+	return err
 }
 
-func (s *MetricsService) saveGithubWorkflowRun(owner, repo string, run github.WorkflowRun) error {
-	duration := run.GetDurationSeconds()
+// unfinishedLookback bounds how far back runs left queued or in progress are
+// picked up again, so that a run the CI server lost does not pin every
+// collection to its start.
+const unfinishedLookback = 7 * 24 * time.Hour
 
-	status := run.Status
-	if run.Conclusion != "" {
-		status = run.Conclusion
+// collectFrom returns where a collection of a provider's runs must start to
+// also refetch the runs of a repository, or one of its workflows, that were
+// still queued or in progress when they were stored: the start of the oldest
+// one, or since when there are none.
+func (s *MetricsService) collectFrom(provider, repository, workflowName string, since time.Time) (time.Time, error) {
+	var oldest sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT MIN(created_at) FROM github_workflows
+		WHERE provider = $1 AND repository = $2
+		AND ($3 = '' OR workflow_name = $3)
+		AND status IN ('queued', 'in_progress')
+		AND created_at >= $4`,
+		provider, repository, workflowName, since.Add(-unfinishedLookback)).Scan(&oldest)
+	if err != nil {
+		return since, fmt.Errorf("failed to look up unfinished runs: %w", err)
 	}
+	if oldest.Valid && oldest.Time.Before(since) {
+		return oldest.Time, nil
+	}
+	return since, nil
+}
 
-	workflow := &models.GithubWorkflow{
-		Repository:   fmt.Sprintf("%s/%s", owner, repo),
-		WorkflowName: run.Name,
-		Status:       status,
-		Duration:     duration,
-		CreatedAt:    run.CreatedAt,
-		CompletedAt:  run.UpdatedAt,
+func (s *MetricsService) saveGithubPullRequest(pr *models.GithubPullRequest) error {
+	query := `
+		INSERT INTO github_pull_requests (provider, repository, number, title, author, state, head_branch, base_branch, created_at, updated_at, closed_at, merged_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (provider, repository, number) DO UPDATE SET
+			title = $4, state = $6, head_branch = $7, base_branch = $8, updated_at = $10, closed_at = $11, merged_at = $12`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	return s.saveGithubWorkflow(workflow)
-}
+	if _, err := tx.Exec(query, pr.Provider, pr.Repository, pr.Number, pr.Title, pr.Author, pr.State, pr.HeadBranch,
+		pr.BaseBranch, pr.CreatedAt, pr.UpdatedAt, pr.ClosedAt, pr.MergedAt); err != nil {
+		return err
+	}
 
-func (s *MetricsService) CollectSonarqubeMetrics(projectKey string) error {
-	metricKeys := []string{
-		"ncloc", "coverage", "duplicated_lines_density",
-		"bugs", "vulnerabilities", "code_smells",
-		"reliability_rating", "security_rating", "sqale_rating",
+	keys := ticketKeys(ticketKeyPattern, pr.Title)
+	keys = append(keys, ticketKeys(branchTicketKeyPattern, pr.HeadBranch)...)
+	if err := saveTicketLinks(tx, "pull_request", pr.Provider, pr.Repository, strconv.Itoa(pr.Number), keys); err != nil {
+		return err
 	}
 
-	metrics, err := s.sonarClient.GetProjectMetrics(projectKey, metricKeys)
+	return tx.Commit()
+}
+
+func (s *MetricsService) savePullRequestActivity(activity *models.PullRequestActivity) error {
+	query := `
+		INSERT INTO pull_request_activities (provider, repository, number, action, author, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider, repository, number, action, author, created_at) DO NOTHING`
+
+	_, err := s.db.Exec(query, activity.Provider, activity.Repository, activity.Number, activity.Action,
+		activity.Author, activity.CreatedAt)
+	return err
+}
+
+func (s *MetricsService) saveRepository(repository *models.Repository) error {
+	query := `
+		INSERT INTO repositories (provider, repository, default_branch, web_url, archived, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider, repository) DO UPDATE SET
+			default_branch = $3, web_url = $4, archived = $5, updated_at = $6`
+
+	_, err := s.db.Exec(query, repository.Provider, repository.Repository, repository.DefaultBranch,
+		repository.WebURL, repository.Archived, repository.UpdatedAt)
+	return err
+}
+
+func (s *MetricsService) saveCIJob(job *models.CIJob) error {
+	query := `
+		INSERT INTO ci_jobs (provider, repository, pipeline_id, external_id, name, stage, status, duration, queued_duration, created_at, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (provider, repository, external_id) DO UPDATE SET
+			status = $7, duration = $8, queued_duration = $9, started_at = $11, finished_at = $12`
+
+	_, err := s.db.Exec(query, job.Provider, job.Repository, job.PipelineID, job.ExternalID, job.Name, job.Stage,
+		job.Status, job.Duration, job.QueuedDuration, job.CreatedAt, job.StartedAt, job.FinishedAt)
+	return err
+}
+
+func (s *MetricsService) saveDeployment(deployment *models.Deployment) error {
+	query := `
+		INSERT INTO deployments (provider, repository, external_id, environment, ref, sha, status, created_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (provider, repository, external_id) DO UPDATE SET
+			status = $7, finished_at = $9`
+
+	_, err := s.db.Exec(query, deployment.Provider, deployment.Repository, deployment.ExternalID,
+		deployment.Environment, deployment.Ref, deployment.SHA, deployment.Status,
+		deployment.CreatedAt, deployment.FinishedAt)
+	return err
+}
+
+func (s *MetricsService) saveIncident(incident *models.Incident) error {
+	query := `
+		INSERT INTO incidents (source, external_id, number, title, status, urgency, service_id, service_name, triggered_at, acknowledged_at, resolved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (source, external_id) DO UPDATE SET
+			title = $4, status = $5, urgency = $6, service_id = $7, service_name = $8, acknowledged_at = $10, resolved_at = $11`
+
+	_, err := s.db.Exec(query, incident.Source, incident.ExternalID, incident.Number, incident.Title, incident.Status,
+		incident.Urgency, incident.ServiceID, incident.ServiceName, incident.TriggeredAt, incident.AcknowledgedAt,
+		incident.ResolvedAt)
+	return err
+}
+
+// unresolvedIncidents returns the external IDs of the stored incidents of a
+// source that have no resolution time.
+func (s *MetricsService) unresolvedIncidents(source string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT external_id FROM incidents WHERE source = $1 AND resolved_at IS NULL`, source)
 	if err != nil {
-		return fmt.Errorf("failed to get sonarqube metrics: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	for _, metric := range metrics {
-		sonarMetric := &models.SonarqubeMetric{
-			ProjectKey:  projectKey,
-			MetricKey:   metric.Metric,
-			Value:       metric.Value,
-			Component:   metric.Component,
-			CollectedAt: time.Now(),
-		}
-
-		if err := s.saveSonarqubeMetric(sonarMetric); err != nil {
-			return fmt.Errorf("failed to save sonarqube metric: %w", err)
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
 	}
+	return ids, rows.Err()
+}
 
-	return nil
+// saveIncidentService records a service name. A non-empty repository replaces
+// the stored mapping; an empty one keeps it.
+func (s *MetricsService) saveIncidentService(service *models.IncidentService) error {
+	query := `
+		INSERT INTO incident_services (source, service_id, service_name, repository, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (source, service_id) DO UPDATE SET
+			service_name = $3,
+			repository = CASE WHEN EXCLUDED.repository = '' THEN incident_services.repository ELSE EXCLUDED.repository END,
+			updated_at = $5`
+
+	_, err := s.db.Exec(query, service.Source, service.ServiceID, service.ServiceName, service.Repository, service.UpdatedAt)
+	return err
 }
 
-func (s *MetricsService) CollectJiraMetrics(jql string) error {
-	issues, err := s.jiraClient.SearchIssues(jql)
+func (s *MetricsService) saveGithubCommit(commit *models.GithubCommit) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to search jira issues: %w", err)
+		return err
 	}
+	defer tx.Rollback()
 
-	for _, issue := range issues {
-		assignee := ""
-		if issue.Fields.Assignee != nil {
-			assignee = issue.Fields.Assignee.DisplayName
-		}
+	query := `
+		INSERT INTO github_commits (repository, sha, author, author_email, message, committed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (repository, sha) DO NOTHING`
 
-		ticket := &models.JiraTicket{
-			TicketKey:  issue.Key,
-			Summary:    issue.Fields.Summary,
-			Status:     issue.Fields.Status.Name,
-			Priority:   issue.Fields.Priority.Name,
-			Assignee:   assignee,
-			CreatedAt:  issue.Fields.Created,
-			UpdatedAt:  issue.Fields.Updated,
-			ResolvedAt: issue.Fields.Resolved,
-		}
+	if _, err := tx.Exec(query, commit.Repository, commit.SHA, commit.Author, commit.AuthorEmail,
+		commit.Message, commit.CommittedAt); err != nil {
+		return err
+	}
+
+	fileQuery := `
+		INSERT INTO github_commit_files (repository, sha, path, additions, deletions, committed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (repository, sha, path) DO NOTHING`
 
-		if err := s.saveJiraTicket(ticket); err != nil {
-			return fmt.Errorf("failed to save jira ticket: %w", err)
+	for _, file := range commit.Files {
+		if _, err := tx.Exec(fileQuery, commit.Repository, commit.SHA, file.Path,
+			file.Additions, file.Deletions, commit.CommittedAt); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	keys := ticketKeys(ticketKeyPattern, commit.Message)
+	if err := saveTicketLinks(tx, "commit", "github", commit.Repository, commit.SHA, keys); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *MetricsService) saveGithubWorkflow(workflow *models.GithubWorkflow) error {
+func (s *MetricsService) saveSonarqubeMetric(metric *models.SonarqubeMetric) error {
 	query := `
-		INSERT INTO github_workflows (repository, workflow_name, status, duration, created_at, completed_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (repository, workflow_name, created_at) DO NOTHING`
+		INSERT INTO sonarqube_metrics (project_key, branch, pull_request, metric_key, value, numeric_value, component, qualifier, path, collected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (project_key, branch, pull_request, metric_key, component, collected_at) DO NOTHING`
 	
-	_, err := s.db.Exec(query, workflow.Repository, workflow.WorkflowName, workflow.Status,
-		workflow.Duration, workflow.CreatedAt, workflow.CompletedAt)
+	_, err := s.db.Exec(query, metric.ProjectKey, metric.Branch, metric.PullRequest, metric.MetricKey,
+		metric.Value, metric.NumericValue, metric.Component, metric.Qualifier, metric.Path, metric.CollectedAt)
 	return err
 }
 
-func (s *MetricsService) saveSonarqubeMetric(metric *models.SonarqubeMetric) error {
+func (s *MetricsService) saveSonarqubeBranch(branch *models.SonarqubeBranch) error {
+	query := `
+		INSERT INTO sonarqube_branches (project_key, name, is_main, type, quality_gate_status, analysis_date)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (project_key, name) DO UPDATE SET
+			is_main = $3, type = $4, quality_gate_status = $5, analysis_date = $6`
+
+	_, err := s.db.Exec(query, branch.ProjectKey, branch.Name, branch.IsMain, branch.Type,
+		branch.QualityGateStatus, branch.AnalysisDate)
+	return err
+}
+
+func (s *MetricsService) saveSonarqubePullRequest(pr *models.SonarqubePullRequest) error {
 	query := `
-		INSERT INTO sonarqube_metrics (project_key, metric_key, value, component, collected_at)
+		INSERT INTO sonarqube_pull_requests (project_key, repository, pull_request, title, branch, base_branch, quality_gate_status, analysis_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (project_key, pull_request) DO UPDATE SET
+			repository = $2, title = $4, branch = $5, base_branch = $6, quality_gate_status = $7, analysis_date = $8`
+
+	_, err := s.db.Exec(query, pr.ProjectKey, pr.Repository, pr.PullRequest, pr.Title, pr.Branch,
+		pr.BaseBranch, pr.QualityGateStatus, pr.AnalysisDate)
+	return err
+}
+
+func (s *MetricsService) saveSonarqubeQualityGate(gate *models.SonarqubeQualityGate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO sonarqube_quality_gates (project_key, branch, pull_request, status, collected_at)
 		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (project_key, metric_key, component, collected_at) DO NOTHING`
-	
-	_, err := s.db.Exec(query, metric.ProjectKey, metric.MetricKey, metric.Value,
-		metric.Component, metric.CollectedAt)
+		RETURNING id`
+
+	if err := tx.QueryRow(query, gate.ProjectKey, gate.Branch, gate.PullRequest,
+		gate.Status, gate.CollectedAt).Scan(&gate.ID); err != nil {
+		return err
+	}
+
+	conditionQuery := `
+		INSERT INTO sonarqube_quality_gate_conditions (quality_gate_id, metric_key, comparator, error_threshold, actual_value, status)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, condition := range gate.Conditions {
+		if _, err := tx.Exec(conditionQuery, gate.ID, condition.MetricKey, condition.Comparator,
+			condition.ErrorThreshold, condition.ActualValue, condition.Status); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *MetricsService) saveSonarqubeIssue(issue *models.SonarqubeIssue) error {
+	query := `
+		INSERT INTO sonarqube_issues (issue_key, project_key, rule, severity, type, file, line, status, resolution, author, created_at, updated_at, closed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (issue_key) DO UPDATE SET
+			severity = $4, type = $5, file = $6, line = $7, status = $8, resolution = $9, updated_at = $12, closed_at = $13`
+
+	_, err := s.db.Exec(query, issue.IssueKey, issue.ProjectKey, issue.Rule, issue.Severity, issue.Type,
+		issue.File, issue.Line, issue.Status, issue.Resolution, issue.Author,
+		issue.CreatedAt, issue.UpdatedAt, issue.ClosedAt)
+	return err
+}
+
+func (s *MetricsService) saveSonarqubeHotspot(hotspot *models.SonarqubeHotspot) error {
+	query := `
+		INSERT INTO sonarqube_hotspots (hotspot_key, project_key, rule, security_category, vulnerability_probability, file, line, status, resolution, created_at, updated_at, closed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (hotspot_key) DO UPDATE SET
+			vulnerability_probability = $5, file = $6, line = $7, status = $8, resolution = $9, updated_at = $11, closed_at = $12`
+
+	_, err := s.db.Exec(query, hotspot.HotspotKey, hotspot.ProjectKey, hotspot.Rule, hotspot.SecurityCategory,
+		hotspot.VulnerabilityProbability, hotspot.File, hotspot.Line, hotspot.Status, hotspot.Resolution,
+		hotspot.CreatedAt, hotspot.UpdatedAt, hotspot.ClosedAt)
 	return err
 }
 
 func (s *MetricsService) saveJiraTicket(ticket *models.JiraTicket) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO jira_tickets (ticket_key, summary, status, priority, assignee, created_at, updated_at, resolved_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (ticket_key) DO UPDATE SET
-			summary = $2, status = $3, priority = $4, assignee = $5, updated_at = $7, resolved_at = $8`
+		INSERT INTO jira_tickets (source, ticket_key, summary, status, status_category, priority, assignee, assignee_name, estimate, sprint_id, created_at, updated_at, resolved_at, labels, components)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $15, $8, $9, $10, $11, $12, $13, COALESCE($14::TEXT[], '{}'))
+		ON CONFLICT (source, ticket_key) DO UPDATE SET
+			summary = $3, status = $4, status_category = $5, priority = $6, assignee = $7, assignee_name = $15,
+			estimate = $8, sprint_id = $9, updated_at = $11, resolved_at = $12, labels = $13,
+			components = EXCLUDED.components`
 	
-	_, err := s.db.Exec(query, ticket.TicketKey, ticket.Summary, ticket.Status,
-		ticket.Priority, ticket.Assignee, ticket.CreatedAt, ticket.UpdatedAt, ticket.ResolvedAt)
+	if _, err := tx.Exec(query, ticket.Source, ticket.TicketKey, ticket.Summary, ticket.Status, ticket.StatusCategory,
+		ticket.Priority, ticket.Assignee, ticket.Estimate, ticket.SprintID, ticket.CreatedAt, ticket.UpdatedAt,
+		ticket.ResolvedAt, pq.Array(ticket.Labels), pq.Array(ticket.Components), ticket.AssigneeName); err != nil {
+		return err
+	}
+
+	transitionQuery := `
+		INSERT INTO ticket_transitions (source, ticket_key, from_status, to_status, to_category, author, transitioned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (source, ticket_key, to_status, transitioned_at) DO UPDATE SET author = EXCLUDED.author`
+
+	for _, transition := range ticket.Transitions {
+		if _, err := tx.Exec(transitionQuery, ticket.Source, ticket.TicketKey, transition.FromStatus,
+			transition.ToStatus, transition.ToCategory, transition.Author, transition.TransitionedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *MetricsService) saveSprint(sprint *models.Sprint) error {
+	query := `
+		INSERT INTO sprints (source, external_id, name, state, starts_at, ends_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (source, external_id) DO UPDATE SET
+			name = $3, state = $4, starts_at = $5, ends_at = $6, completed_at = $7`
+
+	_, err := s.db.Exec(query, sprint.Source, sprint.ExternalID, sprint.Name, sprint.State,
+		sprint.StartsAt, sprint.EndsAt, sprint.CompletedAt)
+	return err
+}
+
+// saveIdentity records an account seen in a system. Accounts with an email
+// are linked to the person with that email, who is created when unknown;
+// links made by hand are left alone.
+func (s *MetricsService) saveIdentity(identity *models.Identity) error {
+	if identity.AccountID == "" {
+		return nil
+	}
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+
+	if email != "" {
+		if _, err := s.db.Exec(`
+			INSERT INTO people (name, email)
+			VALUES ($1, $2)
+			ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name
+			WHERE people.name = '' AND EXCLUDED.name <> ''`, identity.DisplayName, email); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO identities (system, account_id, display_name, email, person_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM people WHERE email = NULLIF($4, '')))
+		ON CONFLICT (system, account_id) DO UPDATE SET
+			display_name = CASE WHEN $3 = '' THEN identities.display_name ELSE $3 END,
+			email = CASE WHEN $4 = '' THEN identities.email ELSE $4 END,
+			person_id = CASE WHEN identities.manual THEN identities.person_id
+				ELSE COALESCE(EXCLUDED.person_id, identities.person_id) END,
+			last_seen_at = NOW()`
+
+	_, err := s.db.Exec(query, identity.System, identity.AccountID, identity.DisplayName, email)
 	return err
-}
\ No newline at end of file
+}
+
+// identityRecorder saves each account once per collection run.
+type identityRecorder struct {
+	metrics *MetricsService
+	seen    map[string]bool
+}
+
+func (s *MetricsService) newIdentityRecorder() *identityRecorder {
+	return &identityRecorder{metrics: s, seen: make(map[string]bool)}
+}
+
+func (r *identityRecorder) save(system, accountID, displayName, email string) error {
+	key := system + "/" + accountID
+	if r.seen[key] {
+		return nil
+	}
+	r.seen[key] = true
+	return r.metrics.saveIdentity(&models.Identity{System: system, AccountID: accountID,
+		DisplayName: displayName, Email: email})
+}
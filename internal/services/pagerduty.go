@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/pagerduty"
+)
+
+type PagerdutyCollector struct {
+	metrics *MetricsService
+	client  *pagerduty.Client
+	config  *config.Config
+}
+
+func NewPagerdutyCollector(metrics *MetricsService, cfg *config.Config) *PagerdutyCollector {
+	return &PagerdutyCollector{
+		metrics: metrics,
+		client:  pagerduty.NewClient(cfg.PagerdutyURL, cfg.PagerdutyToken),
+		config:  cfg,
+	}
+}
+
+func (c *PagerdutyCollector) Name() string {
+	return "pagerduty"
+}
+
+func (c *PagerdutyCollector) Validate() error {
+	if c.config.PagerdutyURL == "" || c.config.PagerdutyToken == "" {
+		return fmt.Errorf("PAGERDUTY_URL and PAGERDUTY_TOKEN are required")
+	}
+	return nil
+}
+
+func (c *PagerdutyCollector) Health(ctx context.Context) error {
+	_, err := c.client.GetAbilities(ctx)
+	return err
+}
+
+// Collect stores incidents created since the given time, looking back 90 days
+// on the first run, and refreshes every incident that is still open or was
+// stored unresolved so that late acknowledgements and resolutions are picked
+// up.
+func (c *PagerdutyCollector) Collect(ctx context.Context, since time.Time) error {
+	if since.IsZero() {
+		since = time.Now().AddDate(0, 0, -90)
+	}
+
+	repositories := make(map[string]string)
+	var serviceIDs []string
+	for _, service := range c.config.PagerdutyServices {
+		repositories[service.ID] = service.Repository
+		serviceIDs = append(serviceIDs, service.ID)
+	}
+
+	unresolved, err := c.metrics.unresolvedIncidents("pagerduty")
+	if err != nil {
+		return fmt.Errorf("failed to get unresolved incidents: %w", err)
+	}
+
+	incidents, err := c.incidents(since, serviceIDs, unresolved)
+	if err != nil {
+		return err
+	}
+
+	services := make(map[string]bool)
+	var errs []error
+	for _, incident := range incidents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !services[incident.Service.ID] {
+			services[incident.Service.ID] = true
+
+			service := &models.IncidentService{
+				Source:      "pagerduty",
+				ServiceID:   incident.Service.ID,
+				ServiceName: incident.Service.Summary,
+				Repository:  repositories[incident.Service.ID],
+				UpdatedAt:   time.Now(),
+			}
+			if err := c.metrics.saveIncidentService(service); err != nil {
+				errs = append(errs, fmt.Errorf("failed to save service %s: %w", incident.Service.ID, err))
+			}
+		}
+
+		if err := c.saveIncident(incident); err != nil {
+			log.Printf("Error saving PagerDuty incident %d: %v", incident.IncidentNumber, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// incidents returns the incidents created since the given time and the open
+// ones, each once. Unresolved incidents that are neither were resolved since
+// the last run and are fetched by ID; those deleted since are skipped.
+func (c *PagerdutyCollector) incidents(since time.Time, serviceIDs, unresolved []string) ([]pagerduty.Incident, error) {
+	recent, err := c.client.GetIncidents(since, serviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %w", err)
+	}
+
+	open, err := c.client.GetOpenIncidents(serviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open incidents: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var incidents []pagerduty.Incident
+	for _, incident := range append(recent, open...) {
+		if !seen[incident.ID] {
+			seen[incident.ID] = true
+			incidents = append(incidents, incident)
+		}
+	}
+
+	for _, id := range unresolved {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		incident, err := c.client.GetIncident(id)
+		if errors.Is(err, pagerduty.ErrNotFound) {
+			log.Printf("PagerDuty incident %s no longer exists", id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get incident %s: %w", id, err)
+		}
+		incidents = append(incidents, *incident)
+	}
+
+	return incidents, nil
+}
+
+// saveIncident reads acknowledgement and resolution times from the incident
+// log, since the incident itself only keeps its latest state.
+func (c *PagerdutyCollector) saveIncident(incident pagerduty.Incident) error {
+	entries, err := c.client.GetLogEntries(incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get log entries: %w", err)
+	}
+
+	record := &models.Incident{
+		Source:      "pagerduty",
+		ExternalID:  incident.ID,
+		Number:      incident.IncidentNumber,
+		Title:       incident.Title,
+		Status:      incident.Status,
+		Urgency:     incident.Urgency,
+		ServiceID:   incident.Service.ID,
+		ServiceName: incident.Service.Summary,
+		TriggeredAt: incident.CreatedAt,
+	}
+
+	for _, entry := range entries {
+		createdAt := entry.CreatedAt
+		switch entry.Type {
+		case "acknowledge_log_entry":
+			if record.AcknowledgedAt == nil || createdAt.Before(*record.AcknowledgedAt) {
+				record.AcknowledgedAt = &createdAt
+			}
+		case "resolve_log_entry":
+			if record.ResolvedAt == nil || createdAt.After(*record.ResolvedAt) {
+				record.ResolvedAt = &createdAt
+			}
+		}
+	}
+
+	if incident.Status == "resolved" && record.ResolvedAt == nil {
+		record.ResolvedAt = incident.ResolvedAt
+		if record.ResolvedAt == nil {
+			record.ResolvedAt = &incident.LastStatusChangeAt
+		}
+	}
+
+	if err := c.metrics.saveIncident(record); err != nil {
+		return fmt.Errorf("failed to save incident: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/database/dbtest"
+)
+
+// pagerdutyServer serves P1 as created since the last run, P2 as open and P3
+// as resolved since the last run. Any other incident does not exist.
+func pagerdutyServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	triggeredAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	resolvedAt := triggeredAt.Add(2 * time.Hour)
+	incident := func(id, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":                    id,
+			"incident_number":       len(id),
+			"title":                 "Incident " + id,
+			"status":                status,
+			"urgency":               "high",
+			"service":               map[string]string{"id": "S1", "summary": "API"},
+			"created_at":            triggeredAt,
+			"last_status_change_at": triggeredAt,
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch r.URL.Path {
+		case "/incidents":
+			if r.URL.Query().Get("date_range") == "all" {
+				body = map[string]interface{}{"incidents": []interface{}{incident("P1", "triggered"), incident("P2", "acknowledged")}}
+			} else {
+				body = map[string]interface{}{"incidents": []interface{}{incident("P1", "triggered")}}
+			}
+		case "/incidents/P3":
+			body = map[string]interface{}{"incident": incident("P3", "resolved")}
+		case "/incidents/P1/log_entries", "/incidents/P2/log_entries":
+			body = map[string]interface{}{"log_entries": []interface{}{}}
+		case "/incidents/P3/log_entries":
+			body = map[string]interface{}{"log_entries": []interface{}{
+				map[string]interface{}{"id": "L1", "type": "acknowledge_log_entry", "created_at": triggeredAt.Add(time.Hour)},
+				map[string]interface{}{"id": "L2", "type": "resolve_log_entry", "created_at": resolvedAt},
+			}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestPagerdutyCollector(db *sql.DB, baseURL string) *PagerdutyCollector {
+	return NewPagerdutyCollector(NewMetricsService(db), &config.Config{
+		PagerdutyURL:      baseURL,
+		PagerdutyToken:    "secret",
+		PagerdutyServices: []config.IncidentServiceConfig{{ID: "S1", Repository: "acme/api"}},
+	})
+}
+
+func TestPagerdutyIncidentsRefetchesUnresolved(t *testing.T) {
+	collector := newTestPagerdutyCollector(nil, pagerdutyServer(t).URL)
+
+	incidents, err := collector.incidents(time.Now().Add(-time.Hour), []string{"S1"}, []string{"P2", "P3", "P4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, incident := range incidents {
+		ids = append(ids, incident.ID)
+	}
+	if len(ids) != 3 || ids[0] != "P1" || ids[1] != "P2" || ids[2] != "P3" {
+		t.Fatalf("incidents = %v, want [P1 P2 P3]", ids)
+	}
+	if incidents[2].Status != "resolved" {
+		t.Errorf("P3 status = %q, want resolved", incidents[2].Status)
+	}
+}
+
+func TestPagerdutyCollectResolvesStoredIncidents(t *testing.T) {
+	db := dbtest.Open(t)
+	collector := newTestPagerdutyCollector(db, pagerdutyServer(t).URL)
+
+	cleanup := func() {
+		db.Exec(`DELETE FROM incidents WHERE source = 'pagerduty' AND external_id IN ('P1', 'P2', 'P3')`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	triggeredAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`
+		INSERT INTO incidents (source, external_id, number, title, status, urgency, service_id, service_name, triggered_at)
+		VALUES ('pagerduty', 'P3', 2, 'Incident P3', 'triggered', 'high', 'S1', 'API', $1)`, triggeredAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := collector.Collect(context.Background(), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var status string
+	var acknowledgedAt, resolvedAt sql.NullTime
+	err := db.QueryRow(`SELECT status, acknowledged_at, resolved_at FROM incidents WHERE source = 'pagerduty' AND external_id = 'P3'`).
+		Scan(&status, &acknowledgedAt, &resolvedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "resolved" {
+		t.Errorf("status = %q, want resolved", status)
+	}
+	if !acknowledgedAt.Valid || !acknowledgedAt.Time.Equal(triggeredAt.Add(time.Hour)) {
+		t.Errorf("acknowledged_at = %v", acknowledgedAt)
+	}
+	if !resolvedAt.Valid || !resolvedAt.Time.Equal(triggeredAt.Add(2*time.Hour)) {
+		t.Errorf("resolved_at = %v", resolvedAt)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM incidents WHERE source = 'pagerduty' AND external_id IN ('P1', 'P2')`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("stored %d of P1 and P2, want 2", count)
+	}
+}
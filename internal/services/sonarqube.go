@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-pulse/internal/config"
+	"code-pulse/internal/models"
+	"code-pulse/pkg/sonarqube"
+)
+
+type SonarqubeCollector struct {
+	metrics *MetricsService
+	client  *sonarqube.Client
+	config  *config.Config
+}
+
+func NewSonarqubeCollector(metrics *MetricsService, cfg *config.Config) *SonarqubeCollector {
+	return &SonarqubeCollector{
+		metrics: metrics,
+		client:  sonarqube.NewClient(cfg.SonarqubeURL, cfg.SonarqubeToken),
+		config:  cfg,
+	}
+}
+
+func (c *SonarqubeCollector) Name() string {
+	return "sonarqube"
+}
+
+func (c *SonarqubeCollector) Validate() error {
+	if c.config.SonarqubeURL == "" || c.config.SonarqubeToken == "" {
+		return fmt.Errorf("SONARQUBE_URL and SONARQUBE_TOKEN are required")
+	}
+	if len(c.config.SonarqubeProjects) == 0 {
+		return fmt.Errorf("SONARQUBE_PROJECTS is empty")
+	}
+	return nil
+}
+
+func (c *SonarqubeCollector) Health(ctx context.Context) error {
+	return c.client.ValidateAuthentication(ctx)
+}
+
+// Collect takes a snapshot of every configured project. Measures and quality
+// gates are point-in-time values, so since is not used to narrow the requests.
+func (c *SonarqubeCollector) Collect(ctx context.Context, since time.Time) error {
+	var errs []error
+	report := func(err error, format string, args ...interface{}) {
+		if err != nil {
+			log.Printf(format+": %v", append(args, err)...)
+			errs = append(errs, err)
+		}
+	}
+
+	for _, project := range c.config.SonarqubeProjects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.Printf("Collecting metrics for SonarQube project: %s", project.Key)
+
+		for _, branch := range c.branches(project) {
+			report(c.collectMeasures(project.Key, branch, "", c.config.SonarqubeMetricKeys),
+				"Error collecting SonarQube metrics for %s (branch %q)", project.Key, branch)
+			report(c.collectQualityGate(project.Key, branch, ""),
+				"Error collecting SonarQube quality gate for %s (branch %q)", project.Key, branch)
+		}
+
+		if len(c.config.SonarqubeTreeMetricKeys) > 0 {
+			report(c.collectComponentTree(project.Key, "", c.config.SonarqubeTreeMetricKeys,
+				c.config.SonarqubeTreeQualifiers, c.config.SonarqubeTreeDepth),
+				"Error collecting SonarQube component tree for %s", project.Key)
+		}
+
+		report(c.collectIssues(project.Key, since), "Error collecting SonarQube issues for %s", project.Key)
+		report(c.collectHotspots(project.Key), "Error collecting SonarQube hotspots for %s", project.Key)
+
+		pullRequests, err := c.collectPullRequests(project.Key, project.Repository)
+		report(err, "Error collecting SonarQube pull requests for %s", project.Key)
+
+		for _, pr := range pullRequests {
+			report(c.collectMeasures(project.Key, "", pr.PullRequest, c.config.SonarqubePullRequestMetricKeys),
+				"Error collecting SonarQube metrics for %s pull request %s", project.Key, pr.PullRequest)
+			report(c.collectQualityGate(project.Key, "", pr.PullRequest),
+				"Error collecting SonarQube quality gate for %s pull request %s", project.Key, pr.PullRequest)
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	return errors.Join(errs...)
+}
+
+// branches returns the configured branches of a project, or every branch
+// SonarQube knows about when none are configured.
+func (c *SonarqubeCollector) branches(project config.SonarqubeProjectConfig) []string {
+	branches, err := c.collectBranches(project.Key)
+	if err != nil {
+		log.Printf("Error collecting SonarQube branches for %s: %v", project.Key, err)
+	}
+
+	if len(project.Branches) > 0 {
+		return project.Branches
+	}
+
+	if err != nil {
+		return []string{""}
+	}
+
+	var names []string
+	for _, branch := range branches {
+		names = append(names, branch.Name)
+	}
+
+	return names
+}
+
+func (c *SonarqubeCollector) collectMeasures(projectKey, branch, pullRequest string, metricKeys []string) error {
+	metrics, err := c.client.GetProjectMetrics(projectKey, branch, pullRequest, metricKeys)
+	if err != nil {
+		return fmt.Errorf("failed to get sonarqube metrics: %w", err)
+	}
+
+	for _, metric := range metrics {
+		sonarMetric := &models.SonarqubeMetric{
+			ProjectKey:   projectKey,
+			Branch:       branch,
+			PullRequest:  pullRequest,
+			MetricKey:    metric.Metric,
+			Value:        metric.Value,
+			NumericValue: parseMeasureValue(metric.Metric, metric.Value),
+			Component:    metric.Component,
+			Qualifier:    "TRK",
+			CollectedAt:  time.Now(),
+		}
+
+		if err := c.metrics.saveSonarqubeMetric(sonarMetric); err != nil {
+			return fmt.Errorf("failed to save sonarqube metric: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *SonarqubeCollector) collectComponentTree(projectKey, branch string, metricKeys, qualifiers []string, depth int) error {
+	// A truncated tree is still stored; the error reports what was left out.
+	components, err := c.client.GetComponentTree(projectKey, branch, metricKeys, qualifiers, depth)
+	truncated := errors.Is(err, sonarqube.ErrTruncated)
+	if err != nil && !truncated {
+		return fmt.Errorf("failed to get sonarqube component tree: %w", err)
+	}
+
+	collectedAt := time.Now()
+	for _, component := range components {
+		for _, metric := range component.Metrics {
+			sonarMetric := &models.SonarqubeMetric{
+				ProjectKey:   projectKey,
+				Branch:       branch,
+				MetricKey:    metric.Metric,
+				Value:        metric.Value,
+				NumericValue: parseMeasureValue(metric.Metric, metric.Value),
+				Component:    component.Key,
+				Qualifier:    component.Qualifier,
+				Path:         component.Path,
+				CollectedAt:  collectedAt,
+			}
+
+			if err := c.metrics.saveSonarqubeMetric(sonarMetric); err != nil {
+				return fmt.Errorf("failed to save sonarqube metric: %w", err)
+			}
+		}
+	}
+
+	if truncated {
+		return fmt.Errorf("narrow SONARQUBE_TREE_QUALIFIERS or SONARQUBE_TREE_DEPTH: %w", err)
+	}
+	return nil
+}
+
+// parseMeasureValue converts a measure to a number where possible. Ratings are
+// numeric on current servers but some versions and plugins report letters.
+func parseMeasureValue(metricKey, value string) *float64 {
+	if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+		return &parsed
+	}
+
+	if strings.HasSuffix(metricKey, "_rating") && len(value) == 1 && value >= "A" && value <= "E" {
+		rating := float64(value[0]-'A') + 1
+		return &rating
+	}
+
+	return nil
+}
+
+func (c *SonarqubeCollector) collectBranches(projectKey string) ([]models.SonarqubeBranch, error) {
+	branches, err := c.client.GetProjectBranches(projectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sonarqube branches: %w", err)
+	}
+
+	var saved []models.SonarqubeBranch
+	for _, branch := range branches {
+		sonarBranch := models.SonarqubeBranch{
+			ProjectKey:        projectKey,
+			Name:              branch.Name,
+			IsMain:            branch.IsMain,
+			Type:              branch.Type,
+			QualityGateStatus: branch.Status.QualityGateStatus,
+		}
+		if branch.AnalysisDate != nil {
+			sonarBranch.AnalysisDate = &branch.AnalysisDate.Time
+		}
+
+		if err := c.metrics.saveSonarqubeBranch(&sonarBranch); err != nil {
+			return nil, fmt.Errorf("failed to save sonarqube branch: %w", err)
+		}
+		saved = append(saved, sonarBranch)
+	}
+
+	return saved, nil
+}
+
+func (c *SonarqubeCollector) collectPullRequests(projectKey, repository string) ([]models.SonarqubePullRequest, error) {
+	pullRequests, err := c.client.GetProjectPullRequests(projectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sonarqube pull requests: %w", err)
+	}
+
+	var saved []models.SonarqubePullRequest
+	for _, pr := range pullRequests {
+		sonarPR := models.SonarqubePullRequest{
+			ProjectKey:        projectKey,
+			Repository:        repository,
+			PullRequest:       pr.Key,
+			Title:             pr.Title,
+			Branch:            pr.Branch,
+			BaseBranch:        pr.Base,
+			QualityGateStatus: pr.Status.QualityGateStatus,
+		}
+		if pr.AnalysisDate != nil {
+			sonarPR.AnalysisDate = &pr.AnalysisDate.Time
+		}
+
+		if err := c.metrics.saveSonarqubePullRequest(&sonarPR); err != nil {
+			return nil, fmt.Errorf("failed to save sonarqube pull request: %w", err)
+		}
+		saved = append(saved, sonarPR)
+	}
+
+	return saved, nil
+}
+
+func (c *SonarqubeCollector) collectQualityGate(projectKey, branch, pullRequest string) error {
+	status, err := c.client.GetQualityGateStatus(projectKey, branch, pullRequest)
+	if err != nil {
+		return fmt.Errorf("failed to get sonarqube quality gate status: %w", err)
+	}
+
+	gate := &models.SonarqubeQualityGate{
+		ProjectKey:  projectKey,
+		Branch:      branch,
+		PullRequest: pullRequest,
+		Status:      status.Status,
+		CollectedAt: time.Now(),
+	}
+
+	for _, condition := range status.Conditions {
+		if condition.Status == "OK" {
+			continue
+		}
+
+		gate.Conditions = append(gate.Conditions, models.SonarqubeQualityGateCondition{
+			MetricKey:      condition.MetricKey,
+			Comparator:     condition.Comparator,
+			ErrorThreshold: condition.ErrorThreshold,
+			ActualValue:    condition.ActualValue,
+			Status:         condition.Status,
+		})
+	}
+
+	if err := c.metrics.saveSonarqubeQualityGate(gate); err != nil {
+		return fmt.Errorf("failed to save sonarqube quality gate: %w", err)
+	}
+
+	return nil
+}
+
+// collectIssues stores the issues created since the last run, and refreshes
+// the ones stored as open, which the creation date search no longer returns
+// once they are closed.
+func (c *SonarqubeCollector) collectIssues(projectKey string, since time.Time) error {
+	from := since
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	// Truncated results are still stored; the error reports what was left out.
+	issues, searchErr := c.client.SearchIssues(projectKey, from, time.Now())
+	if searchErr != nil && !errors.Is(searchErr, sonarqube.ErrTruncated) {
+		return fmt.Errorf("failed to search sonarqube issues: %w", searchErr)
+	}
+
+	open, err := c.openIssueKeys(projectKey, since)
+	if err != nil {
+		return err
+	}
+	refreshed, err := c.client.GetIssues(open)
+	if err != nil {
+		return fmt.Errorf("failed to refresh open sonarqube issues: %w", err)
+	}
+	issues = append(issues, refreshed...)
+
+	identities := c.metrics.newIdentityRecorder()
+	for _, issue := range issues {
+		if issue.CreationDate == nil {
+			continue
+		}
+
+		// Issue authors come from SCM blame and are usually commit emails.
+		if strings.Contains(issue.Author, "@") {
+			if err := identities.save("sonarqube", issue.Author, "", issue.Author); err != nil {
+				return fmt.Errorf("failed to save sonarqube author: %w", err)
+			}
+		}
+
+		sonarIssue := &models.SonarqubeIssue{
+			IssueKey:   issue.Key,
+			ProjectKey: projectKey,
+			Rule:       issue.Rule,
+			Severity:   issue.Severity,
+			Type:       issue.Type,
+			File:       componentPath(issue.Component),
+			Status:     issue.Status,
+			Resolution: issue.Resolution,
+			Author:     issue.Author,
+			CreatedAt:  issue.CreationDate.Time,
+			UpdatedAt:  issue.CreationDate.Time,
+		}
+		if issue.Line > 0 {
+			sonarIssue.Line = &issue.Line
+		}
+		if issue.UpdateDate != nil {
+			sonarIssue.UpdatedAt = issue.UpdateDate.Time
+		}
+		if issue.CloseDate != nil {
+			sonarIssue.ClosedAt = &issue.CloseDate.Time
+		}
+
+		if err := c.metrics.saveSonarqubeIssue(sonarIssue); err != nil {
+			return fmt.Errorf("failed to save sonarqube issue: %w", err)
+		}
+	}
+
+	if searchErr != nil {
+		return fmt.Errorf("failed to search every sonarqube issue: %w", searchErr)
+	}
+	return nil
+}
+
+// openIssueKeys returns the issues of a project stored without a close date
+// that were created before since.
+func (c *SonarqubeCollector) openIssueKeys(projectKey string, since time.Time) ([]string, error) {
+	rows, err := c.metrics.db.Query(`
+		SELECT issue_key FROM sonarqube_issues
+		WHERE project_key = $1 AND closed_at IS NULL AND created_at < $2
+		ORDER BY issue_key`, projectKey, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open sonarqube issues: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan sonarqube issue: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (c *SonarqubeCollector) collectHotspots(projectKey string) error {
+	hotspots, err := c.client.SearchHotspots(projectKey)
+	if err != nil {
+		return fmt.Errorf("failed to search sonarqube hotspots: %w", err)
+	}
+
+	for _, hotspot := range hotspots {
+		if hotspot.CreationDate == nil {
+			continue
+		}
+
+		sonarHotspot := &models.SonarqubeHotspot{
+			HotspotKey:               hotspot.Key,
+			ProjectKey:               projectKey,
+			Rule:                     hotspot.RuleKey,
+			SecurityCategory:         hotspot.SecurityCategory,
+			VulnerabilityProbability: hotspot.VulnerabilityProbability,
+			File:                     componentPath(hotspot.Component),
+			Status:                   hotspot.Status,
+			Resolution:               hotspot.Resolution,
+			CreatedAt:                hotspot.CreationDate.Time,
+			UpdatedAt:                hotspot.CreationDate.Time,
+		}
+		if hotspot.Line > 0 {
+			sonarHotspot.Line = &hotspot.Line
+		}
+		if hotspot.UpdateDate != nil {
+			sonarHotspot.UpdatedAt = hotspot.UpdateDate.Time
+		}
+		// Hotspots carry no close date; a review is the last update.
+		if hotspot.Status == "REVIEWED" {
+			sonarHotspot.ClosedAt = &sonarHotspot.UpdatedAt
+		}
+
+		if err := c.metrics.saveSonarqubeHotspot(sonarHotspot); err != nil {
+			return fmt.Errorf("failed to save sonarqube hotspot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// componentPath strips the "project:" prefix from a SonarQube component key.
+func componentPath(component string) string {
+	if i := strings.Index(component, ":"); i >= 0 {
+		return component[i+1:]
+	}
+	return component
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Ticket keys are a project key and a number, as in PAY-123. Branch names
+// are often lower case, so they are matched in any case and upper-cased;
+// names such as release-2 yield keys of unknown projects, which readers of
+// ticket_links skip. The backfill in migrations.sql uses the same patterns.
+var (
+	ticketKeyPattern       = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+	branchTicketKeyPattern = regexp.MustCompile(`(?i)\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+)
+
+// ticketKeys returns the distinct ticket keys pattern finds in text.
+func ticketKeys(pattern *regexp.Regexp, text string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllString(text, -1) {
+		key := strings.ToUpper(match)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// saveTicketLinks replaces the tickets a pull request or commit references,
+// so that a key edited out of a title no longer links it.
+func saveTicketLinks(tx *sql.Tx, kind, provider, repository, ref string, keys []string) error {
+	if _, err := tx.Exec(`
+		DELETE FROM ticket_links
+		WHERE kind = $1 AND provider = $2 AND repository = $3 AND ref = $4`,
+		kind, provider, repository, ref); err != nil {
+		return fmt.Errorf("failed to clear ticket links: %w", err)
+	}
+
+	for _, key := range keys {
+		if _, err := tx.Exec(`
+			INSERT INTO ticket_links (ticket_key, kind, provider, repository, ref)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (kind, provider, repository, ref, ticket_key) DO NOTHING`,
+			key, kind, provider, repository, ref); err != nil {
+			return fmt.Errorf("failed to save ticket link: %w", err)
+		}
+	}
+	return nil
+}
+
+// LinkDeployments records the first successful deployment to environment
+// after each merged pull request not linked to one yet. Collectors store
+// pull requests and deployments independently, so this runs after each of
+// them.
+func (s *MetricsService) LinkDeployments(environment string) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO pull_request_deployments (provider, repository, number, environment, deployment_id, deployed_at)
+		SELECT pr.provider, pr.repository, pr.number, $1, d.id, d.created_at
+		FROM github_pull_requests pr
+		JOIN LATERAL (
+			SELECT id, created_at
+			FROM deployments
+			WHERE provider = pr.provider
+			AND repository = pr.repository
+			AND environment = $1
+			AND status = 'success'
+			AND created_at >= pr.merged_at
+			ORDER BY created_at
+			LIMIT 1
+		) d ON TRUE
+		WHERE pr.merged_at IS NOT NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM pull_request_deployments pd
+			WHERE pd.provider = pr.provider
+			AND pd.repository = pr.repository
+			AND pd.number = pr.number
+			AND pd.environment = $1
+		)
+		ON CONFLICT (provider, repository, number, environment) DO NOTHING`, environment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link deployments: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,419 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pageSize = 100
+
+// Client talks to Bitbucket Cloud (api.bitbucket.org) or a Bitbucket Data
+// Center instance and returns the same types for both. With a username the
+// token is sent as a Cloud app password or Data Center password using basic
+// auth; without one it is sent as a bearer access token.
+type Client struct {
+	username   string
+	token      string
+	httpClient *http.Client
+	baseURL    string
+	cloud      bool
+}
+
+// Repository is identified by its full name, workspace/slug on Cloud and
+// PROJECT/slug on Data Center.
+type Repository struct {
+	FullName      string
+	DefaultBranch string
+	WebURL        string
+}
+
+type PullRequest struct {
+	ID           int
+	Title        string
+	State        string // OPEN, MERGED, DECLINED or SUPERSEDED
+	Author       string
+	SourceBranch string
+	TargetBranch string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ClosedAt     *time.Time
+}
+
+// Activity is a pull request event such as APPROVED, COMMENTED, MERGED,
+// DECLINED, UPDATED or CHANGES_REQUESTED.
+type Activity struct {
+	Action    string
+	Author    string
+	CreatedAt time.Time
+}
+
+type Pipeline struct {
+	UUID              string
+	BuildNumber       int
+	State             string // PENDING, IN_PROGRESS or the result once COMPLETED
+	Trigger           string
+	Selector          string // default, branches:main, custom:deploy, ...
+	RefName           string
+	Commit            string
+	DurationInSeconds int
+	CreatedAt         time.Time
+	CompletedAt       *time.Time
+}
+
+type Step struct {
+	UUID        string
+	Name        string
+	State       string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+type cloudUser struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+func (u *cloudUser) name() string {
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return u.DisplayName
+}
+
+type cloudState struct {
+	Name   string `json:"name"`
+	Result struct {
+		Name string `json:"name"`
+	} `json:"result"`
+}
+
+func (s *cloudState) status() string {
+	if s.Name == "COMPLETED" && s.Result.Name != "" {
+		return s.Result.Name
+	}
+	return s.Name
+}
+
+type serverUser struct {
+	Name string `json:"name"`
+}
+
+func NewClient(baseURL, username, token string) *Client {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return &Client{
+		username:   username,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    baseURL,
+		cloud:      strings.Contains(baseURL, "bitbucket.org"),
+	}
+}
+
+// IsCloud reports whether the client talks to Bitbucket Cloud. Pipelines
+// only exist there.
+func (c *Client) IsCloud() bool {
+	return c.cloud
+}
+
+func (c *Client) GetRepository(fullName string) (*Repository, error) {
+	owner, slug, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cloud {
+		var repo cloudRepository
+		if err := c.get(c.baseURL+"/2.0/repositories/"+url.PathEscape(owner)+"/"+url.PathEscape(slug), url.Values{}, &repo); err != nil {
+			return nil, err
+		}
+		return repo.toRepository(), nil
+	}
+
+	var repo serverRepository
+	if err := c.get(c.serverRepoURL(owner, slug), url.Values{}, &repo); err != nil {
+		return nil, err
+	}
+	return repo.toRepository(), nil
+}
+
+// GetRepositories lists the repositories of a Cloud workspace or a Data
+// Center project.
+func (c *Client) GetRepositories(workspace string) ([]Repository, error) {
+	var repositories []Repository
+
+	if c.cloud {
+		err := cloudPages(c, c.baseURL+"/2.0/repositories/"+url.PathEscape(workspace), url.Values{}, func(repo cloudRepository) bool {
+			repositories = append(repositories, *repo.toRepository())
+			return true
+		})
+		return repositories, err
+	}
+
+	err := serverPages(c, c.baseURL+"/rest/api/1.0/projects/"+url.PathEscape(workspace)+"/repos", url.Values{}, func(repo serverRepository) bool {
+		repositories = append(repositories, *repo.toRepository())
+		return true
+	})
+	return repositories, err
+}
+
+// GetPipelines returns Cloud pipelines created since the given time.
+func (c *Client) GetPipelines(fullName string, since time.Time) ([]Pipeline, error) {
+	if !c.cloud {
+		return nil, fmt.Errorf("pipelines are only available on Bitbucket Cloud")
+	}
+
+	owner, slug, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("sort", "-created_on")
+
+	var pipelines []Pipeline
+	path := c.baseURL + "/2.0/repositories/" + url.PathEscape(owner) + "/" + url.PathEscape(slug) + "/pipelines/"
+	err = cloudPages(c, path, params, func(p cloudPipeline) bool {
+		if p.CreatedOn.Before(since) {
+			return false
+		}
+		pipelines = append(pipelines, Pipeline{
+			UUID:              p.UUID,
+			BuildNumber:       p.BuildNumber,
+			State:             p.State.status(),
+			Trigger:           p.Trigger.Name,
+			Selector:          p.Target.Selector.name(),
+			RefName:           p.Target.RefName,
+			Commit:            p.Target.Commit.Hash,
+			DurationInSeconds: p.DurationInSeconds,
+			CreatedAt:         p.CreatedOn,
+			CompletedAt:       p.CompletedOn,
+		})
+		return true
+	})
+	return pipelines, err
+}
+
+func (c *Client) GetPipelineSteps(fullName, pipelineUUID string) ([]Step, error) {
+	owner, slug, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	path := c.baseURL + "/2.0/repositories/" + url.PathEscape(owner) + "/" + url.PathEscape(slug) +
+		"/pipelines/" + url.PathEscape(pipelineUUID) + "/steps/"
+	err = cloudPages(c, path, url.Values{}, func(s cloudStep) bool {
+		steps = append(steps, Step{
+			UUID:        s.UUID,
+			Name:        s.Name,
+			State:       s.State.status(),
+			StartedAt:   s.StartedOn,
+			CompletedAt: s.CompletedOn,
+		})
+		return true
+	})
+	return steps, err
+}
+
+// GetPullRequests returns pull requests in every state updated since the
+// given time.
+func (c *Client) GetPullRequests(fullName string, since time.Time) ([]PullRequest, error) {
+	owner, slug, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pullRequests []PullRequest
+
+	if c.cloud {
+		params := url.Values{}
+		for _, state := range []string{"OPEN", "MERGED", "DECLINED", "SUPERSEDED"} {
+			params.Add("state", state)
+		}
+		params.Set("q", fmt.Sprintf("updated_on >= %s", since.UTC().Format(time.RFC3339)))
+		params.Set("sort", "-updated_on")
+
+		path := c.baseURL + "/2.0/repositories/" + url.PathEscape(owner) + "/" + url.PathEscape(slug) + "/pullrequests"
+		err = cloudPages(c, path, params, func(pr cloudPullRequest) bool {
+			pullRequests = append(pullRequests, pr.toPullRequest())
+			return true
+		})
+		return pullRequests, err
+	}
+
+	// Data Center cannot filter by update time, but NEWEST lists the most
+	// recently updated first, so paging stops at the first one updated
+	// before since.
+	params := url.Values{}
+	params.Set("state", "ALL")
+	params.Set("order", "NEWEST")
+
+	err = serverPages(c, c.serverRepoURL(owner, slug)+"/pull-requests", params, func(pr serverPullRequest) bool {
+		converted := pr.toPullRequest()
+		if converted.UpdatedAt.Before(since) {
+			return false
+		}
+		pullRequests = append(pullRequests, converted)
+		return true
+	})
+	return pullRequests, err
+}
+
+func (c *Client) GetPullRequestActivity(fullName string, id int) ([]Activity, error) {
+	owner, slug, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	var activities []Activity
+
+	if c.cloud {
+		path := fmt.Sprintf("%s/2.0/repositories/%s/%s/pullrequests/%d/activity",
+			c.baseURL, url.PathEscape(owner), url.PathEscape(slug), id)
+		err = cloudPages(c, path, url.Values{}, func(a cloudActivity) bool {
+			if activity, ok := a.toActivity(); ok {
+				activities = append(activities, activity)
+			}
+			return true
+		})
+		return activities, err
+	}
+
+	path := fmt.Sprintf("%s/pull-requests/%d/activities", c.serverRepoURL(owner, slug), id)
+	err = serverPages(c, path, url.Values{}, func(a serverActivity) bool {
+		activities = append(activities, Activity{
+			Action:    a.Action,
+			Author:    a.User.Name,
+			CreatedAt: fromMillis(a.CreatedDate),
+		})
+		return true
+	})
+	return activities, err
+}
+
+// Ping checks the credentials against the current user on Cloud and the
+// application properties on Data Center.
+func (c *Client) Ping(ctx context.Context) error {
+	var response map[string]interface{}
+	if c.cloud {
+		return c.getContext(ctx, c.baseURL+"/2.0/user", url.Values{}, &response)
+	}
+	return c.getContext(ctx, c.baseURL+"/rest/api/1.0/application-properties", url.Values{}, &response)
+}
+
+func (c *Client) serverRepoURL(project, slug string) string {
+	return c.baseURL + "/rest/api/1.0/projects/" + url.PathEscape(project) + "/repos/" + url.PathEscape(slug)
+}
+
+func splitFullName(fullName string) (string, string, error) {
+	owner, slug, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || slug == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/slug", fullName)
+	}
+	return owner, slug, nil
+}
+
+func fromMillis(millis int64) time.Time {
+	return time.UnixMilli(millis)
+}
+
+// cloudPages follows the next links of a Cloud paginated response, passing
+// each value to fn until it returns false.
+func cloudPages[T any](c *Client, path string, params url.Values, fn func(T) bool) error {
+	params.Set("pagelen", strconv.Itoa(pageSize))
+	next := path + "?" + params.Encode()
+
+	for next != "" {
+		var page struct {
+			Values []T    `json:"values"`
+			Next   string `json:"next"`
+		}
+		if err := c.get(next, nil, &page); err != nil {
+			return err
+		}
+
+		for _, value := range page.Values {
+			if !fn(value) {
+				return nil
+			}
+		}
+		next = page.Next
+	}
+
+	return nil
+}
+
+// serverPages follows start/limit paging of a Data Center response, passing
+// each value to fn until it returns false.
+func serverPages[T any](c *Client, path string, params url.Values, fn func(T) bool) error {
+	params.Set("limit", strconv.Itoa(pageSize))
+	params.Set("start", "0")
+
+	for {
+		var page struct {
+			Values        []T  `json:"values"`
+			IsLastPage    bool `json:"isLastPage"`
+			NextPageStart int  `json:"nextPageStart"`
+		}
+		if err := c.get(path, params, &page); err != nil {
+			return err
+		}
+
+		for _, value := range page.Values {
+			if !fn(value) {
+				return nil
+			}
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			return nil
+		}
+		params.Set("start", strconv.Itoa(page.NextPageStart))
+	}
+}
+
+// get requests an absolute URL; params are appended when given.
+func (c *Client) get(rawURL string, params url.Values, v interface{}) error {
+	return c.getContext(context.Background(), rawURL, params, v)
+}
+
+func (c *Client) getContext(ctx context.Context, rawURL string, params url.Values, v interface{}) error {
+	if len(params) > 0 {
+		rawURL += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
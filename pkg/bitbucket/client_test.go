@@ -0,0 +1,55 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetPullRequestsDataCenterStopsAtSince(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	pullRequest := func(id int, updatedAt time.Time) serverPullRequest {
+		return serverPullRequest{ID: id, State: "OPEN", CreatedDate: updatedAt.UnixMilli(), UpdatedDate: updatedAt.UnixMilli()}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("order"); got != "NEWEST" {
+			t.Errorf("order = %q", got)
+		}
+
+		page := map[string]interface{}{"isLastPage": false}
+		switch start, _ := strconv.Atoi(r.URL.Query().Get("start")); start {
+		case 0:
+			page["values"] = []serverPullRequest{pullRequest(3, since.Add(time.Hour)), pullRequest(2, since)}
+			page["nextPageStart"] = 2
+		case 2:
+			page["values"] = []serverPullRequest{pullRequest(1, since.Add(-time.Hour)), pullRequest(0, since.Add(-2*time.Hour))}
+			page["nextPageStart"] = 4
+		default:
+			t.Errorf("fetched page at %d past since", start)
+			http.Error(w, "unexpected page", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	pullRequests, err := NewClient(server.URL, "", "secret").GetPullRequests("PROJ/repo", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pullRequests) != 2 || pullRequests[0].ID != 3 || pullRequests[1].ID != 2 {
+		t.Errorf("pull requests = %+v", pullRequests)
+	}
+}
@@ -0,0 +1,204 @@
+package bitbucket
+
+import "time"
+
+// Wire formats of the Cloud 2.0 and Data Center 1.0 REST APIs.
+
+type cloudRepository struct {
+	FullName   string `json:"full_name"`
+	Mainbranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+func (r *cloudRepository) toRepository() *Repository {
+	repo := &Repository{FullName: r.FullName, WebURL: r.Links.HTML.Href}
+	if r.Mainbranch != nil {
+		repo.DefaultBranch = r.Mainbranch.Name
+	}
+	return repo
+}
+
+type cloudPipeline struct {
+	UUID        string     `json:"uuid"`
+	BuildNumber int        `json:"build_number"`
+	State       cloudState `json:"state"`
+	Trigger     struct {
+		Name string `json:"name"`
+	} `json:"trigger"`
+	Target struct {
+		Selector cloudSelector `json:"selector"`
+		RefName  string        `json:"ref_name"`
+		Commit   struct {
+			Hash string `json:"hash"`
+		} `json:"commit"`
+	} `json:"target"`
+	DurationInSeconds int        `json:"duration_in_seconds"`
+	CreatedOn         time.Time  `json:"created_on"`
+	CompletedOn       *time.Time `json:"completed_on"`
+}
+
+type cloudSelector struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+}
+
+func (s *cloudSelector) name() string {
+	if s.Pattern == "" {
+		return s.Type
+	}
+	return s.Type + ":" + s.Pattern
+}
+
+type cloudStep struct {
+	UUID        string     `json:"uuid"`
+	Name        string     `json:"name"`
+	State       cloudState `json:"state"`
+	StartedOn   *time.Time `json:"started_on"`
+	CompletedOn *time.Time `json:"completed_on"`
+}
+
+type cloudBranchRef struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+}
+
+type cloudPullRequest struct {
+	ID          int            `json:"id"`
+	Title       string         `json:"title"`
+	State       string         `json:"state"`
+	Author      cloudUser      `json:"author"`
+	Source      cloudBranchRef `json:"source"`
+	Destination cloudBranchRef `json:"destination"`
+	CreatedOn   time.Time      `json:"created_on"`
+	UpdatedOn   time.Time      `json:"updated_on"`
+}
+
+// toPullRequest approximates the close time with the last update; the
+// activity log has the exact merge or decline time.
+func (pr *cloudPullRequest) toPullRequest() PullRequest {
+	pullRequest := PullRequest{
+		ID:           pr.ID,
+		Title:        pr.Title,
+		State:        pr.State,
+		Author:       pr.Author.name(),
+		SourceBranch: pr.Source.Branch.Name,
+		TargetBranch: pr.Destination.Branch.Name,
+		CreatedAt:    pr.CreatedOn,
+		UpdatedAt:    pr.UpdatedOn,
+	}
+	if pr.State != "OPEN" {
+		closedAt := pr.UpdatedOn
+		pullRequest.ClosedAt = &closedAt
+	}
+	return pullRequest
+}
+
+type cloudActivity struct {
+	Update *struct {
+		State  string    `json:"state"`
+		Date   time.Time `json:"date"`
+		Author cloudUser `json:"author"`
+	} `json:"update"`
+	Approval *struct {
+		Date time.Time `json:"date"`
+		User cloudUser `json:"user"`
+	} `json:"approval"`
+	ChangesRequested *struct {
+		Date time.Time `json:"date"`
+		User cloudUser `json:"user"`
+	} `json:"changes_requested"`
+	Comment *struct {
+		CreatedOn time.Time `json:"created_on"`
+		User      cloudUser `json:"user"`
+	} `json:"comment"`
+}
+
+// toActivity maps Cloud activity onto Data Center action names. Updates that
+// close the pull request become MERGED or DECLINED.
+func (a *cloudActivity) toActivity() (Activity, bool) {
+	switch {
+	case a.Approval != nil:
+		return Activity{Action: "APPROVED", Author: a.Approval.User.name(), CreatedAt: a.Approval.Date}, true
+	case a.ChangesRequested != nil:
+		return Activity{Action: "CHANGES_REQUESTED", Author: a.ChangesRequested.User.name(), CreatedAt: a.ChangesRequested.Date}, true
+	case a.Comment != nil:
+		return Activity{Action: "COMMENTED", Author: a.Comment.User.name(), CreatedAt: a.Comment.CreatedOn}, true
+	case a.Update != nil:
+		action := "UPDATED"
+		if a.Update.State == "MERGED" || a.Update.State == "DECLINED" {
+			action = a.Update.State
+		}
+		return Activity{Action: action, Author: a.Update.Author.name(), CreatedAt: a.Update.Date}, true
+	default:
+		return Activity{}, false
+	}
+}
+
+type serverRepository struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+func (r *serverRepository) toRepository() *Repository {
+	repo := &Repository{FullName: r.Project.Key + "/" + r.Slug}
+	if len(r.Links.Self) > 0 {
+		repo.WebURL = r.Links.Self[0].Href
+	}
+	return repo
+}
+
+type serverRef struct {
+	DisplayID string `json:"displayId"`
+}
+
+type serverPullRequest struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Author struct {
+		User serverUser `json:"user"`
+	} `json:"author"`
+	FromRef     serverRef `json:"fromRef"`
+	ToRef       serverRef `json:"toRef"`
+	CreatedDate int64     `json:"createdDate"`
+	UpdatedDate int64     `json:"updatedDate"`
+	ClosedDate  int64     `json:"closedDate"`
+}
+
+func (pr *serverPullRequest) toPullRequest() PullRequest {
+	pullRequest := PullRequest{
+		ID:           pr.ID,
+		Title:        pr.Title,
+		State:        pr.State,
+		Author:       pr.Author.User.Name,
+		SourceBranch: pr.FromRef.DisplayID,
+		TargetBranch: pr.ToRef.DisplayID,
+		CreatedAt:    fromMillis(pr.CreatedDate),
+		UpdatedAt:    fromMillis(pr.UpdatedDate),
+	}
+	if pr.ClosedDate > 0 {
+		closedAt := fromMillis(pr.ClosedDate)
+		pullRequest.ClosedAt = &closedAt
+	}
+	return pullRequest
+}
+
+type serverActivity struct {
+	Action      string     `json:"action"`
+	CreatedDate int64      `json:"createdDate"`
+	User        serverUser `json:"user"`
+}
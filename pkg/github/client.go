@@ -1,10 +1,14 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,6 +16,10 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	baseURL    string
+
+	// rateLimitRemaining is the remaining request quota reported by the
+	// last response, or -1 before any response reported it.
+	rateLimitRemaining atomic.Int64
 }
 
 type Workflow struct {
@@ -31,7 +39,10 @@ type WorkflowRun struct {
 	DisplayTitle string    `json:"display_title"`
 	Status       string    `json:"status"`
 	Conclusion   string    `json:"conclusion"`
+	Event        string    `json:"event"`
 	WorkflowID   int       `json:"workflow_id"`
+	HeadBranch   string    `json:"head_branch"`
+	HeadSHA      string    `json:"head_sha"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	RunStartedAt time.Time `json:"run_started_at"`
@@ -55,44 +66,81 @@ type WorkflowRunsResponse struct {
 	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
 }
 
+type PullRequest struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	User      User       `json:"user"`
+	Head      Ref        `json:"head"`
+	Base      Ref        `json:"base"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+}
+
+type Commit struct {
+	SHA    string        `json:"sha"`
+	Commit CommitDetails `json:"commit"`
+	Author *User         `json:"author"`
+	Files  []CommitFile  `json:"files"`
+}
+
+type CommitDetails struct {
+	Message string       `json:"message"`
+	Author  CommitAuthor `json:"author"`
+}
+
+type CommitAuthor struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+type CommitFile struct {
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+type Ref struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
 func NewClient(token string) *Client {
-	return &Client{
+	c := &Client{
 		token:      token,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		baseURL:    "https://api.github.com",
 	}
+	c.rateLimitRemaining.Store(-1)
+	return c
+}
+
+// RateLimitRemaining returns the request quota left as of the last response.
+func (c *Client) RateLimitRemaining() (int, bool) {
+	remaining := c.rateLimitRemaining.Load()
+	return int(remaining), remaining >= 0
 }
 
 func (c *Client) GetWorkflows(owner, repo string) ([]Workflow, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/actions/workflows", c.baseURL, owner, repo)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
-
 	var response WorkflowsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := c.get(url, &response); err != nil {
+		return nil, err
 	}
 
 	return response.Workflows, nil
 }
 
-func (c *Client) GetWorkflowRunsByName(owner, repo, workflowName string) ([]WorkflowRun, error) {
+func (c *Client) GetWorkflowRunsByName(owner, repo, workflowName string, since time.Time) ([]WorkflowRun, error) {
 	workflows, err := c.GetWorkflows(owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workflows: %w", err)
@@ -110,19 +158,207 @@ func (c *Client) GetWorkflowRunsByName(owner, repo, workflowName string) ([]Work
 		return nil, fmt.Errorf("workflow '%s' not found in repository %s/%s", workflowName, owner, repo)
 	}
 
-	return c.GetWorkflowRuns(owner, repo, workflowID)
+	return c.GetWorkflowRuns(owner, repo, workflowID, since)
+}
+
+// GetWorkflowRuns returns the runs of a workflow, or of every workflow in the
+// repository when workflowID is 0, created at or after since, following the
+// Link header through every page.
+func (c *Client) GetWorkflowRuns(owner, repo string, workflowID int, since time.Time) ([]WorkflowRun, error) {
+	params := url.Values{}
+	params.Set("per_page", "100")
+	if !since.IsZero() {
+		params.Set("created", ">="+since.UTC().Format(time.RFC3339))
+	}
+
+	path := fmt.Sprintf("actions/workflows/%d/runs", workflowID)
+	if workflowID == 0 {
+		path = "actions/runs"
+	}
+
+	next := fmt.Sprintf("%s/repos/%s/%s/%s?%s", c.baseURL, owner, repo, path, params.Encode())
+
+	var runs []WorkflowRun
+	for next != "" {
+		var response WorkflowRunsResponse
+		link, err := c.getPage(context.Background(), next, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, response.WorkflowRuns...)
+		next = link
+	}
+
+	return runs, nil
 }
 
-func (c *Client) GetWorkflowRuns(owner, repo string, workflowID int) ([]WorkflowRun, error) {
+// GetPullRequests returns the pull requests in every state updated at or
+// after since, following the Link header from the most recently updated down.
+func (c *Client) GetPullRequests(owner, repo string, since time.Time) ([]PullRequest, error) {
 	params := url.Values{}
+	params.Set("state", "all")
+	params.Set("sort", "updated")
+	params.Set("direction", "desc")
 	params.Set("per_page", "100")
 
-	url := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%d/runs?%s",
-		c.baseURL, owner, repo, workflowID, params.Encode())
+	next := fmt.Sprintf("%s/repos/%s/%s/pulls?%s", c.baseURL, owner, repo, params.Encode())
+
+	var pullRequests []PullRequest
+	for next != "" {
+		var page []PullRequest
+		link, err := c.getPage(context.Background(), next, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pr := range page {
+			if pr.UpdatedAt.Before(since) {
+				return pullRequests, nil
+			}
+			pullRequests = append(pullRequests, pr)
+		}
+		next = link
+	}
+
+	return pullRequests, nil
+}
+
+// GetCommits lists the commits on the default branch since the given time,
+// following pagination. File details are only returned by GetCommit.
+func (c *Client) GetCommits(owner, repo string, since time.Time) ([]Commit, error) {
+	var commits []Commit
 
-	req, err := http.NewRequest("GET", url, nil)
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("since", since.UTC().Format(time.RFC3339))
+		params.Set("per_page", "100")
+		params.Set("page", fmt.Sprintf("%d", page))
+
+		url := fmt.Sprintf("%s/repos/%s/%s/commits?%s", c.baseURL, owner, repo, params.Encode())
+
+		var pageCommits []Commit
+		if err := c.get(url, &pageCommits); err != nil {
+			return nil, err
+		}
+
+		commits = append(commits, pageCommits...)
+
+		if len(pageCommits) < 100 {
+			break
+		}
+	}
+
+	return commits, nil
+}
+
+func (c *Client) GetCommit(owner, repo, sha string) (*Commit, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.baseURL, owner, repo, sha)
+
+	var commit Commit
+	if err := c.get(url, &commit); err != nil {
+		return nil, err
+	}
+
+	return &commit, nil
+}
+
+type Deployment struct {
+	ID          int       `json:"id"`
+	SHA         string    `json:"sha"`
+	Ref         string    `json:"ref"`
+	Environment string    `json:"environment"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type DeploymentStatus struct {
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetDeployments returns the deployments updated at or after since. They are
+// listed newest first, so the Link header is followed until a page holds no
+// deployment updated since then.
+func (c *Client) GetDeployments(owner, repo string, since time.Time) ([]Deployment, error) {
+	params := url.Values{}
+	params.Set("per_page", "100")
+
+	next := fmt.Sprintf("%s/repos/%s/%s/deployments?%s", c.baseURL, owner, repo, params.Encode())
+
+	var deployments []Deployment
+	for next != "" {
+		var page []Deployment
+		link, err := c.getPage(context.Background(), next, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		recent := false
+		for _, d := range page {
+			if !d.UpdatedAt.Before(since) {
+				deployments = append(deployments, d)
+				recent = true
+			}
+		}
+		if !recent {
+			break
+		}
+		next = link
+	}
+
+	return deployments, nil
+}
+
+// GetLatestDeploymentStatus returns the most recent status of a deployment,
+// or nil if none has been reported yet.
+func (c *Client) GetLatestDeploymentStatus(owner, repo string, deploymentID int) (*DeploymentStatus, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/deployments/%d/statuses?per_page=1", c.baseURL, owner, repo, deploymentID)
+
+	var statuses []DeploymentStatus
+	if err := c.get(url, &statuses); err != nil {
+		return nil, err
+	}
+
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	return &statuses[0], nil
+}
+
+type RateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+type RateLimitResponse struct {
+	Rate RateLimit `json:"rate"`
+}
+
+func (c *Client) GetRateLimit(ctx context.Context) (*RateLimit, error) {
+	url := fmt.Sprintf("%s/rate_limit", c.baseURL)
+
+	var response RateLimitResponse
+	if _, err := c.getPage(ctx, url, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Rate, nil
+}
+
+func (c *Client) get(url string, v interface{}) error {
+	_, err := c.getPage(context.Background(), url, v)
+	return err
+}
+
+// getPage fetches a page into v and returns the URL of the next page from
+// the Link header, empty on the last one.
+func (c *Client) getPage(ctx context.Context, url string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
@@ -130,18 +366,38 @@ func (c *Client) GetWorkflowRuns(owner, repo string, workflowID int) ([]Workflow
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return "", fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if remaining, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		c.rateLimitRemaining.Store(remaining)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return "", fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
 
-	var response WorkflowRunsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return response.WorkflowRuns, nil
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink picks the rel="next" URL out of a Link header such as
+// <https://api.github.com/...&page=2>; rel="next", <...>; rel="last".
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
 }
@@ -0,0 +1,100 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret")
+	client.baseURL = server.URL
+	return client, server
+}
+
+func writeTestJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+func TestGetWorkflowRunsFollowsLinkHeader(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var server *httptest.Server
+	client, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/org/repo/actions/runs" {
+			http.Error(w, "unexpected path", http.StatusNotFound)
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			if got := r.URL.Query().Get("created"); got != ">=2024-03-01T00:00:00Z" {
+				t.Errorf("created = %q", got)
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next", <%s%s?page=2>; rel="last"`,
+				server.URL, r.URL.Path, server.URL, r.URL.Path))
+			writeTestJSON(t, w, WorkflowRunsResponse{WorkflowRuns: []WorkflowRun{{ID: 1}, {ID: 2}}})
+		case "2":
+			writeTestJSON(t, w, WorkflowRunsResponse{WorkflowRuns: []WorkflowRun{{ID: 3}}})
+		default:
+			http.Error(w, "unexpected page", http.StatusBadRequest)
+		}
+	})
+
+	runs, err := client.GetWorkflowRuns("org", "repo", 0, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[2].ID != 3 {
+		t.Errorf("runs = %+v", runs)
+	}
+}
+
+func TestGetDeploymentsStopsAtSince(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	deployment := func(id int, updatedAt time.Time) Deployment {
+		return Deployment{ID: id, CreatedAt: updatedAt, UpdatedAt: updatedAt}
+	}
+
+	var server *httptest.Server
+	client, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		next := func(page int) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, server.URL, r.URL.Path, page))
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			next(2)
+			writeTestJSON(t, w, []Deployment{deployment(3, since.Add(2*time.Hour)), deployment(2, since.Add(time.Hour))})
+		case "2":
+			next(3)
+			writeTestJSON(t, w, []Deployment{deployment(1, since), deployment(0, since.Add(-time.Hour))})
+		case "3":
+			next(4)
+			writeTestJSON(t, w, []Deployment{deployment(-1, since.Add(-2*time.Hour))})
+		default:
+			t.Errorf("fetched page %s past since", r.URL.Query().Get("page"))
+			http.Error(w, "unexpected page", http.StatusBadRequest)
+		}
+	})
+
+	deployments, err := client.GetDeployments("org", "repo", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments) != 3 || deployments[2].ID != 1 {
+		t.Errorf("deployments = %+v", deployments)
+	}
+}
@@ -0,0 +1,237 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type Client struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+
+	// rateLimitRemaining is the remaining request quota reported by the
+	// last response, or -1 before any response reported it.
+	rateLimitRemaining atomic.Int64
+}
+
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	WebURL            string `json:"web_url"`
+	Archived          bool   `json:"archived"`
+}
+
+type Pipeline struct {
+	ID             int        `json:"id"`
+	IID            int        `json:"iid"`
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	Source         string     `json:"source"`
+	Ref            string     `json:"ref"`
+	SHA            string     `json:"sha"`
+	Duration       int        `json:"duration"`
+	QueuedDuration float64    `json:"queued_duration"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+type Job struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Stage          string     `json:"stage"`
+	Status         string     `json:"status"`
+	Duration       float64    `json:"duration"`
+	QueuedDuration float64    `json:"queued_duration"`
+	CreatedAt      time.Time  `json:"created_at"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+type User struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type MergeRequest struct {
+	IID          int        `json:"iid"`
+	Title        string     `json:"title"`
+	State        string     `json:"state"`
+	Author       User       `json:"author"`
+	SourceBranch string     `json:"source_branch"`
+	TargetBranch string     `json:"target_branch"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	MergedAt     *time.Time `json:"merged_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+}
+
+type Environment struct {
+	Name string `json:"name"`
+}
+
+type Deployment struct {
+	ID          int         `json:"id"`
+	Status      string      `json:"status"`
+	Ref         string      `json:"ref"`
+	SHA         string      `json:"sha"`
+	Environment Environment `json:"environment"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// NewClient returns a client for gitlab.com or a self-managed instance. The
+// base URL is the instance root, e.g. https://gitlab.example.com.
+func NewClient(baseURL, token string) *Client {
+	c := &Client{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/") + "/api/v4",
+	}
+	c.rateLimitRemaining.Store(-1)
+	return c
+}
+
+// RateLimitRemaining returns the request quota left as of the last response.
+// Instances without rate limiting do not report one.
+func (c *Client) RateLimitRemaining() (int, bool) {
+	remaining := c.rateLimitRemaining.Load()
+	return int(remaining), remaining >= 0
+}
+
+func (c *Client) GetProject(path string) (*Project, error) {
+	var project Project
+	if _, err := c.get("/projects/"+url.PathEscape(path), url.Values{}, &project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+func (c *Client) GetGroupProjects(group string) ([]Project, error) {
+	params := url.Values{}
+	params.Set("include_subgroups", "true")
+	params.Set("archived", "false")
+
+	return getAll[Project](c, "/groups/"+url.PathEscape(group)+"/projects", params)
+}
+
+func (c *Client) GetPipelines(projectID int, updatedAfter time.Time) ([]Pipeline, error) {
+	params := url.Values{}
+	params.Set("updated_after", updatedAfter.UTC().Format(time.RFC3339))
+	params.Set("order_by", "updated_at")
+
+	return getAll[Pipeline](c, fmt.Sprintf("/projects/%d/pipelines", projectID), params)
+}
+
+// GetPipeline returns a single pipeline. Unlike the list endpoint it includes
+// durations and start/finish times.
+func (c *Client) GetPipeline(projectID, pipelineID int) (*Pipeline, error) {
+	var pipeline Pipeline
+	if _, err := c.get(fmt.Sprintf("/projects/%d/pipelines/%d", projectID, pipelineID), url.Values{}, &pipeline); err != nil {
+		return nil, err
+	}
+
+	return &pipeline, nil
+}
+
+func (c *Client) GetPipelineJobs(projectID, pipelineID int) ([]Job, error) {
+	params := url.Values{}
+	params.Set("include_retried", "true")
+
+	return getAll[Job](c, fmt.Sprintf("/projects/%d/pipelines/%d/jobs", projectID, pipelineID), params)
+}
+
+func (c *Client) GetMergeRequests(projectID int, updatedAfter time.Time) ([]MergeRequest, error) {
+	params := url.Values{}
+	params.Set("state", "all")
+	params.Set("updated_after", updatedAfter.UTC().Format(time.RFC3339))
+
+	return getAll[MergeRequest](c, fmt.Sprintf("/projects/%d/merge_requests", projectID), params)
+}
+
+func (c *Client) GetDeployments(projectID int, updatedAfter time.Time) ([]Deployment, error) {
+	params := url.Values{}
+	params.Set("updated_after", updatedAfter.UTC().Format(time.RFC3339))
+	params.Set("order_by", "updated_at")
+
+	return getAll[Deployment](c, fmt.Sprintf("/projects/%d/deployments", projectID), params)
+}
+
+func (c *Client) GetCurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if _, err := c.getContext(ctx, "/user", url.Values{}, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// getAll follows the X-Next-Page header until the last page.
+func getAll[T any](c *Client, path string, params url.Values) ([]T, error) {
+	params.Set("per_page", "100")
+	params.Set("page", "1")
+
+	var items []T
+	for {
+		var page []T
+		header, err := c.get(path, params, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page...)
+
+		next := header.Get("X-Next-Page")
+		if next == "" {
+			return items, nil
+		}
+		params.Set("page", next)
+	}
+}
+
+func (c *Client) get(path string, params url.Values, v interface{}) (http.Header, error) {
+	return c.getContext(context.Background(), path, params, v)
+}
+
+func (c *Client) getContext(ctx context.Context, path string, params url.Values, v interface{}) (http.Header, error) {
+	url := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if remaining, err := strconv.ParseInt(resp.Header.Get("RateLimit-Remaining"), 10, 64); err == nil {
+		c.rateLimitRemaining.Store(remaining)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp.Header, nil
+}
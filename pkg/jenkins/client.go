@@ -0,0 +1,257 @@
+package jenkins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	buildsPageSize = 50
+
+	jobTree   = "_class,name,fullName,jobs[_class,name,fullName]"
+	buildTree = "number,result,building,duration,timestamp,url," +
+		"actions[_class,causes[shortDescription,userId],lastBuiltRevision[SHA1,branch[name]],queuingDurationMillis]"
+)
+
+type Client struct {
+	username   string
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Job is a buildable job. Folders, organization folders and multibranch
+// projects are walked and not returned themselves; Parent and ParentClass
+// describe the container a job was found in.
+type Job struct {
+	Class    string `json:"_class"`
+	Name     string `json:"name"`
+	FullName string `json:"fullName"`
+	Jobs     []Job  `json:"jobs"`
+
+	Parent      string `json:"-"`
+	ParentClass string `json:"-"`
+}
+
+// IsMultibranch reports whether the job is a branch of a multibranch pipeline.
+func (j *Job) IsMultibranch() bool {
+	return strings.HasSuffix(j.ParentClass, "WorkflowMultiBranchProject")
+}
+
+type Build struct {
+	Number    int      `json:"number"`
+	Result    string   `json:"result"`
+	Building  bool     `json:"building"`
+	Duration  int64    `json:"duration"`  // Milliseconds
+	Timestamp int64    `json:"timestamp"` // Milliseconds since epoch
+	URL       string   `json:"url"`
+	Actions   []Action `json:"actions"`
+}
+
+type Action struct {
+	Class                 string    `json:"_class"`
+	Causes                []Cause   `json:"causes"`
+	LastBuiltRevision     *Revision `json:"lastBuiltRevision"`
+	QueuingDurationMillis int64     `json:"queuingDurationMillis"`
+}
+
+type Cause struct {
+	ShortDescription string `json:"shortDescription"`
+	UserID           string `json:"userId"`
+}
+
+type Revision struct {
+	SHA1   string   `json:"SHA1"`
+	Branch []Branch `json:"branch"`
+}
+
+type Branch struct {
+	Name string `json:"name"`
+}
+
+type Info struct {
+	Mode            string `json:"mode"`
+	NodeDescription string `json:"nodeDescription"`
+}
+
+func (b *Build) StartedAt() time.Time {
+	return time.UnixMilli(b.Timestamp)
+}
+
+func (b *Build) GetDurationSeconds() int {
+	return int(b.Duration / 1000)
+}
+
+// Status returns the build result, or in_progress while the build runs.
+func (b *Build) Status() string {
+	if b.Building || b.Result == "" {
+		return "in_progress"
+	}
+	return b.Result
+}
+
+// Cause returns the description of the first cause that triggered the build.
+func (b *Build) Cause() string {
+	for _, action := range b.Actions {
+		for _, cause := range action.Causes {
+			if cause.ShortDescription != "" {
+				return cause.ShortDescription
+			}
+		}
+	}
+	return ""
+}
+
+// Revision returns the SCM revision the build checked out, if any.
+func (b *Build) Revision() *Revision {
+	for _, action := range b.Actions {
+		if action.LastBuiltRevision != nil {
+			return action.LastBuiltRevision
+		}
+	}
+	return nil
+}
+
+// QueueDurationSeconds returns the time spent in the queue. It is recorded by
+// the Metrics plugin and is zero without it.
+func (b *Build) QueueDurationSeconds() int {
+	for _, action := range b.Actions {
+		if action.QueuingDurationMillis > 0 {
+			return int(action.QueuingDurationMillis / 1000)
+		}
+	}
+	return 0
+}
+
+func NewClient(baseURL, username, token string) *Client {
+	return &Client{
+		username:   username,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// GetJobs walks the job with the given full name, or the whole instance if it
+// is empty, and returns every buildable job beneath it.
+func (c *Client) GetJobs(fullName string) ([]Job, error) {
+	var root Job
+	if err := c.get(jobPath(fullName), jobTree, &root); err != nil {
+		return nil, err
+	}
+
+	if root.Jobs == nil {
+		return []Job{root}, nil
+	}
+
+	var jobs []Job
+	for _, child := range root.Jobs {
+		if child.FullName == "" {
+			child.FullName = strings.TrimPrefix(fullName+"/"+child.Name, "/")
+		}
+
+		children, err := c.GetJobs(child.FullName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, job := range children {
+			if job.Parent == "" {
+				job.Parent = fullName
+				job.ParentClass = root.Class
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// GetBuilds returns builds of a job started at or after since, newest first.
+func (c *Client) GetBuilds(fullName string, since time.Time) ([]Build, error) {
+	var builds []Build
+	for start := 0; ; start += buildsPageSize {
+		var response struct {
+			AllBuilds []Build `json:"allBuilds"`
+		}
+
+		tree := fmt.Sprintf("allBuilds[%s]{%d,%d}", buildTree, start, start+buildsPageSize)
+		if err := c.get(jobPath(fullName), tree, &response); err != nil {
+			return nil, err
+		}
+
+		for _, build := range response.AllBuilds {
+			if build.StartedAt().Before(since) {
+				return builds, nil
+			}
+			builds = append(builds, build)
+		}
+
+		if len(response.AllBuilds) < buildsPageSize {
+			return builds, nil
+		}
+	}
+}
+
+func (c *Client) GetInfo(ctx context.Context) (*Info, error) {
+	var info Info
+	if err := c.getContext(ctx, "", "mode,nodeDescription", &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// jobPath turns a full name such as folder/project/main into the
+// /job/folder/job/project/job/main URL path.
+func jobPath(fullName string) string {
+	if fullName == "" {
+		return ""
+	}
+
+	var path strings.Builder
+	for _, segment := range strings.Split(fullName, "/") {
+		path.WriteString("/job/")
+		path.WriteString(url.PathEscape(segment))
+	}
+	return path.String()
+}
+
+func (c *Client) get(path, tree string, v interface{}) error {
+	return c.getContext(context.Background(), path, tree, v)
+}
+
+func (c *Client) getContext(ctx context.Context, path, tree string, v interface{}) error {
+	params := url.Values{}
+	params.Set("tree", tree)
+	url := fmt.Sprintf("%s%s/api/json?%s", c.baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(c.username, c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
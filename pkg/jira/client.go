@@ -1,9 +1,11 @@
 package jira
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -15,8 +17,9 @@ type Client struct {
 }
 
 type Issue struct {
-	Key    string `json:"key"`
-	Fields Fields `json:"fields"`
+	Key       string    `json:"key"`
+	Fields    Fields    `json:"fields"`
+	Changelog Changelog `json:"changelog"`
 }
 
 type Fields struct {
@@ -27,23 +30,118 @@ type Fields struct {
 	Created   time.Time `json:"created"`
 	Updated   time.Time `json:"updated"`
 	Resolved  *time.Time `json:"resolutiondate"`
+	Labels    []string   `json:"labels"`
+	Components []Component `json:"components"`
+
+	// Custom holds every field as returned, for custom fields such as
+	// sprints and story points whose ids differ between instances.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+func (f *Fields) UnmarshalJSON(data []byte) error {
+	type fields Fields
+	if err := json.Unmarshal(data, (*fields)(f)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &f.Custom)
+}
+
+// Float returns a numeric custom field, or nil when it is unset.
+func (f *Fields) Float(id string) *float64 {
+	var value *float64
+	if raw, ok := f.Custom[id]; ok {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil
+		}
+	}
+	return value
+}
+
+// Sprint returns the most recent sprint of a Jira Cloud sprint field.
+func (f *Fields) Sprint(id string) *Sprint {
+	var sprints []Sprint
+	if raw, ok := f.Custom[id]; ok {
+		if err := json.Unmarshal(raw, &sprints); err != nil {
+			return nil
+		}
+	}
+	if len(sprints) == 0 {
+		return nil
+	}
+	return &sprints[len(sprints)-1]
 }
 
 type Status struct {
-	Name string `json:"name"`
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	StatusCategory StatusCategory `json:"statusCategory"`
+}
+
+// StatusCategory key is one of new, indeterminate or done.
+type StatusCategory struct {
+	Key string `json:"key"`
+}
+
+type Sprint struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	State        string     `json:"state"`
+	StartDate    *time.Time `json:"startDate"`
+	EndDate      *time.Time `json:"endDate"`
+	CompleteDate *time.Time `json:"completeDate"`
+}
+
+type Changelog struct {
+	Histories []History `json:"histories"`
+}
+
+type History struct {
+	Author  *User         `json:"author"`
+	Created time.Time     `json:"created"`
+	Items   []HistoryItem `json:"items"`
+}
+
+type HistoryItem struct {
+	Field      string `json:"field"`
+	FromString string `json:"fromString"`
+	ToString   string `json:"toString"`
+	To         string `json:"to"`
 }
 
 type Priority struct {
 	Name string `json:"name"`
 }
 
+type Component struct {
+	Name string `json:"name"`
+}
+
+// User is a Jira Cloud user, identified by accountId, or a Jira Server user,
+// identified by key. The email is hidden unless the user's profile allows it.
 type User struct {
-	DisplayName string `json:"displayName"`
+	AccountID    string `json:"accountId"`
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+// ID returns the stable id of a user.
+func (u *User) ID() string {
+	if u.AccountID != "" {
+		return u.AccountID
+	}
+	if u.Key != "" {
+		return u.Key
+	}
+	return u.Name
 }
 
 type SearchResponse struct {
-	Issues []Issue `json:"issues"`
-	Total  int     `json:"total"`
+	Issues     []Issue `json:"issues"`
+	StartAt    int     `json:"startAt"`
+	MaxResults int     `json:"maxResults"`
+	Total      int     `json:"total"`
 }
 
 func NewClient(baseURL, email, token string) *Client {
@@ -55,12 +153,61 @@ func NewClient(baseURL, email, token string) *Client {
 	}
 }
 
+// SearchIssues returns every issue matching the JQL with its changelog.
 func (c *Client) SearchIssues(jql string) ([]Issue, error) {
-	url := fmt.Sprintf("%s/rest/api/2/search?jql=%s", c.baseURL, jql)
+	var issues []Issue
+
+	for {
+		params := url.Values{}
+		params.Set("jql", jql)
+		params.Set("startAt", fmt.Sprintf("%d", len(issues)))
+		params.Set("maxResults", "100")
+		params.Set("expand", "changelog")
+
+		var response SearchResponse
+		if err := c.get("/rest/api/2/search", params, &response); err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, response.Issues...)
+
+		if len(response.Issues) == 0 || len(issues) >= response.Total {
+			break
+		}
+	}
+
+	return issues, nil
+}
+
+// GetStatuses returns every workflow status with its category.
+func (c *Client) GetStatuses() ([]Status, error) {
+	var statuses []Status
+	if err := c.get("/rest/api/2/status", url.Values{}, &statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+func (c *Client) GetCurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.getContext(ctx, "/rest/api/2/myself", url.Values{}, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (c *Client) get(path string, params url.Values, v interface{}) error {
+	return c.getContext(context.Background(), path, params, v)
+}
+
+func (c *Client) getContext(ctx context.Context, path string, params url.Values, v interface{}) error {
+	url := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.SetBasicAuth(c.email, c.token)
@@ -68,18 +215,17 @@ func (c *Client) SearchIssues(jql string) ([]Issue, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
 
-	var response SearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return response.Issues, nil
-}
\ No newline at end of file
+	return nil
+}
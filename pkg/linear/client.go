@@ -0,0 +1,308 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Nested connections count towards Linear's query complexity limit, so pages
+// are kept smaller than the API maximum of 250.
+const (
+	issuesPageSize  = 50
+	historyPageSize = 50
+	cyclesPageSize  = 100
+)
+
+type Client struct {
+	token      string
+	httpClient *http.Client
+	url        string
+}
+
+// State type is one of triage, backlog, unstarted, started, completed or
+// canceled.
+type State struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type Team struct {
+	Key string `json:"key"`
+}
+
+type Cycle struct {
+	ID          string     `json:"id"`
+	Number      int        `json:"number"`
+	Name        string     `json:"name"`
+	Team        Team       `json:"team"`
+	StartsAt    time.Time  `json:"startsAt"`
+	EndsAt      time.Time  `json:"endsAt"`
+	CompletedAt *time.Time `json:"completedAt"`
+}
+
+// DisplayName returns the cycle name, which is optional in Linear.
+func (c *Cycle) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s Cycle %d", c.Team.Key, c.Number)
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type HistoryEntry struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     *User     `json:"actor"`
+	FromState *State    `json:"fromState"`
+	ToState   *State    `json:"toState"`
+}
+
+type Issue struct {
+	ID            string     `json:"id"`
+	Identifier    string     `json:"identifier"`
+	Title         string     `json:"title"`
+	PriorityLabel string     `json:"priorityLabel"`
+	Estimate      *float64   `json:"estimate"`
+	State         State      `json:"state"`
+	Team          Team       `json:"team"`
+	Assignee      *User      `json:"assignee"`
+	Cycle         *Cycle     `json:"cycle"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+	CanceledAt    *time.Time `json:"canceledAt"`
+	Labels        struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	History struct {
+		Nodes    []HistoryEntry `json:"nodes"`
+		PageInfo pageInfo       `json:"pageInfo"`
+	} `json:"history"`
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+const issuesQuery = `
+query Issues($first: Int!, $after: String, $filter: IssueFilter, $historyFirst: Int!) {
+  issues(first: $first, after: $after, filter: $filter, orderBy: updatedAt) {
+    nodes {
+      id identifier title priorityLabel estimate createdAt updatedAt completedAt canceledAt
+      state { id name type }
+      team { key }
+      assignee { id name email }
+      cycle { id number name startsAt endsAt completedAt team { key } }
+      labels { nodes { name } }
+      history(first: $historyFirst) {
+        nodes { createdAt actor { id name email } fromState { id name type } toState { id name type } }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+const historyQuery = `
+query History($id: String!, $first: Int!, $after: String) {
+  issue(id: $id) {
+    history(first: $first, after: $after) {
+      nodes { createdAt actor { id name email } fromState { id name type } toState { id name type } }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+const cyclesQuery = `
+query Cycles($first: Int!, $after: String, $filter: CycleFilter) {
+  cycles(first: $first, after: $after, filter: $filter) {
+    nodes { id number name startsAt endsAt completedAt team { key } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// NewClient returns a client for the GraphQL endpoint, normally
+// https://api.linear.app/graphql. The token is a personal API key.
+func NewClient(url, token string) *Client {
+	return &Client{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		url:        url,
+	}
+}
+
+// GetIssues returns issues updated since the given time with their full
+// state history. An empty teams returns issues of every team.
+func (c *Client) GetIssues(teams []string, since time.Time) ([]Issue, error) {
+	filter := map[string]interface{}{
+		"updatedAt": map[string]interface{}{"gte": since.UTC().Format(time.RFC3339)},
+	}
+	if len(teams) > 0 {
+		filter["team"] = teamFilter(teams)
+	}
+
+	var issues []Issue
+	err := paginate(c, issuesQuery, "issues", map[string]interface{}{
+		"first":        issuesPageSize,
+		"filter":       filter,
+		"historyFirst": historyPageSize,
+	}, func(page []Issue) {
+		issues = append(issues, page...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range issues {
+		if err := c.completeHistory(&issues[i]); err != nil {
+			return nil, fmt.Errorf("failed to get history of %s: %w", issues[i].Identifier, err)
+		}
+	}
+	return issues, nil
+}
+
+// completeHistory fetches the history pages of an issue after the first,
+// which is returned with the issue itself.
+func (c *Client) completeHistory(issue *Issue) error {
+	history := &issue.History
+	for history.PageInfo.HasNextPage && history.PageInfo.EndCursor != "" {
+		var data struct {
+			Issue struct {
+				History struct {
+					Nodes    []HistoryEntry `json:"nodes"`
+					PageInfo pageInfo       `json:"pageInfo"`
+				} `json:"history"`
+			} `json:"issue"`
+		}
+		err := c.query(historyQuery, map[string]interface{}{
+			"id":    issue.ID,
+			"first": historyPageSize,
+			"after": history.PageInfo.EndCursor,
+		}, &data)
+		if err != nil {
+			return err
+		}
+
+		history.Nodes = append(history.Nodes, data.Issue.History.Nodes...)
+		history.PageInfo = data.Issue.History.PageInfo
+	}
+	return nil
+}
+
+func (c *Client) GetCycles(teams []string) ([]Cycle, error) {
+	variables := map[string]interface{}{"first": cyclesPageSize}
+	if len(teams) > 0 {
+		variables["filter"] = map[string]interface{}{"team": teamFilter(teams)}
+	}
+
+	var cycles []Cycle
+	err := paginate(c, cyclesQuery, "cycles", variables, func(page []Cycle) {
+		cycles = append(cycles, page...)
+	})
+	return cycles, err
+}
+
+func (c *Client) GetViewer(ctx context.Context) (*User, error) {
+	var data struct {
+		Viewer User `json:"viewer"`
+	}
+	if err := c.queryContext(ctx, `query { viewer { name email } }`, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return &data.Viewer, nil
+}
+
+func teamFilter(teams []string) map[string]interface{} {
+	return map[string]interface{}{"key": map[string]interface{}{"in": teams}}
+}
+
+// paginate runs a connection query, following endCursor while hasNextPage is
+// set and passing the nodes of each page to fn.
+func paginate[T any](c *Client, query, connection string, variables map[string]interface{}, fn func([]T)) error {
+	for {
+		var data map[string]struct {
+			Nodes    []T      `json:"nodes"`
+			PageInfo pageInfo `json:"pageInfo"`
+		}
+		if err := c.query(query, variables, &data); err != nil {
+			return err
+		}
+
+		page := data[connection]
+		fn(page.Nodes)
+
+		if !page.PageInfo.HasNextPage || page.PageInfo.EndCursor == "" {
+			return nil
+		}
+		variables["after"] = page.PageInfo.EndCursor
+	}
+}
+
+func (c *Client) query(query string, variables map[string]interface{}, v interface{}) error {
+	return c.queryContext(context.Background(), query, variables, v)
+}
+
+func (c *Client) queryContext(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("query failed: %s", strings.Join(messages, "; "))
+	}
+
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		return fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	return nil
+}
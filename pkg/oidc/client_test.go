@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"code-pulse/pkg/oidc/oidctest"
+)
+
+func validClaims(issuer *oidctest.Issuer) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    issuer.URL,
+		"sub":    "user-1",
+		"aud":    oidctest.ClientID,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"nonce":  "nonce-1",
+		"email":  "ada@example.com",
+		"name":   "Ada",
+		"groups": []string{"payments"},
+	}
+}
+
+func newTestClient(issuer *oidctest.Issuer) *Client {
+	return NewClient(issuer.URL+"/", oidctest.ClientID, oidctest.ClientSecret, "https://pulse.example.com/auth/callback",
+		[]string{"openid", "email"})
+}
+
+func TestDiscoverChecksIssuer(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+
+	discovery, err := newTestClient(issuer).Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discovery.TokenEndpoint != issuer.URL+"/token" {
+		t.Errorf("token endpoint = %q", discovery.TokenEndpoint)
+	}
+
+	// The same server reached under another name reports a different issuer.
+	other := NewClient(strings.Replace(issuer.URL, "127.0.0.1", "localhost", 1), oidctest.ClientID, oidctest.ClientSecret, "", nil)
+	if _, err := other.Discover(); err == nil || !strings.Contains(err.Error(), "reports issuer") {
+		t.Errorf("expected an issuer mismatch, got %v", err)
+	}
+}
+
+func TestAuthCodeURLUsesPKCE(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+
+	raw, err := newTestClient(issuer).AuthCodeURL("state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge := sha256.Sum256([]byte("verifier-1"))
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             oidctest.ClientID,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"scope":                 "openid email",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if !strings.HasPrefix(raw, issuer.URL+"/authorize?") {
+		t.Errorf("authorization URL = %q", raw)
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+	issuer.Claims = validClaims(issuer)
+	client := newTestClient(issuer)
+
+	token, err := client.Exchange(oidctest.Code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := client.VerifyIDToken(token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "ada@example.com" || claims.Name != "Ada" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if groups := claims.Strings("groups"); len(groups) != 1 || groups[0] != "payments" {
+		t.Errorf("groups = %v", groups)
+	}
+
+	if _, err := client.Exchange("code-2", "verifier-1"); err == nil {
+		t.Error("expected an error for an unknown code")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+	client := newTestClient(issuer)
+
+	tests := []struct {
+		name   string
+		kid    string
+		modify func(map[string]interface{})
+	}{
+		{"nonce", oidctest.KeyID, func(c map[string]interface{}) { c["nonce"] = "other" }},
+		{"audience", oidctest.KeyID, func(c map[string]interface{}) { c["aud"] = []string{"someone-else"} }},
+		{"expired", oidctest.KeyID, func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"missing expiry", oidctest.KeyID, func(c map[string]interface{}) { delete(c, "exp") }},
+		{"issuer", oidctest.KeyID, func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }},
+		{"subject", oidctest.KeyID, func(c map[string]interface{}) { delete(c, "sub") }},
+		{"unknown key", "key-2", func(c map[string]interface{}) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(issuer)
+			tt.modify(claims)
+			if _, err := client.VerifyIDToken(issuer.Sign(t, tt.kid, claims), "nonce-1"); err == nil {
+				t.Error("expected the ID token to be rejected")
+			}
+		})
+	}
+
+	t.Run("signature", func(t *testing.T) {
+		token := issuer.Sign(t, oidctest.KeyID, validClaims(issuer))
+		parts := strings.Split(token, ".")
+		forged, _ := json.Marshal(map[string]interface{}{
+			"iss": issuer.URL, "sub": "admin", "aud": oidctest.ClientID, "exp": time.Now().Add(time.Hour).Unix(), "nonce": "nonce-1",
+		})
+		parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+		if _, err := client.VerifyIDToken(strings.Join(parts, "."), "nonce-1"); err == nil {
+			t.Error("expected a token with altered claims to be rejected")
+		}
+	})
+
+	t.Run("none algorithm", func(t *testing.T) {
+		header, _ := json.Marshal(jwtHeader{Alg: "none", Kid: oidctest.KeyID})
+		payload, _ := json.Marshal(validClaims(issuer))
+		token := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		if _, err := client.VerifyIDToken(token, "nonce-1"); err == nil {
+			t.Error("expected an unsigned token to be rejected")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// Package oidctest runs an OpenID provider for tests of OpenID Connect
+// clients.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// The client credentials the token endpoint accepts, the one authorization
+// code it redeems and the id of the key tokens are signed with.
+const (
+	ClientID     = "client"
+	ClientSecret = "secret"
+	Code         = "code-1"
+	KeyID        = "key-1"
+)
+
+// Issuer is an OpenID provider serving discovery, a JWKS with one RSA key and
+// a token endpoint that redeems Code for an ID token carrying Claims. Nonce,
+// when set, is added to the ID token unless Claims hold one.
+type Issuer struct {
+	*httptest.Server
+	Claims map[string]interface{}
+	Nonce  string
+
+	key *rsa.PrivateKey
+}
+
+// NewIssuer starts an issuer that is closed when the test ends.
+func NewIssuer(t *testing.T) *Issuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &Issuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != ClientID || secret != ClientSecret {
+		http.Error(w, "invalid_client", http.StatusUnauthorized)
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != Code ||
+		r.PostFormValue("code_verifier") == "" {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	claims := make(map[string]interface{}, len(i.Claims)+1)
+	if i.Nonce != "" {
+		claims["nonce"] = i.Nonce
+	}
+	for name, value := range i.Claims {
+		claims[name] = value
+	}
+	idToken, err := i.sign(KeyID, claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// Sign returns an RS256 ID token with the given claims, signed with the
+// issuer's key but naming kid as the key id.
+func (i *Issuer) Sign(t *testing.T, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	token, err := i.sign(kid, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (i *Issuer) sign(kid string, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
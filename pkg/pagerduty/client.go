@@ -0,0 +1,184 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pageSize = 100
+
+// ErrNotFound is returned when an incident no longer exists.
+var ErrNotFound = errors.New("not found")
+
+type Client struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+type Reference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary"`
+}
+
+type Incident struct {
+	ID                 string     `json:"id"`
+	IncidentNumber     int        `json:"incident_number"`
+	Title              string     `json:"title"`
+	Status             string     `json:"status"`
+	Urgency            string     `json:"urgency"`
+	Service            Reference  `json:"service"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastStatusChangeAt time.Time  `json:"last_status_change_at"`
+	ResolvedAt         *time.Time `json:"resolved_at"`
+}
+
+type LogEntry struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewClient returns a REST API v2 client. The base URL is normally
+// https://api.pagerduty.com.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// GetIncidents returns incidents created since the given time. An empty
+// serviceIDs returns incidents of every service.
+func (c *Client) GetIncidents(since time.Time, serviceIDs []string) ([]Incident, error) {
+	params := url.Values{}
+	params.Set("since", since.UTC().Format(time.RFC3339))
+	params.Set("until", time.Now().UTC().Format(time.RFC3339))
+	for _, id := range serviceIDs {
+		params.Add("service_ids[]", id)
+	}
+
+	return getAll[Incident](c, "/incidents", "incidents", params)
+}
+
+// GetOpenIncidents returns triggered and acknowledged incidents regardless of
+// when they were created.
+func (c *Client) GetOpenIncidents(serviceIDs []string) ([]Incident, error) {
+	params := url.Values{}
+	params.Set("date_range", "all")
+	params.Add("statuses[]", "triggered")
+	params.Add("statuses[]", "acknowledged")
+	for _, id := range serviceIDs {
+		params.Add("service_ids[]", id)
+	}
+
+	return getAll[Incident](c, "/incidents", "incidents", params)
+}
+
+// GetIncident returns a single incident in its current state.
+func (c *Client) GetIncident(id string) (*Incident, error) {
+	var response struct {
+		Incident Incident `json:"incident"`
+	}
+	if err := c.get("/incidents/"+url.PathEscape(id), url.Values{}, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Incident, nil
+}
+
+// GetLogEntries returns the overview log of an incident: triggers,
+// acknowledgements, escalations and resolution.
+func (c *Client) GetLogEntries(incidentID string) ([]LogEntry, error) {
+	params := url.Values{}
+	params.Set("is_overview", "true")
+
+	return getAll[LogEntry](c, "/incidents/"+url.PathEscape(incidentID)+"/log_entries", "log_entries", params)
+}
+
+func (c *Client) GetAbilities(ctx context.Context) ([]string, error) {
+	var response struct {
+		Abilities []string `json:"abilities"`
+	}
+	if err := c.getContext(ctx, "/abilities", url.Values{}, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Abilities, nil
+}
+
+// getAll follows offset pagination while the response reports more results.
+// Items are read from the given key of each page.
+func getAll[T any](c *Client, path, key string, params url.Values) ([]T, error) {
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	var items []T
+	for offset := 0; ; offset += pageSize {
+		params.Set("offset", strconv.Itoa(offset))
+
+		var page map[string]json.RawMessage
+		if err := c.get(path, params, &page); err != nil {
+			return nil, err
+		}
+
+		var pageItems []T
+		if err := json.Unmarshal(page[key], &pageItems); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		items = append(items, pageItems...)
+
+		var more bool
+		if raw, ok := page["more"]; ok {
+			if err := json.Unmarshal(raw, &more); err != nil {
+				return nil, fmt.Errorf("failed to decode pagination: %w", err)
+			}
+		}
+		if !more || len(pageItems) == 0 {
+			return items, nil
+		}
+	}
+}
+
+func (c *Client) get(path string, params url.Values, v interface{}) error {
+	return c.getContext(context.Background(), path, params, v)
+}
+
+func (c *Client) getContext(ctx context.Context, path string, params url.Values, v interface{}) error {
+	url := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Token token="+c.token)
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
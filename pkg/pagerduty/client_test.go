@@ -0,0 +1,123 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Token token=secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL+"/", "secret")
+}
+
+func writeTestJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+func TestGetIncidentsFollowsOffsets(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/incidents" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if got := query.Get("since"); got != "2024-03-01T00:00:00Z" {
+			t.Errorf("since = %q", got)
+		}
+		if got := query["service_ids[]"]; len(got) != 2 || got[0] != "S1" || got[1] != "S2" {
+			t.Errorf("service_ids[] = %v", got)
+		}
+
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		switch offset {
+		case 0:
+			writeTestJSON(t, w, map[string]interface{}{
+				"incidents": []map[string]interface{}{{"id": "P1", "incident_number": 1}},
+				"more":      true,
+			})
+		case pageSize:
+			writeTestJSON(t, w, map[string]interface{}{
+				"incidents": []map[string]interface{}{{"id": "P2", "incident_number": 2}},
+				"more":      false,
+			})
+		default:
+			t.Errorf("unexpected offset %d", offset)
+			http.Error(w, "unexpected offset", http.StatusBadRequest)
+		}
+	})
+
+	incidents, err := client.GetIncidents(since, []string{"S1", "S2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 2 || incidents[0].ID != "P1" || incidents[1].ID != "P2" {
+		t.Fatalf("incidents = %+v", incidents)
+	}
+}
+
+func TestGetIncident(t *testing.T) {
+	resolvedAt := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/incidents/P1":
+			writeTestJSON(t, w, map[string]interface{}{
+				"incident": map[string]interface{}{
+					"id":          "P1",
+					"status":      "resolved",
+					"service":     map[string]string{"id": "S1", "summary": "API"},
+					"resolved_at": resolvedAt,
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	incident, err := client.GetIncident("P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incident.Status != "resolved" || incident.Service.ID != "S1" {
+		t.Errorf("incident = %+v", incident)
+	}
+	if incident.ResolvedAt == nil || !incident.ResolvedAt.Equal(resolvedAt) {
+		t.Errorf("resolved_at = %v", incident.ResolvedAt)
+	}
+
+	if _, err := client.GetIncident("P2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing incident error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetAbilitiesReportsFailures(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if _, err := client.GetAbilities(context.Background()); err == nil {
+		t.Fatal("expected an error for a 401 response")
+	}
+}
@@ -1,13 +1,25 @@
 package sonarqube
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	dateFormat  = "2006-01-02T15:04:05-0700"
+	pageSize    = 500
+	searchLimit = 10000
+
+	// issueKeysPerRequest keeps issue lookups by key within URL length limits.
+	issueKeysPerRequest = 100
+)
+
 type Client struct {
 	token      string
 	httpClient *http.Client
@@ -15,9 +27,31 @@ type Client struct {
 }
 
 type Metric struct {
-	Metric    string `json:"metric"`
+	Metric    string   `json:"metric"`
+	Value     string   `json:"value"`
+	Period    *Period  `json:"period"`
+	Periods   []Period `json:"periods"`
+	Component string   `json:"component"`
+}
+
+// NewCodeValue returns the new-code period value of a measure. SonarQube 8.1+
+// reports it in "period"; older servers use the first entry of "periods".
+func (m Metric) NewCodeValue() (string, bool) {
+	if m.Period != nil {
+		return m.Period.Value, true
+	}
+	for _, period := range m.Periods {
+		if period.Index == 1 {
+			return period.Value, true
+		}
+	}
+	return "", false
+}
+
+type Period struct {
+	Index     int    `json:"index"`
 	Value     string `json:"value"`
-	Component string `json:"component"`
+	BestValue bool   `json:"bestValue"`
 }
 
 type Component struct {
@@ -30,6 +64,137 @@ type MeasuresResponse struct {
 	Component Component `json:"component"`
 }
 
+type TreeComponent struct {
+	Key       string   `json:"key"`
+	Name      string   `json:"name"`
+	Qualifier string   `json:"qualifier"`
+	Path      string   `json:"path"`
+	Language  string   `json:"language"`
+	Metrics   []Metric `json:"measures"`
+}
+
+type ComponentTreeResponse struct {
+	Paging     Paging          `json:"paging"`
+	Components []TreeComponent `json:"components"`
+}
+
+type QualityGateCondition struct {
+	Status         string `json:"status"`
+	MetricKey      string `json:"metricKey"`
+	Comparator     string `json:"comparator"`
+	ErrorThreshold string `json:"errorThreshold"`
+	ActualValue    string `json:"actualValue"`
+}
+
+type ProjectStatus struct {
+	Status     string                 `json:"status"`
+	Conditions []QualityGateCondition `json:"conditions"`
+}
+
+type ProjectStatusResponse struct {
+	ProjectStatus ProjectStatus `json:"projectStatus"`
+}
+
+type AnalysisStatus struct {
+	QualityGateStatus string `json:"qualityGateStatus"`
+}
+
+type Branch struct {
+	Name         string         `json:"name"`
+	IsMain       bool           `json:"isMain"`
+	Type         string         `json:"type"`
+	Status       AnalysisStatus `json:"status"`
+	AnalysisDate *Time          `json:"analysisDate"`
+}
+
+type BranchesResponse struct {
+	Branches []Branch `json:"branches"`
+}
+
+type PullRequest struct {
+	Key          string         `json:"key"`
+	Title        string         `json:"title"`
+	Branch       string         `json:"branch"`
+	Base         string         `json:"base"`
+	Status       AnalysisStatus `json:"status"`
+	AnalysisDate *Time          `json:"analysisDate"`
+}
+
+type PullRequestsResponse struct {
+	PullRequests []PullRequest `json:"pullRequests"`
+}
+
+type Paging struct {
+	PageIndex int `json:"pageIndex"`
+	PageSize  int `json:"pageSize"`
+	Total     int `json:"total"`
+}
+
+type Issue struct {
+	Key          string `json:"key"`
+	Rule         string `json:"rule"`
+	Severity     string `json:"severity"`
+	Type         string `json:"type"`
+	Component    string `json:"component"`
+	Project      string `json:"project"`
+	Line         int    `json:"line"`
+	Status       string `json:"status"`
+	Resolution   string `json:"resolution"`
+	Author       string `json:"author"`
+	CreationDate *Time  `json:"creationDate"`
+	UpdateDate   *Time  `json:"updateDate"`
+	CloseDate    *Time  `json:"closeDate"`
+}
+
+type IssuesResponse struct {
+	Paging Paging  `json:"paging"`
+	Issues []Issue `json:"issues"`
+}
+
+type Hotspot struct {
+	Key                      string `json:"key"`
+	Component                string `json:"component"`
+	Project                  string `json:"project"`
+	SecurityCategory         string `json:"securityCategory"`
+	VulnerabilityProbability string `json:"vulnerabilityProbability"`
+	Status                   string `json:"status"`
+	Resolution               string `json:"resolution"`
+	Line                     int    `json:"line"`
+	RuleKey                  string `json:"ruleKey"`
+	Author                   string `json:"author"`
+	CreationDate             *Time  `json:"creationDate"`
+	UpdateDate               *Time  `json:"updateDate"`
+}
+
+type HotspotsResponse struct {
+	Paging   Paging    `json:"paging"`
+	Hotspots []Hotspot `json:"hotspots"`
+}
+
+// Time decodes the timestamps SonarQube returns, which use a numeric zone
+// offset without a colon (e.g. 2017-04-01T02:15:42+0200).
+type Time struct {
+	time.Time
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		return nil
+	}
+
+	parsed, err := time.Parse(dateFormat, value)
+	if err != nil {
+		parsed, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse time %q: %w", value, err)
+		}
+	}
+
+	t.Time = parsed
+	return nil
+}
+
 func NewClient(baseURL, token string) *Client {
 	return &Client{
 		token:      token,
@@ -38,18 +203,312 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
-func (c *Client) GetProjectMetrics(projectKey string, metricKeys []string) ([]Metric, error) {
+func (c *Client) GetProjectMetrics(projectKey, branch, pullRequest string, metricKeys []string) ([]Metric, error) {
 	params := url.Values{}
 	params.Set("component", projectKey)
+	setAnalysisTarget(params, branch, pullRequest)
 	for _, key := range metricKeys {
 		params.Add("metricKeys", key)
 	}
 
-	url := fmt.Sprintf("%s/api/measures/component?%s", c.baseURL, params.Encode())
-	
-	req, err := http.NewRequest("GET", url, nil)
+	var response MeasuresResponse
+	if err := c.get("/api/measures/component", params, &response); err != nil {
+		return nil, err
+	}
+
+	for i := range response.Component.Metrics {
+		response.Component.Metrics[i].Component = response.Component.Key
+	}
+	resolveNewCodeValues(response.Component.Metrics)
+
+	return response.Component.Metrics, nil
+}
+
+// ErrTruncated is returned along with the results fetched so far when a
+// component tree or issue search holds more than the 10,000 results the API
+// pages through.
+var ErrTruncated = errors.New("results truncated")
+
+// GetComponentTree returns the measures of the directories and files below a
+// project, restricted to the given qualifiers (e.g. DIR, FIL). A positive
+// depth walks the tree one directory level at a time so that the server only
+// returns components within that many path segments.
+func (c *Client) GetComponentTree(projectKey, branch string, metricKeys, qualifiers []string, depth int) ([]TreeComponent, error) {
+	if depth <= 0 {
+		return c.getComponentTree(projectKey, branch, "all", metricKeys, qualifiers)
+	}
+
+	// Directories are needed to descend, whether or not they were asked for.
+	keep := make(map[string]bool, len(qualifiers))
+	walk := qualifiers
+	for _, qualifier := range qualifiers {
+		keep[qualifier] = true
+	}
+	if !keep["DIR"] {
+		walk = append(append([]string(nil), qualifiers...), "DIR")
+	}
+
+	var components []TreeComponent
+	var truncated error
+	parents := []string{projectKey}
+	for level := 1; level <= depth && len(parents) > 0; level++ {
+		var next []string
+		for _, parent := range parents {
+			children, err := c.getComponentTree(parent, branch, "children", metricKeys, walk)
+			if errors.Is(err, ErrTruncated) {
+				truncated = err
+			} else if err != nil {
+				return nil, err
+			}
+
+			for _, child := range children {
+				if child.Qualifier == "DIR" {
+					next = append(next, child.Key)
+				}
+				if keep[child.Qualifier] {
+					components = append(components, child)
+				}
+			}
+		}
+		parents = next
+	}
+
+	return components, truncated
+}
+
+// getComponentTree pages through the components below a component with the
+// given strategy (all, children or leaves).
+func (c *Client) getComponentTree(component, branch, strategy string, metricKeys, qualifiers []string) ([]TreeComponent, error) {
+	var components []TreeComponent
+
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("component", component)
+		setAnalysisTarget(params, branch, "")
+		params.Set("metricKeys", strings.Join(metricKeys, ","))
+		params.Set("qualifiers", strings.Join(qualifiers, ","))
+		params.Set("strategy", strategy)
+		params.Set("ps", fmt.Sprintf("%d", pageSize))
+		params.Set("p", fmt.Sprintf("%d", page))
+
+		var response ComponentTreeResponse
+		if err := c.get("/api/measures/component_tree", params, &response); err != nil {
+			return nil, err
+		}
+
+		for _, component := range response.Components {
+			for i := range component.Metrics {
+				component.Metrics[i].Component = component.Key
+			}
+			resolveNewCodeValues(component.Metrics)
+
+			components = append(components, component)
+		}
+
+		if len(response.Components) < pageSize || page*pageSize >= response.Paging.Total {
+			break
+		}
+		if page*pageSize >= searchLimit {
+			return components, fmt.Errorf("%w: %s has %d components, only the first %d are returned",
+				ErrTruncated, component, response.Paging.Total, searchLimit)
+		}
+	}
+
+	return components, nil
+}
+
+func resolveNewCodeValues(metrics []Metric) {
+	for i := range metrics {
+		if metrics[i].Value == "" {
+			if value, ok := metrics[i].NewCodeValue(); ok {
+				metrics[i].Value = value
+			}
+		}
+	}
+}
+
+func (c *Client) GetQualityGateStatus(projectKey, branch, pullRequest string) (*ProjectStatus, error) {
+	params := url.Values{}
+	params.Set("projectKey", projectKey)
+	setAnalysisTarget(params, branch, pullRequest)
+
+	var response ProjectStatusResponse
+	if err := c.get("/api/qualitygates/project_status", params, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.ProjectStatus, nil
+}
+
+func (c *Client) GetProjectBranches(projectKey string) ([]Branch, error) {
+	params := url.Values{}
+	params.Set("project", projectKey)
+
+	var response BranchesResponse
+	if err := c.get("/api/project_branches/list", params, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Branches, nil
+}
+
+func (c *Client) GetProjectPullRequests(projectKey string) ([]PullRequest, error) {
+	params := url.Values{}
+	params.Set("project", projectKey)
+
+	var response PullRequestsResponse
+	if err := c.get("/api/project_pull_requests/list", params, &response); err != nil {
+		return nil, err
+	}
+
+	return response.PullRequests, nil
+}
+
+// SearchIssues returns every issue of a project created within [from, to).
+// The search API refuses to page past 10,000 results, so ranges holding more
+// than that are split in half and searched separately. A one second range
+// cannot be split further; its first 10,000 issues are returned with an
+// ErrTruncated error.
+func (c *Client) SearchIssues(projectKey string, from, to time.Time) ([]Issue, error) {
+	var issues []Issue
+
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("componentKeys", projectKey)
+		params.Set("createdAfter", from.Format(dateFormat))
+		params.Set("createdBefore", to.Format(dateFormat))
+		params.Set("s", "CREATION_DATE")
+		params.Set("ps", fmt.Sprintf("%d", pageSize))
+		params.Set("p", fmt.Sprintf("%d", page))
+
+		var response IssuesResponse
+		if err := c.get("/api/issues/search", params, &response); err != nil {
+			return nil, err
+		}
+
+		if page == 1 && response.Paging.Total > searchLimit && to.Sub(from) > time.Second {
+			mid := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+
+			older, err := c.SearchIssues(projectKey, from, mid)
+			if err != nil && !errors.Is(err, ErrTruncated) {
+				return nil, err
+			}
+			newer, newerErr := c.SearchIssues(projectKey, mid, to)
+			if newerErr != nil && !errors.Is(newerErr, ErrTruncated) {
+				return nil, newerErr
+			}
+			if err == nil {
+				err = newerErr
+			}
+
+			return append(older, newer...), err
+		}
+
+		issues = append(issues, response.Issues...)
+
+		if len(response.Issues) < pageSize || page*pageSize >= response.Paging.Total {
+			break
+		}
+		if page*pageSize >= searchLimit {
+			return issues, fmt.Errorf("%w: %s has %d issues created at %s, only the first %d are returned",
+				ErrTruncated, projectKey, response.Paging.Total, from.Format(dateFormat), searchLimit)
+		}
+	}
+
+	return issues, nil
+}
+
+// GetIssues returns the issues with the given keys, whatever their creation
+// date, to pick up status changes of issues created before the last search.
+func (c *Client) GetIssues(keys []string) ([]Issue, error) {
+	var issues []Issue
+
+	for start := 0; start < len(keys); start += issueKeysPerRequest {
+		end := start + issueKeysPerRequest
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		params := url.Values{}
+		params.Set("issues", strings.Join(keys[start:end], ","))
+		params.Set("ps", fmt.Sprintf("%d", issueKeysPerRequest))
+
+		var response IssuesResponse
+		if err := c.get("/api/issues/search", params, &response); err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, response.Issues...)
+	}
+
+	return issues, nil
+}
+
+// SearchHotspots returns the security hotspots of a project. Hotspots cannot
+// be filtered by date, so each review status is searched separately to stay
+// under the 10,000 result limit.
+func (c *Client) SearchHotspots(projectKey string) ([]Hotspot, error) {
+	var hotspots []Hotspot
+
+	for _, status := range []string{"TO_REVIEW", "REVIEWED"} {
+		for page := 1; ; page++ {
+			params := url.Values{}
+			params.Set("projectKey", projectKey)
+			params.Set("status", status)
+			params.Set("ps", fmt.Sprintf("%d", pageSize))
+			params.Set("p", fmt.Sprintf("%d", page))
+
+			var response HotspotsResponse
+			if err := c.get("/api/hotspots/search", params, &response); err != nil {
+				return nil, err
+			}
+
+			hotspots = append(hotspots, response.Hotspots...)
+
+			if len(response.Hotspots) < pageSize || page*pageSize >= response.Paging.Total || page*pageSize >= searchLimit {
+				break
+			}
+		}
+	}
+
+	return hotspots, nil
+}
+
+type ValidationResponse struct {
+	Valid bool `json:"valid"`
+}
+
+func (c *Client) ValidateAuthentication(ctx context.Context) error {
+	var response ValidationResponse
+	if err := c.getContext(ctx, "/api/authentication/validate", url.Values{}, &response); err != nil {
+		return err
+	}
+
+	if !response.Valid {
+		return fmt.Errorf("authentication token is not valid")
+	}
+
+	return nil
+}
+
+func setAnalysisTarget(params url.Values, branch, pullRequest string) {
+	if pullRequest != "" {
+		params.Set("pullRequest", pullRequest)
+	} else if branch != "" {
+		params.Set("branch", branch)
+	}
+}
+
+func (c *Client) get(path string, params url.Values, v interface{}) error {
+	return c.getContext(context.Background(), path, params, v)
+}
+
+func (c *Client) getContext(ctx context.Context, path string, params url.Values, v interface{}) error {
+	url := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
@@ -57,22 +516,17 @@ func (c *Client) GetProjectMetrics(projectKey string, metricKeys []string) ([]Me
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
 
-	var response MeasuresResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	for i := range response.Component.Metrics {
-		response.Component.Metrics[i].Component = projectKey
-	}
-
-	return response.Component.Metrics, nil
-}
\ No newline at end of file
+	return nil
+}
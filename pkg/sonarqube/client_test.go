@@ -0,0 +1,50 @@
+package sonarqube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSearchIssuesSplitsRangesAndReportsTruncation(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Second)
+	crowded := from.Add(time.Second).Format(dateFormat)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, _ := strconv.Atoi(query.Get("p"))
+
+		// The second second holds more issues than the API pages through.
+		total := 1
+		if query.Get("createdAfter") == crowded {
+			total = searchLimit + 1
+		} else if query.Get("createdBefore") == to.Format(dateFormat) {
+			total = searchLimit + 2
+		}
+
+		var response IssuesResponse
+		response.Paging.Total = total
+		for i := (page - 1) * pageSize; i < total && i < page*pageSize; i++ {
+			response.Issues = append(response.Issues, Issue{Key: fmt.Sprintf("%s-%d", query.Get("createdAfter"), i)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	issues, err := NewClient(server.URL, "secret").SearchIssues("project", from, to)
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("error = %v, want ErrTruncated", err)
+	}
+	if len(issues) != 1+searchLimit {
+		t.Errorf("got %d issues, want %d", len(issues), 1+searchLimit)
+	}
+}